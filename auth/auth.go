@@ -0,0 +1,308 @@
+// Package auth detects the sign-in methods a page offers besides a
+// password: OAuth and OpenID Connect identity providers ("Sign in with
+// Google"), SAML and enterprise single sign-on, and passkeys (WebAuthn).
+package auth
+
+import (
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/happyhackingspace/dit/internal/htmlutil"
+)
+
+// Kind is the family of a sign-in method.
+type Kind string
+
+// Sign-in method kinds.
+const (
+	KindOAuth   Kind = "oauth"   // social or OpenID Connect identity provider
+	KindSSO     Kind = "sso"     // enterprise identity provider or generic SSO entry point
+	KindSAML    Kind = "saml"    // SAML request or response form, or SAML endpoint
+	KindPasskey Kind = "passkey" // WebAuthn / passkey / security key
+)
+
+// Method is a sign-in method found on a page. URL is the target of the
+// button or link, resolved against the page URL when one is known.
+type Method struct {
+	Kind     Kind   `json:"kind"`
+	Provider string `json:"provider,omitempty"`
+	Text     string `json:"text,omitempty"`
+	URL      string `json:"url,omitempty"`
+	Selector string `json:"selector,omitempty"`
+}
+
+// maxTextLen bounds the element text reported in a Method.
+const maxTextLen = 80
+
+var (
+	passkeyText = regexp.MustCompile(`\b(passkeys?|security key|webauthn|face id|touch id|windows hello)\b`)
+	ssoText     = regexp.MustCompile(`\b(single sign[- ]on|(sign|log) ?in (with|using|via) sso|continue with sso|use sso|sso (login|sign[- ]?in)|enterprise (login|sign[- ]?in)|sign in with your (company|organi[sz]ation))\b`)
+	authHint    = regexp.MustCompile(`login|log-in|signin|sign-in|sign_in|oauth|auth|sso`)
+	passkeyJS   = regexp.MustCompile(`navigator\.credentials\.(get|create)|publickeycredential`)
+	samlPath    = regexp.MustCompile(`/saml2?(/|$|\?)`)
+)
+
+// authSegments are path segments that mark a link as an authentication
+// endpoint when combined with a provider name, e.g. /users/auth/github.
+var authSegments = map[string]bool{
+	"auth": true, "oauth": true, "oauth2": true, "login": true, "signin": true,
+	"sign_in": true, "connect": true, "social": true, "sso": true,
+	"accounts": true, "omniauth": true, "openid": true, "oidc": true,
+}
+
+// Detect returns the sign-in methods offered by doc, one per kind and
+// provider, in document order. pageURL, if set, resolves relative targets.
+func Detect(doc *goquery.Document, pageURL string) []Method {
+	if doc == nil {
+		return nil
+	}
+	d := detector{doc: doc, base: htmlutil.BaseURL(doc, pageURL), index: make(map[string]int)}
+
+	for _, l := range htmlutil.GetPageLinks(doc) {
+		d.candidate(l.Sel, l.Text, l.Href)
+	}
+	doc.Find(`button, input[type=submit], input[type=button], input[type=image], [role=button]`).Each(func(_ int, s *goquery.Selection) {
+		if goquery.NodeName(s) == "a" {
+			return // already seen as a link
+		}
+		text := strings.TrimSpace(s.Text())
+		if v, ok := s.Attr("value"); ok && text == "" {
+			text = v
+		}
+		d.candidate(s, text, buttonTarget(s))
+	})
+	d.widgets(doc)
+	d.samlForms(doc)
+	d.passkeyMarkup(doc)
+	return d.methods
+}
+
+// detector accumulates methods, merging repeated kind/provider pairs.
+type detector struct {
+	doc     *goquery.Document
+	base    *url.URL
+	methods []Method
+	index   map[string]int
+	ids     htmlutil.IDCounts // counted on the first method found
+}
+
+// selector returns the CSS path of s, counting the document ids once.
+func (d *detector) selector(s *goquery.Selection) string {
+	if d.ids == nil {
+		d.ids = htmlutil.CountIDs(d.doc.Selection)
+	}
+	return d.ids.CSSPath(s)
+}
+
+func (d *detector) add(m Method) {
+	key := string(m.Kind) + "|" + m.Provider
+	if i, ok := d.index[key]; ok {
+		if d.methods[i].URL == "" {
+			d.methods[i].URL = m.URL
+		}
+		return
+	}
+	d.index[key] = len(d.methods)
+	d.methods = append(d.methods, m)
+}
+
+// candidate classifies a link or button from its target, text and
+// attributes. Most links match nothing, so the method and its selector are
+// only built for a match.
+func (d *detector) candidate(s *goquery.Selection, text, target string) {
+	target = strings.TrimSpace(target)
+	lowerTarget := strings.ToLower(target)
+	if strings.HasPrefix(lowerTarget, "javascript:") || strings.HasPrefix(lowerTarget, "mailto:") {
+		target, lowerTarget = "", ""
+	}
+	kind, provider, ok := d.match(s, text, lowerTarget)
+	if !ok {
+		return
+	}
+
+	m := Method{Kind: kind, Provider: provider, Text: truncate(strings.Join(strings.Fields(text), " ")), Selector: d.selector(s)}
+	if target != "" && !strings.HasPrefix(target, "#") {
+		m.URL = htmlutil.ResolveURL(d.base, target)
+	}
+	d.add(m)
+}
+
+// match returns the kind and provider of a link or button, if it is a
+// sign-in method.
+func (d *detector) match(s *goquery.Selection, text, lowerTarget string) (Kind, string, bool) {
+	if p := providerForURL(lowerTarget); p != nil {
+		return p.kind, p.name, true
+	}
+	if isSAMLURL(lowerTarget) {
+		return KindSAML, "", true
+	}
+	label := strings.ToLower(strings.Join(strings.Fields(text+" "+attrs(s, "aria-label", "title")+" "+imgAlt(s)), " "))
+	css := strings.ToLower(attrs(s, "class", "id", "data-provider"))
+	for i := range providers {
+		p := &providers[i]
+		if p.text.MatchString(label) || (p.css.MatchString(css) && authHint.MatchString(css+" "+lowerTarget)) {
+			return p.kind, p.name, true
+		}
+	}
+	switch {
+	case ssoText.MatchString(label) || isSSOPath(lowerTarget):
+		return KindSSO, "", true
+	case passkeyText.MatchString(label):
+		return KindPasskey, "", true
+	}
+	return "", "", false
+}
+
+// widgets finds provider SDK buttons that carry no link of their own.
+func (d *detector) widgets(doc *goquery.Document) {
+	for _, w := range widgets {
+		doc.Find(w.selector).Each(func(_ int, s *goquery.Selection) {
+			m := Method{Kind: KindOAuth, Provider: w.provider, Selector: d.selector(s)}
+			if w.urlAttr != "" {
+				if v, ok := s.Attr(w.urlAttr); ok && v != "" {
+					m.URL = htmlutil.ResolveURL(d.base, v)
+				}
+			}
+			d.add(m)
+		})
+	}
+}
+
+// samlForms finds forms that post a SAML message to an identity provider.
+func (d *detector) samlForms(doc *goquery.Document) {
+	doc.Find(`input[name=SAMLRequest], input[name=SAMLResponse]`).Each(func(_ int, s *goquery.Selection) {
+		form := s.Closest("form")
+		m := Method{Kind: KindSAML, Selector: d.selector(form)}
+		if action := htmlutil.GetFormAction(form); action != "" {
+			m.URL = htmlutil.ResolveURL(d.base, action)
+		}
+		d.add(m)
+	})
+}
+
+// passkeyMarkup finds WebAuthn autofill fields and inline WebAuthn calls.
+func (d *detector) passkeyMarkup(doc *goquery.Document) {
+	doc.Find(`input[autocomplete*=webauthn]`).Each(func(_ int, s *goquery.Selection) {
+		d.add(Method{Kind: KindPasskey, Selector: d.selector(s)})
+	})
+	doc.Find("script:not([src])").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		if passkeyJS.MatchString(strings.ToLower(s.Text())) {
+			d.add(Method{Kind: KindPasskey})
+			return false
+		}
+		return true
+	})
+}
+
+// providerParams are query and form parameters naming an identity provider.
+var providerParams = []string{"provider", "connection", "idp", "strategy"}
+
+// buttonTarget returns where a button leads: its formaction or data URL,
+// or, for a submit button naming a provider (name="provider"
+// value="github"), the action of its form with that parameter added.
+func buttonTarget(s *goquery.Selection) string {
+	for _, a := range []string{"formaction", "data-href", "data-url", "data-link"} {
+		if v, ok := s.Attr(a); ok && strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	name, _ := s.Attr("name")
+	value, _ := s.Attr("value")
+	if value == "" || !slices.Contains(providerParams, strings.ToLower(name)) {
+		return ""
+	}
+	action := htmlutil.GetFormAction(s.Closest("form"))
+	sep := "?"
+	if strings.Contains(action, "?") {
+		sep = "&"
+	}
+	return action + sep + url.QueryEscape(name) + "=" + url.QueryEscape(value)
+}
+
+// providerForURL returns the provider whose authorization endpoint or
+// app-side login route target points to, or nil.
+func providerForURL(target string) *provider {
+	if target == "" {
+		return nil
+	}
+	for i := range providers {
+		for _, h := range providers[i].endpoints {
+			if strings.Contains(target, h) {
+				return &providers[i]
+			}
+		}
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil
+	}
+	var tokens []string
+	for seg := range strings.SplitSeq(u.Path, "/") {
+		tokens = append(tokens, seg)
+		tokens = append(tokens, strings.FieldsFunc(seg, func(r rune) bool { return r == '-' || r == '_' || r == '.' })...)
+	}
+	hasAuth := slices.ContainsFunc(tokens, func(t string) bool { return authSegments[t] })
+	query := u.Query()
+	for _, p := range providerParams {
+		if v := query.Get(p); v != "" {
+			tokens = append(tokens, v)
+			hasAuth = true
+		}
+	}
+	if !hasAuth {
+		return nil
+	}
+	for i := range providers {
+		for _, t := range tokens {
+			for _, name := range providers[i].tokens {
+				if t == name || strings.HasPrefix(t, name+"-") {
+					return &providers[i]
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func isSAMLURL(target string) bool {
+	return strings.Contains(target, "samlrequest=") || samlPath.MatchString(target)
+}
+
+func isSSOPath(target string) bool {
+	u, err := url.Parse(target)
+	if err != nil {
+		return false
+	}
+	for seg := range strings.SplitSeq(u.Path, "/") {
+		if seg == "sso" {
+			return true
+		}
+	}
+	return false
+}
+
+func attrs(s *goquery.Selection, names ...string) string {
+	var parts []string
+	for _, n := range names {
+		if v, ok := s.Attr(n); ok && v != "" {
+			parts = append(parts, v)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func imgAlt(s *goquery.Selection) string {
+	alt, _ := s.Find("img[alt]").First().Attr("alt")
+	return alt
+}
+
+func truncate(s string) string {
+	r := []rune(s)
+	if len(r) <= maxTextLen {
+		return s
+	}
+	return strings.TrimSpace(string(r[:maxTextLen]))
+}
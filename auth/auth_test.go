@@ -0,0 +1,83 @@
+package auth_test
+
+import (
+	"testing"
+
+	"github.com/happyhackingspace/dit/auth"
+	"github.com/happyhackingspace/dit/internal/htmlutil"
+)
+
+func TestDetect(t *testing.T) {
+	doc, err := htmlutil.LoadHTMLString(`<html><head><base href="/app/"></head><body>
+<form action="/session" method="post">
+  <input name="login"><input type="password" name="password" autocomplete="current-password webauthn">
+  <button type="submit">Sign in</button>
+</form>
+<a class="btn" href="auth/github">Sign in with GitHub</a>
+<a href="https://accounts.google.com/o/oauth2/v2/auth?client_id=1">Google</a>
+<form action="/oauth/start"><button name="provider" value="microsoft">Microsoft</button></form>
+<a href="/sso/login">Use single sign-on</a>
+<button type="button" id="passkey">Sign in with a passkey</button>
+<footer><a class="social facebook" href="https://facebook.com/example">Facebook</a></footer>
+</body></html>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]auth.Method)
+	for _, m := range auth.Detect(doc, "https://example.com/login") {
+		got[string(m.Kind)+"/"+m.Provider] = m
+	}
+	want := map[string]string{
+		"oauth/github":    "https://example.com/app/auth/github",
+		"oauth/google":    "https://accounts.google.com/o/oauth2/v2/auth?client_id=1",
+		"oauth/microsoft": "https://example.com/oauth/start?provider=microsoft",
+		"sso/":            "https://example.com/sso/login",
+		"passkey/":        "",
+	}
+	for key, url := range want {
+		m, ok := got[key]
+		if !ok {
+			t.Errorf("missing %s in %+v", key, got)
+			continue
+		}
+		if m.URL != url {
+			t.Errorf("%s URL = %q, want %q", key, m.URL, url)
+		}
+	}
+	if sel := got["passkey/"].Selector; sel != "#passkey" {
+		t.Errorf("passkey selector = %q, want #passkey", sel)
+	}
+	if sel := got["sso/"].Selector; doc.Find(sel).Text() != "Use single sign-on" {
+		t.Errorf("sso selector %q does not locate the link", sel)
+	}
+	if _, ok := got["oauth/facebook"]; ok {
+		t.Error("a social profile link is not a sign-in method")
+	}
+	if len(got) != len(want) {
+		t.Errorf("got %d methods, want %d: %+v", len(got), len(want), got)
+	}
+}
+
+func TestDetectSAMLForm(t *testing.T) {
+	doc, err := htmlutil.LoadHTMLString(`<html><body onload="document.forms[0].submit()">
+<form method="post" action="https://idp.example.org/saml2/sso">
+  <input type="hidden" name="SAMLRequest" value="PHNhbWxwOkF1dGhu">
+  <input type="hidden" name="RelayState" value="/dashboard">
+</form></body></html>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	methods := auth.Detect(doc, "")
+	if len(methods) != 1 || methods[0].Kind != auth.KindSAML || methods[0].URL != "https://idp.example.org/saml2/sso" {
+		t.Errorf("got %+v, want one SAML method", methods)
+	}
+
+	plain, err := htmlutil.LoadHTMLString(`<form action="/login"><input name="user"><input type="password" name="pass"></form>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if methods := auth.Detect(plain, ""); len(methods) != 0 {
+		t.Errorf("password-only page: got %+v", methods)
+	}
+}
@@ -0,0 +1,165 @@
+package auth
+
+import "regexp"
+
+// provider describes how to recognise an identity provider: its hosted
+// authorization endpoints, the names apps use for it in login routes
+// (/auth/github, ?connection=google-oauth2), the button text and the
+// class or id names of its buttons.
+type provider struct {
+	name      string
+	kind      Kind
+	endpoints []string
+	tokens    []string
+	text      *regexp.Regexp
+	css       *regexp.Regexp
+}
+
+// buttonText matches "Sign in with X" style labels for the given names.
+func buttonText(names string) *regexp.Regexp {
+	return regexp.MustCompile(`\b((sign|log) ?(in|on|up)|login|continue|connect|register|authenticate)( with| using| via| through)? (` + names + `)\b`)
+}
+
+// cssName matches a provider name as a word in class or id values.
+func cssName(names string) *regexp.Regexp {
+	return regexp.MustCompile(`(^|[^a-z0-9])(` + names + `)([^a-z0-9]|$)`)
+}
+
+var providers = []provider{
+	{
+		name:      "google",
+		kind:      KindOAuth,
+		endpoints: []string{"accounts.google.com/o/oauth2", "accounts.google.com/signin/oauth", "accounts.google.com/gsi"},
+		tokens:    []string{"google", "google_oauth2", "googleoauth2"},
+		text:      buttonText(`google`),
+		css:       cssName(`google`),
+	},
+	{
+		name:      "microsoft",
+		kind:      KindOAuth,
+		endpoints: []string{"login.microsoftonline.com", "login.live.com/oauth20", "login.windows.net"},
+		tokens:    []string{"microsoft", "azuread", "azure", "windowslive", "office365", "microsoft_graph", "azure_activedirectory_v2", "entra"},
+		text:      buttonText(`microsoft|microsoft account|office 365|azure ad|entra id`),
+		css:       cssName(`microsoft|azure|office365`),
+	},
+	{
+		name:      "github",
+		kind:      KindOAuth,
+		endpoints: []string{"github.com/login/oauth"},
+		tokens:    []string{"github"},
+		text:      buttonText(`github`),
+		css:       cssName(`github`),
+	},
+	{
+		name:      "gitlab",
+		kind:      KindOAuth,
+		endpoints: []string{"gitlab.com/oauth"},
+		tokens:    []string{"gitlab"},
+		text:      buttonText(`gitlab`),
+		css:       cssName(`gitlab`),
+	},
+	{
+		name:      "apple",
+		kind:      KindOAuth,
+		endpoints: []string{"appleid.apple.com/auth"},
+		tokens:    []string{"apple", "appleid"},
+		text:      buttonText(`apple`),
+		css:       cssName(`apple|appleid`),
+	},
+	{
+		name:      "facebook",
+		kind:      KindOAuth,
+		endpoints: []string{"facebook.com/dialog/oauth", "/dialog/oauth?client_id"},
+		tokens:    []string{"facebook", "fb"},
+		text:      buttonText(`facebook`),
+		css:       cssName(`facebook|fb`),
+	},
+	{
+		name:      "twitter",
+		kind:      KindOAuth,
+		endpoints: []string{"api.twitter.com/oauth", "twitter.com/i/oauth2", "x.com/i/oauth2"},
+		tokens:    []string{"twitter"},
+		text:      buttonText(`twitter|x`),
+		css:       cssName(`twitter`),
+	},
+	{
+		name:      "linkedin",
+		kind:      KindOAuth,
+		endpoints: []string{"linkedin.com/oauth"},
+		tokens:    []string{"linkedin"},
+		text:      buttonText(`linkedin`),
+		css:       cssName(`linkedin`),
+	},
+	{
+		name:      "amazon",
+		kind:      KindOAuth,
+		endpoints: []string{"amazon.com/ap/oa"},
+		tokens:    []string{"amazon"},
+		text:      buttonText(`amazon`),
+		css:       cssName(`amazon`),
+	},
+	{
+		name:      "slack",
+		kind:      KindOAuth,
+		endpoints: []string{"slack.com/oauth", "slack.com/openid"},
+		tokens:    []string{"slack"},
+		text:      buttonText(`slack`),
+		css:       cssName(`slack`),
+	},
+	{
+		name:      "discord",
+		kind:      KindOAuth,
+		endpoints: []string{"discord.com/oauth2", "discord.com/api/oauth2"},
+		tokens:    []string{"discord"},
+		text:      buttonText(`discord`),
+		css:       cssName(`discord`),
+	},
+	{
+		name:      "okta",
+		kind:      KindSSO,
+		endpoints: []string{".okta.com/oauth2", ".okta.com/app/", ".oktapreview.com/"},
+		tokens:    []string{"okta"},
+		text:      buttonText(`okta`),
+		css:       cssName(`okta`),
+	},
+	{
+		name:      "auth0",
+		kind:      KindSSO,
+		endpoints: []string{".auth0.com/authorize", ".auth0.com/samlp"},
+		tokens:    []string{"auth0"},
+		text:      buttonText(`auth0`),
+		css:       cssName(`auth0`),
+	},
+	{
+		name:      "onelogin",
+		kind:      KindSSO,
+		endpoints: []string{".onelogin.com/trust/saml2", ".onelogin.com/oidc"},
+		tokens:    []string{"onelogin"},
+		text:      buttonText(`onelogin`),
+		css:       cssName(`onelogin`),
+	},
+	{
+		name:      "keycloak",
+		kind:      KindSSO,
+		endpoints: []string{"/protocol/openid-connect/auth"},
+		tokens:    []string{"keycloak"},
+		text:      buttonText(`keycloak`),
+		css:       cssName(`keycloak`),
+	},
+}
+
+// widget is a provider SDK element rendered into a sign-in button by script.
+type widget struct {
+	selector string
+	provider string
+	urlAttr  string // attribute holding the login callback, if any
+}
+
+var widgets = []widget{
+	{selector: "#g_id_onload", provider: "google", urlAttr: "data-login_uri"},
+	{selector: ".g_id_signin", provider: "google"},
+	{selector: `script[src*="accounts.google.com/gsi/client"]`, provider: "google"},
+	{selector: "#appleid-signin", provider: "apple", urlAttr: "data-redirect-uri"},
+	{selector: `script[src*="appleid.cdn-apple.com"]`, provider: "apple"},
+	{selector: ".fb-login-button", provider: "facebook"},
+}
@@ -1,7 +1,6 @@
 package captcha
 
 import (
-	"regexp"
 	"strings"
 
 	"github.com/PuerkitoBio/goquery"
@@ -36,7 +35,8 @@ const (
 	CaptchaTypeKasada             CaptchaType = "kasada"
 	CaptchaTypeImperva            CaptchaType = "imperva"
 	CaptchaTypeAwsWaf             CaptchaType = "awswaf"
-	CaptchaTypeCoingecko          CaptchaType = "wsiz" // wsiz refers to wsiz.com, Coingecko's bot-protection vendor
+	CaptchaTypeCloudflare         CaptchaType = "cloudflare" // Cloudflare managed challenge or block page
+	CaptchaTypeCoingecko          CaptchaType = "wsiz"       // wsiz refers to wsiz.com, Coingecko's bot-protection vendor
 	CaptchaTypeNovaScape          CaptchaType = "novascape"
 	CaptchaTypeSimple             CaptchaType = "simplecaptcha"
 	CaptchaTypeOther              CaptchaType = "other"
@@ -45,310 +45,96 @@ const (
 // String returns the string representation of CaptchaType
 func (ct CaptchaType) String() string { return string(ct) }
 
-// validCaptchaTypes is the single source of truth for all known CaptchaType values.
-var validCaptchaTypes = map[CaptchaType]struct{}{
-	CaptchaTypeNone:               {},
-	CaptchaTypeRecaptcha:          {},
-	CaptchaTypeRecaptchaV2:        {},
-	CaptchaTypeRecaptchaInvisible: {},
-	CaptchaTypeHCaptcha:           {},
-	CaptchaTypeTurnstile:          {},
-	CaptchaTypeGeetest:            {},
-	CaptchaTypeFriendlyCaptcha:    {},
-	CaptchaTypeRotateCaptcha:      {},
-	CaptchaTypeClickCaptcha:       {},
-	CaptchaTypeImageCaptcha:       {},
-	CaptchaTypePuzzleCaptcha:      {},
-	CaptchaTypeSliderCaptcha:      {},
-	CaptchaTypeMCaptcha:           {},
-	CaptchaTypeDatadome:           {},
-	CaptchaTypePerimeterX:         {},
-	CaptchaTypeArgon:              {},
-	CaptchaTypeBehaviotech:        {},
-	CaptchaTypeSmartCaptcha:       {},
-	CaptchaTypeYandex:             {},
-	CaptchaTypeFuncaptcha:         {},
-	CaptchaTypeKasada:             {},
-	CaptchaTypeImperva:            {},
-	CaptchaTypeAwsWaf:             {},
-	CaptchaTypeCoingecko:          {},
-	CaptchaTypeNovaScape:          {},
-	CaptchaTypeSimple:             {},
-	CaptchaTypeOther:              {},
-}
-
-// IsValidCaptchaType reports whether the provided string maps to a known CaptchaType
+// IsValidCaptchaType reports whether the provided string maps to a known
+// CaptchaType: "none", "other", or a provider of the current rules.
 func IsValidCaptchaType(s string) bool {
-	_, ok := validCaptchaTypes[CaptchaType(s)]
-	return ok
+	ct := CaptchaType(s)
+	return ct == CaptchaTypeNone || ct == CaptchaTypeOther || CurrentRules().Has(ct)
 }
 
-// CaptchaDetector detects CAPTCHA protection in forms using multi-layer detection
-type CaptchaDetector struct{}
-
-// captchaPatternEntry associates a CaptchaType with precompiled regexp patterns.
-type captchaPatternEntry struct {
-	captchaType CaptchaType
-	patterns    []*regexp.Regexp
+// CaptchaDetector detects CAPTCHA protection in forms using multi-layer
+// detection. Signatures come from Rules, or from CurrentRules when nil.
+type CaptchaDetector struct {
+	Rules *Rules
 }
 
-// scriptDomainPatterns are precompiled regexps used by detectByScriptDomain.
-// Ordering: most-specific subtypes first so they are not shadowed by generic entries.
-var scriptDomainPatterns = []captchaPatternEntry{
-	{CaptchaTypeRecaptchaInvisible, []*regexp.Regexp{
-		regexp.MustCompile(`recaptcha.*invisible`),
-		regexp.MustCompile(`grecaptcha\.render.*invisible`),
-	}},
-	{CaptchaTypeRecaptchaV2, []*regexp.Regexp{
-		regexp.MustCompile(`recaptcha.*v2`),
-	}},
-	{CaptchaTypeRecaptcha, []*regexp.Regexp{
-		regexp.MustCompile(`google\.com/recaptcha`),
-		regexp.MustCompile(`recaptcha/api\.js`),
-		regexp.MustCompile(`recaptcha.*\.js`),
-		regexp.MustCompile(`gstatic\.com/.*recaptcha`),
-	}},
-	{CaptchaTypeHCaptcha, []*regexp.Regexp{
-		regexp.MustCompile(`js\.hcaptcha\.com`),
-		regexp.MustCompile(`hcaptcha`),
-	}},
-	{CaptchaTypeTurnstile, []*regexp.Regexp{
-		regexp.MustCompile(`challenges\.cloudflare\.com`),
-		regexp.MustCompile(`js\.cloudflare\.com.*turnstile`),
-	}},
-	{CaptchaTypeGeetest, []*regexp.Regexp{
-		regexp.MustCompile(`geetest`),
-		regexp.MustCompile(`api\.geetest\.com`),
-	}},
-	{CaptchaTypeFriendlyCaptcha, []*regexp.Regexp{
-		regexp.MustCompile(`friendlycaptcha`),
-		regexp.MustCompile(`cdn\.friendlycaptcha\.com`),
-	}},
-	{CaptchaTypeRotateCaptcha, []*regexp.Regexp{
-		regexp.MustCompile(`api\.rotatecaptcha\.com`),
-	}},
-	{CaptchaTypeClickCaptcha, []*regexp.Regexp{
-		regexp.MustCompile(`assets\.clickcaptcha\.com`),
-	}},
-	{CaptchaTypeImageCaptcha, []*regexp.Regexp{
-		regexp.MustCompile(`api\.imagecaptcha\.com`),
-	}},
-	{CaptchaTypePuzzleCaptcha, []*regexp.Regexp{
-		regexp.MustCompile(`puzzle.*captcha`),
-	}},
-	{CaptchaTypeSliderCaptcha, []*regexp.Regexp{
-		regexp.MustCompile(`slider.*captcha`),
-		regexp.MustCompile(`api\.slidercaptcha\.com`),
-		regexp.MustCompile(`slidercaptcha\.com`),
-	}},
-	{CaptchaTypeDatadome, []*regexp.Regexp{
-		regexp.MustCompile(`datadome\.co`),
-	}},
-	{CaptchaTypePerimeterX, []*regexp.Regexp{
-		regexp.MustCompile(`perimeterx\.net`),
-	}},
-	{CaptchaTypeArgon, []*regexp.Regexp{
-		regexp.MustCompile(`argon.*captcha`),
-		regexp.MustCompile(`captcha\.argon`),
-	}},
-	{CaptchaTypeBehaviotech, []*regexp.Regexp{
-		regexp.MustCompile(`behaviotech\.com`),
-	}},
-	// SmartCaptcha (specific Yandex product) before generic Yandex to avoid shadowing.
-	{CaptchaTypeSmartCaptcha, []*regexp.Regexp{
-		regexp.MustCompile(`captcha\.yandex`),
-		regexp.MustCompile(`smartcaptcha\.yandex`),
-	}},
-	{CaptchaTypeYandex, []*regexp.Regexp{
-		regexp.MustCompile(`yandex\.com/.*captcha`),
-	}},
-	{CaptchaTypeFuncaptcha, []*regexp.Regexp{
-		regexp.MustCompile(`funcaptcha\.com`),
-		regexp.MustCompile(`api\.funcaptcha\.com`),
-	}},
-	{CaptchaTypeCoingecko, []*regexp.Regexp{
-		regexp.MustCompile(`wsiz\.com`),
-	}},
-	{CaptchaTypeNovaScape, []*regexp.Regexp{
-		regexp.MustCompile(`novascape\.com`),
-	}},
-	{CaptchaTypeMCaptcha, []*regexp.Regexp{
-		regexp.MustCompile(`mcaptcha`),
-		regexp.MustCompile(`app\.mcaptcha\.io`),
-	}},
-	{CaptchaTypeKasada, []*regexp.Regexp{
-		regexp.MustCompile(`kasada`),
-		regexp.MustCompile(`kas\.kasadaproducts\.com`),
-	}},
-	{CaptchaTypeImperva, []*regexp.Regexp{
-		regexp.MustCompile(`/_Incapsula_Resource`),
-		regexp.MustCompile(`incapsula`),
-		regexp.MustCompile(`imperva`),
-	}},
-	{CaptchaTypeAwsWaf, []*regexp.Regexp{
-		regexp.MustCompile(`/aws-waf-captcha/`),
-		regexp.MustCompile(`awswaf\.com`),
-		regexp.MustCompile(`captcha\.aws\.amazon\.com`),
-	}},
+func (cd *CaptchaDetector) rules() *Rules {
+	if cd != nil && cd.Rules != nil {
+		return cd.Rules
+	}
+	return CurrentRules()
 }
 
-// htmlIntegrationPatterns are precompiled regexps used by DetectCaptchaInHTML.
-// Each regex requires the keyword to appear in a meaningful integration context
-// (script src, class/id attributes, data-sitekey, iframes).
-var htmlIntegrationPatterns = []captchaPatternEntry{
-	{CaptchaTypeRecaptchaInvisible, []*regexp.Regexp{
-		regexp.MustCompile(`class=["'][^"']*g-recaptcha-invisible`),
-		regexp.MustCompile(`data-size=["']invisible["']`),
-	}},
-	{CaptchaTypeRecaptchaV2, []*regexp.Regexp{
-		regexp.MustCompile(`class=["'][^"']*g-recaptcha-v2`),
-	}},
-	{CaptchaTypeRecaptcha, []*regexp.Regexp{
-		regexp.MustCompile(`src=["'][^"']*google\.com/recaptcha`),
-		regexp.MustCompile(`src=["'][^"']*gstatic\.com/[^"']*recaptcha`),
-		regexp.MustCompile(`src=["'][^"']*recaptcha/api\.js`),
-		regexp.MustCompile(`class=["'][^"']*g-recaptcha`),
-	}},
-	{CaptchaTypeHCaptcha, []*regexp.Regexp{
-		regexp.MustCompile(`src=["'][^"']*js\.hcaptcha\.com`),
-		regexp.MustCompile(`class=["'][^"']*h-captcha`),
-		regexp.MustCompile(`data-hcaptcha-widget-id`),
-	}},
-	{CaptchaTypeTurnstile, []*regexp.Regexp{
-		regexp.MustCompile(`src=["'][^"']*challenges\.cloudflare\.com`),
-		regexp.MustCompile(`class=["'][^"']*cf-turnstile`),
-	}},
-	{CaptchaTypeGeetest, []*regexp.Regexp{
-		regexp.MustCompile(`src=["'][^"']*geetest`),
-		regexp.MustCompile(`class=["'][^"']*geetest`),
-	}},
-	{CaptchaTypeFriendlyCaptcha, []*regexp.Regexp{
-		regexp.MustCompile(`src=["'][^"']*friendlycaptcha`),
-		regexp.MustCompile(`class=["'][^"']*frc-captcha`),
-	}},
-	{CaptchaTypeRotateCaptcha, []*regexp.Regexp{
-		regexp.MustCompile(`alt=["'][^"']*rotatecaptcha`),
-		regexp.MustCompile(`src=["'][^"']*rotatecaptcha`),
-	}},
-	{CaptchaTypeClickCaptcha, []*regexp.Regexp{
-		regexp.MustCompile(`alt=["'][^"']*clickcaptcha`),
-		regexp.MustCompile(`src=["'][^"']*clickcaptcha`),
-	}},
-	{CaptchaTypeImageCaptcha, []*regexp.Regexp{
-		regexp.MustCompile(`alt=["'][^"']*imagecaptcha`),
-		regexp.MustCompile(`src=["'][^"']*imagecaptcha`),
-	}},
-	{CaptchaTypePuzzleCaptcha, []*regexp.Regexp{
-		regexp.MustCompile(`class=["'][^"']*__puzzle_captcha`),
-	}},
-	{CaptchaTypeSliderCaptcha, []*regexp.Regexp{
-		regexp.MustCompile(`class=["'][^"']*slider-captcha`),
-		regexp.MustCompile(`src=["'][^"']*slidercaptcha`),
-	}},
-	{CaptchaTypeMCaptcha, []*regexp.Regexp{
-		regexp.MustCompile(`src=["'][^"']*mcaptcha`),
-		regexp.MustCompile(`class=["'][^"']*mcaptcha`),
-		regexp.MustCompile(`data-mcaptcha`),
-	}},
-	{CaptchaTypeKasada, []*regexp.Regexp{
-		regexp.MustCompile(`src=["'][^"']*kasadaproducts\.com`),
-		regexp.MustCompile(`data-kasada`),
-	}},
-	{CaptchaTypeImperva, []*regexp.Regexp{
-		regexp.MustCompile(`src=["'][^"']*/_incapsula_resource`),
-		regexp.MustCompile(`data-incapsula`),
-		regexp.MustCompile(`data-imperva`),
-	}},
-	{CaptchaTypeAwsWaf, []*regexp.Regexp{
-		regexp.MustCompile(`src=["'][^"']*aws-waf-captcha`),
-		regexp.MustCompile(`src=["'][^"']*awswaf\.com`),
-	}},
-	{CaptchaTypeDatadome, []*regexp.Regexp{
-		regexp.MustCompile(`src=["'][^"']*datadome`),
-		regexp.MustCompile(`data-datadome`),
-		regexp.MustCompile(`class=["'][^"']*dd-challenge`),
-	}},
-	{CaptchaTypePerimeterX, []*regexp.Regexp{
-		regexp.MustCompile(`src=["'][^"']*perimeterx`),
-		regexp.MustCompile(`data-px`),
-		regexp.MustCompile(`_pxappid`),
-	}},
-	{CaptchaTypeArgon, []*regexp.Regexp{
-		regexp.MustCompile(`class=["'][^"']*argon-captcha`),
-	}},
-	{CaptchaTypeBehaviotech, []*regexp.Regexp{
-		regexp.MustCompile(`src=["'][^"']*behaviotech\.com`),
-	}},
-	// SmartCaptcha (specific Yandex product) before generic Yandex to avoid shadowing.
-	{CaptchaTypeSmartCaptcha, []*regexp.Regexp{
-		regexp.MustCompile(`src=["'][^"']*captcha\.yandex`),
-		regexp.MustCompile(`class=["'][^"']*smart-captcha`),
-		regexp.MustCompile(`data-smartcaptcha`),
-	}},
-	{CaptchaTypeYandex, []*regexp.Regexp{
-		regexp.MustCompile(`class=["'][^"']*yandex-captcha`),
-	}},
-	{CaptchaTypeFuncaptcha, []*regexp.Regexp{
-		regexp.MustCompile(`src=["'][^"']*funcaptcha\.com`),
-		regexp.MustCompile(`src=["'][^"']*arkoselabs\.com`),
-		regexp.MustCompile(`class=["'][^"']*funcaptcha`),
-	}},
-	{CaptchaTypeCoingecko, []*regexp.Regexp{
-		regexp.MustCompile(`src=["'][^"']*wsiz\.com`),
-	}},
-	{CaptchaTypeNovaScape, []*regexp.Regexp{
-		regexp.MustCompile(`src=["'][^"']*novascape`),
-	}},
-	{CaptchaTypeSimple, []*regexp.Regexp{
-		regexp.MustCompile(`name=["'][^"']*captcha_code`),
-		regexp.MustCompile(`name=["'][^"']*captcha_input`),
-		regexp.MustCompile(`id=["'][^"']*captcha_image`),
-	}},
+// Layer names the detection method that identified a CAPTCHA.
+type Layer string
+
+const (
+	LayerClass         Layer = "class"
+	LayerScriptDomain  Layer = "script-domain"
+	LayerDataAttribute Layer = "data-attribute"
+	LayerID            Layer = "id"
+	LayerAlt           Layer = "alt"
+	LayerFieldName     Layer = "field-name"
+	LayerIframe        Layer = "iframe"
+	LayerGeneric       Layer = "generic"
+	LayerHTML          Layer = "html" // full-page scan by DetectInHTML
+	LayerHeader        Layer = "header"
+	LayerCookie        Layer = "cookie"
+	LayerStatus        Layer = "status"
+)
+
+// Detection is a detected CAPTCHA together with the layer that found it and
+// the evidence that matched, e.g. a script src or a class name.
+type Detection struct {
+	Type     CaptchaType `json:"type"`
+	Layer    Layer       `json:"layer,omitempty"`
+	Evidence string      `json:"evidence,omitempty"`
 }
 
+// Found reports whether a CAPTCHA was detected.
+func (d Detection) Found() bool { return d.Type != CaptchaTypeNone && d.Type != "" }
+
+// none is the Detection returned when nothing matched.
+var none = Detection{Type: CaptchaTypeNone}
+
 // DetectInForm detects CAPTCHA in a form element using comprehensive detection methods
 func (cd *CaptchaDetector) DetectInForm(form *goquery.Selection) CaptchaType {
-	// Layer 1: Class-based detection (most specific and reliable)
-	if captcha := detectByClasses(form); captcha != CaptchaTypeNone {
-		return captcha
-	}
+	return cd.Detect(form).Type
+}
 
-	// Layer 2: Domain-based detection (script src attributes)
-	if captcha := detectByScriptDomain(form); captcha != CaptchaTypeNone {
-		return captcha
+// Detect is like DetectInForm but also reports the layer that fired and
+// the matching evidence.
+func (cd *CaptchaDetector) Detect(form *goquery.Selection) Detection {
+	rules := cd.rules()
+	for _, layer := range formLayers {
+		if found := layer(rules, form); len(found) > 0 {
+			return found[0]
+		}
 	}
+	return none
+}
 
+// formLayers are the form detection layers in priority order. Each returns
+// every match, most specific first.
+var formLayers = []func(*Rules, *goquery.Selection) []Detection{
+	// Layer 1: Class-based detection (most specific and reliable)
+	detectByClasses,
+	// Layer 2: Domain-based detection (script src attributes)
+	detectByScriptDomain,
 	// Layer 3: Data attributes (be specific to avoid false positives)
-	if captcha := detectByDataAttributes(form); captcha != CaptchaTypeNone {
-		return captcha
-	}
-
+	detectByDataAttributes,
 	// Layer 4: Element IDs and alt-text detection
-	if captcha := detectByIDsAndAlt(form); captcha != CaptchaTypeNone {
-		return captcha
-	}
-
+	detectByIDsAndAlt,
 	// Layer 5: Field names (detect simple/text CAPTCHAs by input name)
-	if captcha := detectByFieldNames(form); captcha != CaptchaTypeNone {
-		return captcha
-	}
-
+	detectByFieldNames,
 	// Layer 6: Iframe-based detection
-	if captcha := detectByIframe(form); captcha != CaptchaTypeNone {
-		return captcha
-	}
-
+	detectByIframe,
 	// Layer 7: Generic markers
-	if hasGenericCaptchaMarkers(form) {
-		return CaptchaTypeOther
-	}
-
-	return CaptchaTypeNone
+	detectGenericCaptchaMarkers,
 }
 
 // detectByScriptDomain checks script src attributes for known CAPTCHA provider domains.
-// It uses the precompiled scriptDomainPatterns package-level variable.
-func detectByScriptDomain(form *goquery.Selection) CaptchaType {
+func detectByScriptDomain(rules *Rules, form *goquery.Selection) []Detection {
 	var scriptSrcs []string
 	form.Find("script").Each(func(_ int, s *goquery.Selection) {
 		if src, ok := s.Attr("src"); ok {
@@ -366,255 +152,140 @@ func detectByScriptDomain(form *goquery.Selection) CaptchaType {
 		}
 	})
 
-	// Match scripts against precompiled patterns
-	for _, entry := range scriptDomainPatterns {
-		for _, src := range scriptSrcs {
-			for _, pattern := range entry.patterns {
-				if pattern.MatchString(src) {
-					return entry.captchaType
-				}
-			}
-		}
-	}
-
-	return CaptchaTypeNone
+	return matchEach(rules.layers[LayerScriptDomain], LayerScriptDomain, scriptSrcs)
 }
 
-// The rest of the helper functions are ported verbatim from the original implementation.
 // detectByDataAttributes checks for CAPTCHA-specific data attributes (less common, more specific patterns)
-func detectByDataAttributes(form *goquery.Selection) CaptchaType {
-	html, _ := form.Html()
-	htmlLower := strings.ToLower(html)
-
-	dataAttrPatterns := []struct {
-		captchaType CaptchaType
-		patterns    []string
-	}{
-		{CaptchaTypeKasada, []string{"data-kasada", "kasada"}},
-		{CaptchaTypeImperva, []string{"data-incapsula", "data-imperva"}},
-		{CaptchaTypeDatadome, []string{"data-datadome", "dd-challenge"}},
-		{CaptchaTypePerimeterX, []string{"data-px", "_pxappid"}},
-		{CaptchaTypeMCaptcha, []string{"data-mcaptcha"}},
-		{CaptchaTypeSmartCaptcha, []string{"data-smartcaptcha", "smartcaptcha"}},
-	}
-
-	for _, entry := range dataAttrPatterns {
-		captchaType := entry.captchaType
-		patterns := entry.patterns
-		for _, p := range patterns {
-			if strings.Contains(htmlLower, p) {
-				return captchaType
-			}
-		}
-	}
-
-	return CaptchaTypeNone
+func detectByDataAttributes(rules *Rules, form *goquery.Selection) []Detection {
+	return matchHTML(rules.layers[LayerDataAttribute], LayerDataAttribute, form)
 }
 
-func detectByClasses(form *goquery.Selection) CaptchaType {
-	html, _ := form.Html()
-	htmlLower := strings.ToLower(html)
-
-	classPatterns := []struct {
-		captchaType CaptchaType
-		patterns    []string
-	}{
-		{CaptchaTypeRecaptchaInvisible, []string{"g-recaptcha-invisible", "grecaptcha-invisible"}},
-		{CaptchaTypeRecaptchaV2, []string{"g-recaptcha-v2", "grecaptcha-v2"}},
-		{CaptchaTypeRecaptcha, []string{"g-recaptcha", "grecaptcha"}},
-		{CaptchaTypeHCaptcha, []string{"h-captcha", "hcaptcha"}},
-		{CaptchaTypeTurnstile, []string{"cf-turnstile", "turnstile"}},
-		{CaptchaTypeGeetest, []string{"geetest_", "geetest-box", "gee-test"}},
-		{CaptchaTypeFriendlyCaptcha, []string{"frc-captcha", "friendlycaptcha"}},
-		{CaptchaTypeMCaptcha, []string{"mcaptcha", "mcaptcha-container"}},
-		{CaptchaTypeKasada, []string{"kasada"}},
-		{CaptchaTypeImperva, []string{"incapsula", "imperva"}},
-		{CaptchaTypeAwsWaf, []string{"aws-waf", "awswaf"}},
-		{CaptchaTypeDatadome, []string{"dd-challenge", "dd-top"}},
-		{CaptchaTypePerimeterX, []string{"_px3", "px-container"}},
-		{CaptchaTypeSmartCaptcha, []string{"smart-captcha", "smartcaptcha"}},
-		{CaptchaTypeArgon, []string{"argon-captcha"}},
-		{CaptchaTypePuzzleCaptcha, []string{"puzzle-captcha", "__puzzle_captcha"}},
-		{CaptchaTypeYandex, []string{"yandex-captcha"}},
-		{CaptchaTypeFuncaptcha, []string{"funcaptcha-container"}},
-	}
-
-	for _, entry := range classPatterns {
-		captchaType := entry.captchaType
-		classes := entry.patterns
-		for _, class := range classes {
-			if strings.Contains(htmlLower, class) {
-				return captchaType
-			}
-		}
-	}
-
-	return CaptchaTypeNone
+func detectByClasses(rules *Rules, form *goquery.Selection) []Detection {
+	return matchHTML(rules.layers[LayerClass], LayerClass, form)
 }
 
 // detectByIDsAndAlt checks element IDs and img alt attributes for captcha type hints
-func detectByIDsAndAlt(form *goquery.Selection) CaptchaType {
-	// Check element IDs
-	idPatterns := []struct {
-		captchaType CaptchaType
-		patterns    []string
-	}{
-		{CaptchaTypeGeetest, []string{"geetest", "gt-captcha", "embed-captcha"}},
-		{CaptchaTypeRecaptcha, []string{"recaptcha"}},
-		{CaptchaTypeHCaptcha, []string{"hcaptcha", "h-captcha"}},
-		{CaptchaTypeTurnstile, []string{"cf-turnstile", "turnstile"}},
-		{CaptchaTypeFuncaptcha, []string{"funcaptcha", "arkose"}},
-	}
-
-	idResult := CaptchaTypeNone
+func detectByIDsAndAlt(rules *Rules, form *goquery.Selection) []Detection {
+	var ids, alts []string
 	form.Find("[id]").Each(func(_ int, s *goquery.Selection) {
-		if idResult != CaptchaTypeNone {
-			return
-		}
-		if id, ok := s.Attr("id"); ok {
-			idLower := strings.ToLower(id)
-			for _, entry := range idPatterns {
-				for _, p := range entry.patterns {
-					if strings.Contains(idLower, p) {
-						idResult = entry.captchaType
-						return
-					}
-				}
-			}
-		}
+		id, _ := s.Attr("id")
+		ids = append(ids, id)
 	})
-	if idResult != CaptchaTypeNone {
-		return idResult
-	}
-
-	// Check img alt attributes for captcha type hints
-	altPatterns := []struct {
-		captchaType CaptchaType
-		pattern     string
-	}{
-		{CaptchaTypeRotateCaptcha, "rotatecaptcha"},
-		{CaptchaTypeClickCaptcha, "clickcaptcha"},
-		{CaptchaTypeImageCaptcha, "imagecaptcha"},
-		{CaptchaTypePuzzleCaptcha, "puzzlecaptcha"},
-		{CaptchaTypeSliderCaptcha, "slidercaptcha"},
-		{CaptchaTypeSimple, "textcaptcha"},
-		{CaptchaTypeSimple, "text-captcha"},
-	}
-
-	altResult := CaptchaTypeNone
 	form.Find("img[alt]").Each(func(_ int, s *goquery.Selection) {
-		if altResult != CaptchaTypeNone {
-			return
-		}
-		if alt, ok := s.Attr("alt"); ok {
-			altLower := strings.ToLower(alt)
-			for _, entry := range altPatterns {
-				if strings.Contains(altLower, entry.pattern) {
-					altResult = entry.captchaType
-					return
-				}
-			}
-		}
+		alt, _ := s.Attr("alt")
+		alts = append(alts, alt)
 	})
-	if altResult != CaptchaTypeNone {
-		return altResult
-	}
-
-	return CaptchaTypeNone
+	found := matchValues(rules.layers[LayerID], LayerID, ids)
+	return append(found, matchValues(rules.layers[LayerAlt], LayerAlt, alts)...)
 }
 
-func detectByFieldNames(form *goquery.Selection) CaptchaType {
-	html, _ := form.Html()
-	htmlLower := strings.ToLower(html)
-
-	// Specific checks for scripted puzzle markers
-	if strings.Contains(htmlLower, "__puzzle_captcha") || strings.Contains(htmlLower, "puzzle-captcha") {
-		return CaptchaTypePuzzleCaptcha
-	}
-
-	simpleCaptchaPatterns := []string{
-		"simplecaptcha",
-		"captcha_code",
-		"captcha_input",
-		"text_captcha",
-		"captcha_result",
-	}
-
-	for _, pattern := range simpleCaptchaPatterns {
-		if strings.Contains(htmlLower, pattern) {
-			return CaptchaTypeSimple
-		}
-	}
-
-	return CaptchaTypeNone
+func detectByFieldNames(rules *Rules, form *goquery.Selection) []Detection {
+	return matchHTML(rules.layers[LayerFieldName], LayerFieldName, form)
 }
 
-func detectByIframe(form *goquery.Selection) CaptchaType {
+func detectByIframe(rules *Rules, form *goquery.Selection) []Detection {
 	var iframeSrcs []string
 	form.Find("iframe").Each(func(_ int, s *goquery.Selection) {
 		if src, ok := s.Attr("src"); ok {
 			iframeSrcs = append(iframeSrcs, strings.ToLower(src))
 		}
 	})
+	return matchEach(rules.layers[LayerIframe], LayerIframe, iframeSrcs)
+}
 
-	iframePatterns := []struct {
-		captchaType CaptchaType
-		patterns    []string
-	}{
-		{CaptchaTypeRecaptcha, []string{"google.com/recaptcha", "www.google.com/recaptcha"}},
-		{CaptchaTypeHCaptcha, []string{"hcaptcha.com"}},
-		{CaptchaTypeTurnstile, []string{"cloudflare.com/turnstile"}},
-		{CaptchaTypeFuncaptcha, []string{"funcaptcha"}},
-		{CaptchaTypeYandex, []string{"smartcaptcha.yandex", "captcha.yandex"}},
+// matchHTML matches the lowercased inner HTML of form, reporting the
+// matching pattern as evidence.
+func matchHTML(matchers []compiledMatcher, layer Layer, form *goquery.Selection) []Detection {
+	html, _ := form.Html()
+	htmlLower := strings.ToLower(html)
+
+	var found []Detection
+	for _, m := range matchers {
+		if ev := m.match(htmlLower); ev != "" {
+			found = append(found, Detection{Type: m.captchaType, Layer: layer, Evidence: ev})
+		}
 	}
+	return found
+}
 
-	for _, entry := range iframePatterns {
-		captchaType := entry.captchaType
-		patterns := entry.patterns
-		for _, src := range iframeSrcs {
-			for _, p := range patterns {
-				if strings.Contains(src, p) {
-					return captchaType
-				}
+// matchEach matches lowercased values such as script or iframe sources,
+// reporting the first matching value of each provider as evidence.
+func matchEach(matchers []compiledMatcher, layer Layer, values []string) []Detection {
+	var found []Detection
+	for _, m := range matchers {
+		for _, v := range values {
+			if m.match(v) != "" {
+				found = append(found, Detection{Type: m.captchaType, Layer: layer, Evidence: v})
+				break
 			}
 		}
 	}
-
-	return CaptchaTypeNone
+	return found
 }
 
-func hasGenericCaptchaMarkers(form *goquery.Selection) bool {
-	found := false
-	form.Find("*").Each(func(_ int, s *goquery.Selection) {
-		if found {
-			return
+// matchValues matches attribute values in document order, reporting the
+// original value as evidence. Each value yields at most one provider.
+func matchValues(matchers []compiledMatcher, layer Layer, values []string) []Detection {
+	var found []Detection
+	for _, v := range values {
+		lower := strings.ToLower(v)
+		for _, m := range matchers {
+			if m.match(lower) != "" {
+				found = append(found, Detection{Type: m.captchaType, Layer: layer, Evidence: v})
+				break
+			}
 		}
+	}
+	return found
+}
+
+// detectGenericCaptchaMarkers reports CaptchaTypeOther when an id, name,
+// class or src attribute mentions "captcha".
+func detectGenericCaptchaMarkers(_ *Rules, form *goquery.Selection) []Detection {
+	var found []Detection
+	form.Find("*").EachWithBreak(func(_ int, s *goquery.Selection) bool {
 		for _, attr := range []string{"id", "name", "class", "src"} {
 			if val, ok := s.Attr(attr); ok {
 				if strings.Contains(strings.ToLower(val), "captcha") {
-					found = true
-					return
+					found = append(found, Detection{Type: CaptchaTypeOther, Layer: LayerGeneric, Evidence: attr + "=" + val})
+					return false
 				}
 			}
 		}
+		return true
 	})
 	return found
 }
 
 // DetectCaptchaInHTML performs a best-effort detection on a full HTML string.
-// It uses precompiled regex patterns (htmlIntegrationPatterns) that require captcha
+// It uses the "html" patterns of the current rules, which require captcha
 // keywords to appear in integration contexts (script src, class/id attributes,
 // data-sitekey, iframes) rather than bare mentions in navigation links or text content.
 func DetectCaptchaInHTML(html string) CaptchaType {
-	htmlLower := strings.ToLower(html)
+	return DetectInHTML(html).Type
+}
 
-	for _, entry := range htmlIntegrationPatterns {
-		for _, re := range entry.patterns {
-			if re.MatchString(htmlLower) {
-				return entry.captchaType
-			}
+// DetectInHTML is like DetectCaptchaInHTML but also returns the matching
+// fragment of the page as evidence.
+func DetectInHTML(html string) Detection {
+	htmlLower := strings.ToLower(html)
+	for _, m := range CurrentRules().layers[LayerHTML] {
+		if ev := m.match(htmlLower); ev != "" {
+			return Detection{Type: m.captchaType, Layer: LayerHTML, Evidence: ev}
 		}
 	}
+	return none
+}
 
-	return CaptchaTypeNone
+// detectAllInHTML returns one detection per provider whose "html"
+// patterns match html.
+func detectAllInHTML(rules *Rules, html string) []Detection {
+	var found []Detection
+	htmlLower := strings.ToLower(html)
+	for _, m := range rules.layers[LayerHTML] {
+		if ev := m.match(htmlLower); ev != "" {
+			found = append(found, Detection{Type: m.captchaType, Layer: LayerHTML, Evidence: ev})
+		}
+	}
+	return found
 }
@@ -1,6 +1,9 @@
 package captcha_test
 
 import (
+	"net/http"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/happyhackingspace/dit/captcha"
@@ -694,3 +697,258 @@ func TestDetectBehaviotech(t *testing.T) {
 		t.Errorf("expected behaviotech, got %v", result)
 	}
 }
+
+func TestDetectReportsLayerAndEvidence(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		want     captcha.CaptchaType
+		layer    captcha.Layer
+		evidence string
+	}{
+		{
+			name:     "class",
+			html:     `<form><div class="g-recaptcha" data-sitekey="x"></div></form>`,
+			want:     captcha.CaptchaTypeRecaptcha,
+			layer:    captcha.LayerClass,
+			evidence: "g-recaptcha",
+		},
+		{
+			name:     "script",
+			html:     `<form><script src="https://js.datadome.co/tags.js"></script></form>`,
+			want:     captcha.CaptchaTypeDatadome,
+			layer:    captcha.LayerScriptDomain,
+			evidence: "https://js.datadome.co/tags.js",
+		},
+		{
+			name:     "iframe",
+			html:     `<form><iframe src="https://www.google.com/recaptcha/api2/anchor"></iframe></form>`,
+			want:     captcha.CaptchaTypeRecaptcha,
+			layer:    captcha.LayerIframe,
+			evidence: "https://www.google.com/recaptcha/api2/anchor",
+		},
+		{
+			name:     "generic",
+			html:     `<form><img src="/img/captcha.png"/><input name="answer"/></form>`,
+			want:     captcha.CaptchaTypeOther,
+			layer:    captcha.LayerGeneric,
+			evidence: "src=/img/captcha.png",
+		},
+	}
+	detector := &captcha.CaptchaDetector{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := htmlutil.LoadHTMLString(tt.html)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := detector.Detect(htmlutil.GetForms(doc)[0])
+			if got.Type != tt.want || got.Layer != tt.layer || got.Evidence != tt.evidence {
+				t.Errorf("Detect = %+v, want {%s %s %s}", got, tt.want, tt.layer, tt.evidence)
+			}
+		})
+	}
+
+	d := captcha.DetectInHTML(`<script src="https://challenges.cloudflare.com/turnstile/v0/api.js"></script>`)
+	if d.Type != captcha.CaptchaTypeTurnstile || d.Layer != captcha.LayerHTML || d.Evidence != `src="https://challenges.cloudflare.com` {
+		t.Errorf("DetectInHTML = %+v", d)
+	}
+}
+
+func TestDetectAllLayeredProviders(t *testing.T) {
+	doc, err := htmlutil.LoadHTMLString(`<html><head>
+<script src="https://challenges.cloudflare.com/turnstile/v0/api.js"></script>
+<script src="https://js.datadome.co/tags.js"></script>
+</head><body>
+<form action="/login">
+  <input name="user"/><input type="password" name="pass"/>
+  <div class="g-recaptcha g-recaptcha-invisible" data-sitekey="x"></div>
+  <div class="cf-turnstile"></div>
+  <input name="captcha_hint"/>
+</form></body></html>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := captcha.DetectAll(doc.Selection)
+	got := make(map[captcha.CaptchaType]captcha.Result)
+	for _, r := range results {
+		got[r.Type] = r
+	}
+	for _, want := range []captcha.CaptchaType{captcha.CaptchaTypeRecaptchaInvisible, captcha.CaptchaTypeTurnstile, captcha.CaptchaTypeDatadome} {
+		if _, ok := got[want]; !ok {
+			t.Errorf("missing %s in %+v", want, results)
+		}
+	}
+	if _, ok := got[captcha.CaptchaTypeRecaptcha]; ok {
+		t.Error("base recaptcha should be folded into recaptcha-invisible")
+	}
+	if _, ok := got[captcha.CaptchaTypeOther]; ok {
+		t.Error("generic marker should not be reported next to known providers")
+	}
+
+	turnstile := got[captcha.CaptchaTypeTurnstile]
+	if len(turnstile.Signals) < 2 {
+		t.Errorf("turnstile signals = %+v, want class and html", turnstile.Signals)
+	}
+	for i := 1; i < len(results); i++ {
+		if results[i].Confidence > results[i-1].Confidence {
+			t.Errorf("results not sorted by confidence: %+v", results)
+		}
+	}
+	for _, r := range results {
+		if r.Confidence <= 0 || r.Confidence > 1 {
+			t.Errorf("%s confidence %v out of range", r.Type, r.Confidence)
+		}
+	}
+
+	none, err := htmlutil.LoadHTMLString(`<form><input name="q"/></form>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := captcha.DetectAll(none.Selection); len(r) != 0 {
+		t.Errorf("DetectAll on plain form = %+v, want none", r)
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "rules.yaml")
+	if err := os.WriteFile(yamlPath, []byte(`providers:
+  - name: acmeshield
+    priority: 50
+    match:
+      class: [acme-shield]
+      html: ['src=["''][^"'']*shield\.acme\.test']
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	jsonPath := filepath.Join(dir, "rules.json")
+	if err := os.WriteFile(jsonPath, []byte(`{"providers": [{"name": "hcaptcha", "match": {"class": ["my-hc"]}}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	extra, err := captcha.LoadRules(yamlPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	override, err := captcha.LoadRules(jsonPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := captcha.DefaultRules().With(extra).With(override)
+
+	doc, err := htmlutil.LoadHTMLString(`<form><div class="acme-shield"></div><div class="h-captcha"></div></form>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	form := htmlutil.GetForms(doc)[0]
+	detector := &captcha.CaptchaDetector{Rules: rules}
+	if got := detector.DetectInForm(form); got != "acmeshield" {
+		t.Errorf("custom provider with higher priority: got %s", got)
+	}
+	if got := (&captcha.CaptchaDetector{}).DetectInForm(form); got != captcha.CaptchaTypeHCaptcha {
+		t.Errorf("built-in rules: got %s, want hcaptcha", got)
+	}
+
+	// The JSON file replaced the built-in hcaptcha provider.
+	doc, err = htmlutil.LoadHTMLString(`<form><div class="my-hc"></div></form>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := detector.DetectInForm(htmlutil.GetForms(doc)[0]); got != captcha.CaptchaTypeHCaptcha {
+		t.Errorf("overridden provider: got %s, want hcaptcha", got)
+	}
+
+	captcha.SetRules(rules)
+	t.Cleanup(func() { captcha.SetRules(nil) })
+	if !captcha.IsValidCaptchaType("acmeshield") {
+		t.Error("custom provider should be a valid captcha type")
+	}
+	if got := captcha.DetectCaptchaInHTML(`<script src="https://shield.acme.test/v1.js"></script>`); got != "acmeshield" {
+		t.Errorf("DetectCaptchaInHTML with custom rules: got %s", got)
+	}
+
+	for name, data := range map[string]string{
+		"unknown location": `{"providers": [{"name": "x", "match": {"scripts": ["a"]}}]}`,
+		"bad regexp":       `{"providers": [{"name": "x", "match": {"script": ["("]}}]}`,
+		"reserved name":    `{"providers": [{"name": "none"}]}`,
+		"duplicate":        `{"providers": [{"name": "x"}, {"name": "x"}]}`,
+	} {
+		if _, err := captcha.ParseRules([]byte(data)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestDetectResponse(t *testing.T) {
+	header := http.Header{}
+	header.Set("CF-Mitigated", "challenge")
+	header.Add("Set-Cookie", "cf_chl_rc_m=1; Path=/")
+	header.Set("X-Amzn-Waf-Action", "captcha")
+
+	results := captcha.DetectResponse(&captcha.Response{StatusCode: http.StatusForbidden, Header: header})
+	got := make(map[captcha.CaptchaType]captcha.Result)
+	for _, r := range results {
+		got[r.Type] = r
+	}
+	cf, ok := got[captcha.CaptchaTypeCloudflare]
+	if !ok {
+		t.Fatalf("missing cloudflare in %+v", results)
+	}
+	layers := make(map[captcha.Layer]bool)
+	for _, s := range cf.Signals {
+		layers[s.Layer] = true
+	}
+	for _, l := range []captcha.Layer{captcha.LayerHeader, captcha.LayerCookie, captcha.LayerStatus} {
+		if !layers[l] {
+			t.Errorf("cloudflare signals %+v lack %s", cf.Signals, l)
+		}
+	}
+	if !cf.FromResponse() {
+		t.Error("cloudflare result should come from the response")
+	}
+	if _, ok := got[captcha.CaptchaTypeAwsWaf]; !ok {
+		t.Errorf("missing awswaf (x-amzn-waf-action header) in %+v", results)
+	}
+
+	// Headers and cookies sent on every proxied response are not evidence
+	// of a challenge, whatever the status.
+	cdn := http.Header{}
+	cdn.Set("Server", "cloudflare")
+	cdn.Set("CF-RAY", "8a1b2c3d4e5f-AMS")
+	cdn.Set("X-DataDome", "protected")
+	cdn.Set("X-Iinfo", "10-12345-0 0NNN RT(1) q(0 -1 -1 -1) r(0 -1)")
+	cdn.Add("Set-Cookie", "__cf_bm=abc; Path=/; HttpOnly")
+	cdn.Add("Set-Cookie", "cf_clearance=abc; Path=/")
+	cdn.Add("Set-Cookie", "incap_ses_123_456=xyz; Path=/")
+	cdn.Add("Set-Cookie", "_px3=xyz; Path=/")
+	for _, status := range []int{http.StatusOK, http.StatusForbidden} {
+		if r := captcha.DetectResponse(&captcha.Response{StatusCode: status, Header: cdn}); len(r) != 0 {
+			t.Errorf("CDN headers and cookies, status %d = %+v, want none", status, r)
+		}
+	}
+
+	// A block status alone identifies nothing.
+	if r := captcha.DetectResponse(&captcha.Response{StatusCode: http.StatusForbidden, Header: http.Header{}}); len(r) != 0 {
+		t.Errorf("status only = %+v, want none", r)
+	}
+	if r := captcha.DetectResponse(nil); len(r) != 0 {
+		t.Errorf("nil response = %+v, want none", r)
+	}
+
+	// Response signals add to those found in the page.
+	doc, err := htmlutil.LoadHTMLString(`<html><body><form id="challenge-form" action="/"></form></body></html>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	challenge := http.Header{}
+	challenge.Set("CF-Mitigated", "challenge")
+	combined := captcha.DetectAllWithResponse(doc.Selection, &captcha.Response{StatusCode: http.StatusForbidden, Header: challenge})
+	if len(combined) != 1 || combined[0].Type != captcha.CaptchaTypeCloudflare {
+		t.Fatalf("combined = %+v, want cloudflare only", combined)
+	}
+	if htmlOnly := captcha.DetectAll(doc.Selection); combined[0].Confidence <= htmlOnly[0].Confidence {
+		t.Errorf("response signals did not raise confidence: %v <= %v", combined[0].Confidence, htmlOnly[0].Confidence)
+	}
+}
@@ -0,0 +1,126 @@
+package captcha
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/happyhackingspace/dit/internal/respmatch"
+)
+
+// layerWeights is the confidence contributed by a single signal from each
+// layer. Signals are combined as independent evidence (noisy-OR).
+var layerWeights = map[Layer]float64{
+	LayerScriptDomain:  0.95,
+	LayerIframe:        0.95,
+	LayerClass:         0.9,
+	LayerHTML:          0.85,
+	LayerDataAttribute: 0.8,
+	LayerID:            0.7,
+	LayerAlt:           0.6,
+	LayerFieldName:     0.6,
+	LayerGeneric:       0.3,
+	LayerHeader:        0.9,
+	LayerCookie:        0.85,
+	LayerStatus:        0.3,
+}
+
+// variantOf maps provider variants to their base provider. When both are
+// detected, the base provider's signals are attributed to the variant.
+var variantOf = map[CaptchaType]CaptchaType{
+	CaptchaTypeRecaptchaV2:        CaptchaTypeRecaptcha,
+	CaptchaTypeRecaptchaInvisible: CaptchaTypeRecaptcha,
+	CaptchaTypeSmartCaptcha:       CaptchaTypeYandex,
+}
+
+// Signal is a single piece of evidence for a provider.
+type Signal struct {
+	Layer    Layer  `json:"layer"`
+	Evidence string `json:"evidence"`
+}
+
+// Result is a provider reported by DetectAll.
+type Result struct {
+	Type       CaptchaType `json:"type"`
+	Confidence float64     `json:"confidence"` // 0..1
+	Signals    []Signal    `json:"signals"`
+}
+
+// DetectAll runs every detection layer on s, which may be a form or a
+// whole document (doc.Selection), and returns every provider found, most
+// confident first. Unlike DetectInForm it does not stop at the first
+// match, so pages that combine providers (e.g. reCAPTCHA behind Turnstile)
+// report all of them. The generic "other" type is only reported when no
+// known provider matched.
+func DetectAll(s *goquery.Selection) []Result {
+	return mergeDetections(detectAll(CurrentRules(), s))
+}
+
+// detectAll returns the detections of every form layer and of the HTML
+// patterns on s.
+func detectAll(rules *Rules, s *goquery.Selection) []Detection {
+	var detections []Detection
+	for _, layer := range formLayers {
+		detections = append(detections, layer(rules, s)...)
+	}
+	if html, err := goquery.OuterHtml(s); err == nil {
+		detections = append(detections, detectAllInHTML(rules, html)...)
+	}
+	return detections
+}
+
+// mergeDetections groups detections by provider and scores each provider.
+func mergeDetections(detections []Detection) []Result {
+	signals := make(map[CaptchaType][]Signal)
+	var order []CaptchaType
+	for _, d := range detections {
+		if !d.Found() {
+			continue
+		}
+		if _, ok := signals[d.Type]; !ok {
+			order = append(order, d.Type)
+		}
+		sig := Signal{Layer: d.Layer, Evidence: d.Evidence}
+		if !slices.Contains(signals[d.Type], sig) {
+			signals[d.Type] = append(signals[d.Type], sig)
+		}
+	}
+
+	for _, variant := range order {
+		base, ok := variantOf[variant]
+		if !ok || signals[base] == nil {
+			continue
+		}
+		for _, sig := range signals[base] {
+			if !slices.Contains(signals[variant], sig) {
+				signals[variant] = append(signals[variant], sig)
+			}
+		}
+		delete(signals, base)
+	}
+	if len(signals) > 1 {
+		delete(signals, CaptchaTypeOther)
+	}
+
+	var results []Result
+	for _, t := range order {
+		sigs, ok := signals[t]
+		if !ok {
+			continue
+		}
+		results = append(results, Result{Type: t, Confidence: confidence(sigs), Signals: sigs})
+	}
+	slices.SortStableFunc(results, func(a, b Result) int {
+		return cmp.Compare(b.Confidence, a.Confidence)
+	})
+	return results
+}
+
+// confidence combines signal weights with a noisy-OR.
+func confidence(signals []Signal) float64 {
+	weights := make([]float64, len(signals))
+	for i, s := range signals {
+		weights[i] = layerWeights[s.Layer]
+	}
+	return respmatch.NoisyOR(weights)
+}
@@ -0,0 +1,82 @@
+package captcha
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/happyhackingspace/dit/internal/respmatch"
+)
+
+// Response is the HTTP response metadata a page was served with.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+}
+
+// DetectResponse returns the providers identified by response headers,
+// Set-Cookie names and status code. A status code alone never identifies
+// a provider; it only adds a signal to providers found by other means.
+func DetectResponse(resp *Response) []Result {
+	return mergeDetections(detectResponse(CurrentRules(), resp, nil))
+}
+
+// DetectAllWithResponse is DetectAll combined with the response signals of
+// DetectResponse. resp may be nil.
+func DetectAllWithResponse(s *goquery.Selection, resp *Response) []Result {
+	rules := CurrentRules()
+	detections := detectAll(rules, s)
+	detections = append(detections, detectResponse(rules, resp, detections)...)
+	return mergeDetections(detections)
+}
+
+// detectResponse matches header, cookie and status rules. Status signals
+// are only reported for providers present in found or in the header and
+// cookie matches.
+func detectResponse(rules *Rules, resp *Response, found []Detection) []Detection {
+	if resp == nil {
+		return nil
+	}
+
+	var out []Detection
+	for _, m := range rules.headers {
+		for _, hm := range m.headers {
+			if ev, ok := hm.Match(resp.Header); ok {
+				out = append(out, Detection{Type: m.captchaType, Layer: LayerHeader, Evidence: ev})
+				break
+			}
+		}
+	}
+
+	cookies := respmatch.CookieNames(resp.Header)
+	for _, m := range rules.layers[LayerCookie] {
+		for _, prefix := range m.substrings {
+			if name, ok := respmatch.MatchCookie(prefix, cookies); ok {
+				out = append(out, Detection{Type: m.captchaType, Layer: LayerCookie, Evidence: name})
+				break
+			}
+		}
+	}
+
+	if resp.StatusCode != 0 {
+		present := make(map[CaptchaType]bool)
+		for _, d := range append(found, out...) {
+			present[d.Type] = true
+		}
+		for _, p := range rules.statuses {
+			if present[p.captchaType] && slices.Contains(p.codes, resp.StatusCode) {
+				out = append(out, Detection{Type: p.captchaType, Layer: LayerStatus, Evidence: strconv.Itoa(resp.StatusCode)})
+			}
+		}
+	}
+	return out
+}
+
+// FromResponse reports whether r is supported by a response header or
+// cookie, as opposed to only the page content and status code.
+func (r Result) FromResponse() bool {
+	return slices.ContainsFunc(r.Signals, func(s Signal) bool {
+		return s.Layer == LayerHeader || s.Layer == LayerCookie
+	})
+}
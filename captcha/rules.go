@@ -0,0 +1,247 @@
+package captcha
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/happyhackingspace/dit/internal/respmatch"
+)
+
+//go:embed rules.yaml
+var defaultRulesYAML []byte
+
+// Rules is a set of CAPTCHA and bot-protection provider signatures. Rules
+// are written in YAML or JSON; see rules.yaml for the built-in set and the
+// meaning of each matcher location.
+type Rules struct {
+	Providers []ProviderRule `yaml:"providers" json:"providers"`
+
+	layers   map[Layer][]compiledMatcher
+	headers  []headerRule
+	statuses []statusRule
+}
+
+// ProviderRule describes how to recognise one provider.
+type ProviderRule struct {
+	Name     string   `yaml:"name" json:"name"`
+	Priority int      `yaml:"priority,omitempty" json:"priority,omitempty"` // higher is tried first
+	Match    Matchers `yaml:"match" json:"match"`
+}
+
+// Matchers lists patterns by location. Script and HTML patterns are
+// regular expressions; the others are substrings. All are matched against
+// lowercased input. Header entries are a header name, optionally followed
+// by ":" and a regular expression for its value; cookie entries are cookie
+// name prefixes; status entries are response codes that support, but never
+// establish, a detection.
+type Matchers struct {
+	Class  []string `yaml:"class,omitempty" json:"class,omitempty"`
+	Script []string `yaml:"script,omitempty" json:"script,omitempty"`
+	Data   []string `yaml:"data,omitempty" json:"data,omitempty"`
+	ID     []string `yaml:"id,omitempty" json:"id,omitempty"`
+	Alt    []string `yaml:"alt,omitempty" json:"alt,omitempty"`
+	Field  []string `yaml:"field,omitempty" json:"field,omitempty"`
+	Iframe []string `yaml:"iframe,omitempty" json:"iframe,omitempty"`
+	HTML   []string `yaml:"html,omitempty" json:"html,omitempty"`
+	Header []string `yaml:"header,omitempty" json:"header,omitempty"`
+	Cookie []string `yaml:"cookie,omitempty" json:"cookie,omitempty"`
+	Status []int    `yaml:"status,omitempty" json:"status,omitempty"`
+}
+
+// compiledMatcher holds the patterns of one provider for one layer.
+type compiledMatcher struct {
+	captchaType CaptchaType
+	substrings  []string
+	patterns    []*regexp.Regexp
+}
+
+// headerRule holds the header matchers of one provider.
+type headerRule struct {
+	captchaType CaptchaType
+	headers     []respmatch.Header
+}
+
+// statusRule holds the block status codes of one provider.
+type statusRule struct {
+	captchaType CaptchaType
+	codes       []int
+}
+
+// match returns the first pattern of m found in s, or "".
+func (m compiledMatcher) match(s string) string {
+	for _, sub := range m.substrings {
+		if strings.Contains(s, sub) {
+			return sub
+		}
+	}
+	for _, re := range m.patterns {
+		if found := re.FindString(s); found != "" {
+			return found
+		}
+	}
+	return ""
+}
+
+var defaultRules = sync.OnceValue(func() *Rules {
+	r, err := ParseRules(defaultRulesYAML)
+	if err != nil {
+		panic("captcha: invalid built-in rules: " + err.Error())
+	}
+	return r
+})
+
+// activeRules overrides the built-in rules when set by SetRules.
+var activeRules atomic.Pointer[Rules]
+
+// DefaultRules returns the built-in rules embedded in the binary.
+func DefaultRules() *Rules {
+	return defaultRules()
+}
+
+// SetRules replaces the rules used by package-level detection functions
+// and by detectors without their own rules. Passing nil restores the
+// built-in rules.
+func SetRules(r *Rules) {
+	activeRules.Store(r)
+}
+
+// CurrentRules returns the rules in effect for package-level detection.
+func CurrentRules() *Rules {
+	if r := activeRules.Load(); r != nil {
+		return r
+	}
+	return DefaultRules()
+}
+
+// LoadRules reads rules from a YAML or JSON file.
+func LoadRules(path string) (*Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("load captcha rules: %w", err)
+	}
+	r, err := ParseRules(data)
+	if err != nil {
+		return nil, fmt.Errorf("load captcha rules %s: %w", path, err)
+	}
+	return r, nil
+}
+
+// ParseRules parses rules in YAML or JSON and compiles their patterns.
+// Unknown keys are rejected so that misspelt locations are not silently
+// ignored.
+func ParseRules(data []byte) (*Rules, error) {
+	var r Rules
+	if err := respmatch.Decode(data, &r); err != nil {
+		return nil, err
+	}
+	if err := r.compile(); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// With returns the rules of r extended by extra. Providers in extra
+// replace those of r with the same name; new providers are added.
+func (r *Rules) With(extra *Rules) *Rules {
+	out := &Rules{Providers: slices.Clone(r.Providers)}
+	for _, p := range extra.Providers {
+		if i := slices.IndexFunc(out.Providers, func(q ProviderRule) bool { return q.Name == p.Name }); i >= 0 {
+			out.Providers[i] = p
+		} else {
+			out.Providers = append(out.Providers, p)
+		}
+	}
+	if err := out.compile(); err != nil {
+		// Both inputs compiled successfully, so their patterns are valid.
+		panic("captcha: " + err.Error())
+	}
+	return out
+}
+
+// Has reports whether r defines a provider named name.
+func (r *Rules) Has(name CaptchaType) bool {
+	return slices.ContainsFunc(r.Providers, func(p ProviderRule) bool { return CaptchaType(p.Name) == name })
+}
+
+// compile validates the providers and builds the per-layer matchers,
+// ordered by descending priority and then by position in the file.
+func (r *Rules) compile() error {
+	seen := make(map[string]bool)
+	for _, p := range r.Providers {
+		switch {
+		case p.Name == "":
+			return fmt.Errorf("provider without a name")
+		case p.Name == string(CaptchaTypeNone) || p.Name == string(CaptchaTypeOther):
+			return fmt.Errorf("provider name %q is reserved", p.Name)
+		case seen[p.Name]:
+			return fmt.Errorf("duplicate provider %q", p.Name)
+		}
+		seen[p.Name] = true
+	}
+
+	providers := slices.Clone(r.Providers)
+	slices.SortStableFunc(providers, func(a, b ProviderRule) int { return b.Priority - a.Priority })
+
+	r.layers = make(map[Layer][]compiledMatcher)
+	r.headers, r.statuses = nil, nil
+	for _, p := range providers {
+		ct := CaptchaType(p.Name)
+		add := func(layer Layer, substrings []string) {
+			if len(substrings) == 0 {
+				return
+			}
+			lower := make([]string, len(substrings))
+			for i, s := range substrings {
+				lower[i] = strings.ToLower(s)
+			}
+			r.layers[layer] = append(r.layers[layer], compiledMatcher{captchaType: ct, substrings: lower})
+		}
+		addRegexps := func(layer Layer, exprs []string) error {
+			if len(exprs) == 0 {
+				return nil
+			}
+			m := compiledMatcher{captchaType: ct}
+			for _, expr := range exprs {
+				re, err := regexp.Compile(expr)
+				if err != nil {
+					return fmt.Errorf("provider %q: %s pattern: %w", p.Name, layer, err)
+				}
+				m.patterns = append(m.patterns, re)
+			}
+			r.layers[layer] = append(r.layers[layer], m)
+			return nil
+		}
+
+		add(LayerClass, p.Match.Class)
+		add(LayerDataAttribute, p.Match.Data)
+		add(LayerID, p.Match.ID)
+		add(LayerAlt, p.Match.Alt)
+		add(LayerFieldName, p.Match.Field)
+		add(LayerIframe, p.Match.Iframe)
+		if err := addRegexps(LayerScriptDomain, p.Match.Script); err != nil {
+			return err
+		}
+		if err := addRegexps(LayerHTML, p.Match.HTML); err != nil {
+			return err
+		}
+		add(LayerCookie, p.Match.Cookie)
+
+		if len(p.Match.Header) > 0 {
+			headers, err := respmatch.ParseHeaders(p.Match.Header)
+			if err != nil {
+				return fmt.Errorf("provider %q: %w", p.Name, err)
+			}
+			r.headers = append(r.headers, headerRule{captchaType: ct, headers: headers})
+		}
+		if len(p.Match.Status) > 0 {
+			r.statuses = append(r.statuses, statusRule{captchaType: ct, codes: p.Match.Status})
+		}
+	}
+	return nil
+}
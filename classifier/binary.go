@@ -0,0 +1,398 @@
+package classifier
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/happyhackingspace/dit/crf"
+	"github.com/happyhackingspace/dit/internal/vectorizer"
+)
+
+// binaryMagic starts every binary model file.
+var binaryMagic = [4]byte{'D', 'I', 'T', 'B'}
+
+// binaryVersion is the current binary model format version.
+const binaryVersion uint32 = 1
+
+// binaryModel is the on-disk layout of a binary model. Vocabularies are
+// stored as indices into a shared string table, linear model weights as
+// dense row-major arrays and CRF state weights sparsely.
+type binaryModel struct {
+	Strings []string
+	Form    *binaryLinearModel
+	Page    *binaryLinearModel
+	Field   *binaryCRF
+	Meta    *ModelMeta
+
+	PageMultiLabel *MultiLabelModel
+}
+
+type binaryLinearModel struct {
+	Classes     []string
+	NumFeatures int
+	Coef        []float64 // [len(Classes) * NumFeatures]
+	Intercept   []float64
+	Pipelines   []binaryPipeline
+	Temperature float64
+}
+
+type binaryPipeline struct {
+	Name          string
+	ExtractorType string
+	VecType       string
+	Terms         []uint32 // vocabulary in feature index order
+	NgramRange    [2]int
+	Binary        bool
+	Analyzer      string
+	MinDF         int
+	IDF           []float64
+	StopWords     []uint32
+}
+
+type binaryCRF struct {
+	Labels       []string
+	Attributes   []uint32
+	NumLabels    int
+	StateIndex   []uint32 // indices of non-zero state weights
+	StateWeights []float64
+	Transitions  []float64
+}
+
+// IsBinaryModel reports whether data starts with the binary model header.
+func IsBinaryModel(data []byte) bool {
+	return len(data) >= len(binaryMagic) && bytes.Equal(data[:len(binaryMagic)], binaryMagic[:])
+}
+
+// SaveModelBinary saves the classifier in the compact binary format.
+// CRF attributes whose weights are all zero are pruned.
+func (c *FormFieldClassifier) SaveModelBinary(path string) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("create directory: %w", err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create model file: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	if err := c.WriteBinary(w); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("write model: %w", err)
+	}
+	return f.Close()
+}
+
+// WriteBinary writes the classifier to w in the compact binary format.
+func (c *FormFieldClassifier) WriteBinary(w io.Writer) error {
+	enc := &binaryEncoder{ids: make(map[string]uint32)}
+	bm := binaryModel{Meta: c.metaForSave()}
+
+	var err error
+	if c.FormModel != nil {
+		if bm.Form, err = enc.linearModel(c.FormModel.Classes, c.FormModel.Coef, c.FormModel.Intercept, c.FormModel.Pipelines); err != nil {
+			return fmt.Errorf("encode form model: %w", err)
+		}
+		bm.Form.Temperature = c.FormModel.Temperature
+	}
+	if c.PageModel != nil {
+		if bm.Page, err = enc.linearModel(c.PageModel.Classes, c.PageModel.Coef, c.PageModel.Intercept, c.PageModel.Pipelines); err != nil {
+			return fmt.Errorf("encode page model: %w", err)
+		}
+		bm.Page.Temperature = c.PageModel.Temperature
+		bm.PageMultiLabel = c.PageModel.MultiLabel
+	}
+	if c.FieldModel != nil && c.FieldModel.CRF != nil {
+		bm.Field = enc.crfModel(c.FieldModel.CRF.Prune())
+	}
+	bm.Strings = enc.strings
+
+	if _, err := w.Write(binaryMagic[:]); err != nil {
+		return fmt.Errorf("write model: %w", err)
+	}
+	if err := binary.Write(w, binary.LittleEndian, binaryVersion); err != nil {
+		return fmt.Errorf("write model: %w", err)
+	}
+	if err := gob.NewEncoder(w).Encode(&bm); err != nil {
+		return fmt.Errorf("encode model: %w", err)
+	}
+	return nil
+}
+
+// ReadBinary reads a classifier in the compact binary format from r. The
+// vocabulary and CRF attribute indexes are built on first use, so models
+// that are loaded but not run (or only partly run) skip that cost.
+func ReadBinary(r io.Reader) (*FormFieldClassifier, error) {
+	var magic [4]byte
+	if _, err := io.ReadFull(r, magic[:]); err != nil || magic != binaryMagic {
+		return nil, fmt.Errorf("not a binary model")
+	}
+	var version uint32
+	if err := binary.Read(r, binary.LittleEndian, &version); err != nil {
+		return nil, fmt.Errorf("read model header: %w", err)
+	}
+	if version != binaryVersion {
+		return nil, fmt.Errorf("unsupported binary model version %d (want %d)", version, binaryVersion)
+	}
+
+	var bm binaryModel
+	if err := gob.NewDecoder(r).Decode(&bm); err != nil {
+		return nil, fmt.Errorf("decode model: %w", err)
+	}
+
+	dec := binaryDecoder{strings: bm.Strings}
+	c := &FormFieldClassifier{Meta: bm.Meta}
+	if bm.Form != nil {
+		classes, coef, intercept, pipelines, err := dec.linearModel(bm.Form)
+		if err != nil {
+			return nil, fmt.Errorf("decode form model: %w", err)
+		}
+		c.FormModel = &FormTypeModel{Classes: classes, Coef: coef, Intercept: intercept, Pipelines: pipelines, Temperature: bm.Form.Temperature}
+		c.FormModel.InitRuntime()
+	}
+	if bm.Page != nil {
+		classes, coef, intercept, pipelines, err := dec.linearModel(bm.Page)
+		if err != nil {
+			return nil, fmt.Errorf("decode page model: %w", err)
+		}
+		c.PageModel = &PageTypeModel{Classes: classes, Coef: coef, Intercept: intercept, Pipelines: pipelines, Temperature: bm.Page.Temperature, MultiLabel: bm.PageMultiLabel}
+		c.PageModel.InitRuntime()
+	}
+	if bm.Field != nil {
+		model, err := dec.crfModel(bm.Field)
+		if err != nil {
+			return nil, fmt.Errorf("decode field model: %w", err)
+		}
+		c.FieldModel = &FieldTypeModel{CRF: model}
+	}
+	return c, nil
+}
+
+// binaryEncoder interns strings while converting models to binaryModel.
+type binaryEncoder struct {
+	strings []string
+	ids     map[string]uint32
+}
+
+func (e *binaryEncoder) intern(s string) uint32 {
+	if id, ok := e.ids[s]; ok {
+		return id
+	}
+	id := uint32(len(e.strings))
+	e.ids[s] = id
+	e.strings = append(e.strings, s)
+	return id
+}
+
+func (e *binaryEncoder) internAll(ss []string) []uint32 {
+	ids := make([]uint32, len(ss))
+	for i, s := range ss {
+		ids[i] = e.intern(s)
+	}
+	return ids
+}
+
+func (e *binaryEncoder) linearModel(classes []string, coef [][]float64, intercept []float64, pipelines []SerializedPipeline) (*binaryLinearModel, error) {
+	lm := &binaryLinearModel{
+		Classes:   classes,
+		Intercept: intercept,
+	}
+	if len(coef) > 0 {
+		lm.NumFeatures = len(coef[0])
+	}
+	lm.Coef = make([]float64, 0, len(coef)*lm.NumFeatures)
+	for i, row := range coef {
+		if len(row) != lm.NumFeatures {
+			return nil, fmt.Errorf("coef row %d has %d features, want %d", i, len(row), lm.NumFeatures)
+		}
+		lm.Coef = append(lm.Coef, row...)
+	}
+
+	for _, p := range pipelines {
+		bp := binaryPipeline{
+			Name:          p.Name,
+			ExtractorType: p.ExtractorType,
+			VecType:       p.VecType,
+		}
+		switch p.VecType {
+		case "dict":
+			bp.Terms = e.internAll(p.DictVec.FeatureNames)
+		case "count":
+			terms, err := vocabularyTerms(p.CountVec.Index())
+			if err != nil {
+				return nil, fmt.Errorf("pipeline %q: %w", p.Name, err)
+			}
+			bp.Terms = e.internAll(terms)
+			bp.NgramRange = p.CountVec.NgramRange
+			bp.Binary = p.CountVec.Binary
+			bp.Analyzer = p.CountVec.Analyzer
+			bp.MinDF = p.CountVec.MinDF
+		case "tfidf":
+			terms, err := vocabularyTerms(p.TfidfVec.CountVec.Index())
+			if err != nil {
+				return nil, fmt.Errorf("pipeline %q: %w", p.Name, err)
+			}
+			bp.Terms = e.internAll(terms)
+			bp.NgramRange = p.TfidfVec.CountVec.NgramRange
+			bp.Binary = p.TfidfVec.CountVec.Binary
+			bp.Analyzer = p.TfidfVec.CountVec.Analyzer
+			bp.MinDF = p.TfidfVec.CountVec.MinDF
+			bp.IDF = p.TfidfVec.IDF
+			for _, w := range slices.Sorted(maps.Keys(p.TfidfVec.StopWords)) {
+				if p.TfidfVec.StopWords[w] {
+					bp.StopWords = append(bp.StopWords, e.intern(w))
+				}
+			}
+		default:
+			return nil, fmt.Errorf("pipeline %q: unknown vectorizer type %q", p.Name, p.VecType)
+		}
+		lm.Pipelines = append(lm.Pipelines, bp)
+	}
+	return lm, nil
+}
+
+func (e *binaryEncoder) crfModel(m *crf.Model) *binaryCRF {
+	bc := &binaryCRF{
+		Labels:     m.Labels.ToStr,
+		Attributes: e.internAll(m.Attributes.ToStr),
+		NumLabels:  m.NumLabels,
+	}
+	transOffset := min(m.TransOffset(), len(m.Weights))
+	for i, w := range m.Weights[:transOffset] {
+		if w != 0 {
+			bc.StateIndex = append(bc.StateIndex, uint32(i))
+			bc.StateWeights = append(bc.StateWeights, w)
+		}
+	}
+	bc.Transitions = m.Weights[transOffset:]
+	return bc
+}
+
+// vocabularyTerms returns the terms of a vocabulary ordered by index.
+func vocabularyTerms(vocab map[string]int) ([]string, error) {
+	terms := make([]string, len(vocab))
+	seen := make([]bool, len(vocab))
+	for term, idx := range vocab {
+		if idx < 0 || idx >= len(terms) || seen[idx] {
+			return nil, fmt.Errorf("vocabulary indices are not dense")
+		}
+		terms[idx] = term
+		seen[idx] = true
+	}
+	return terms, nil
+}
+
+// binaryDecoder resolves string table references while rebuilding models.
+type binaryDecoder struct {
+	strings []string
+}
+
+func (d binaryDecoder) lookup(ids []uint32) ([]string, error) {
+	out := make([]string, len(ids))
+	for i, id := range ids {
+		if int(id) >= len(d.strings) {
+			return nil, fmt.Errorf("string id %d out of range", id)
+		}
+		out[i] = d.strings[id]
+	}
+	return out, nil
+}
+
+func (d binaryDecoder) linearModel(lm *binaryLinearModel) ([]string, [][]float64, []float64, []SerializedPipeline, error) {
+	if len(lm.Coef) != len(lm.Classes)*lm.NumFeatures {
+		return nil, nil, nil, nil, fmt.Errorf("coef has %d weights, want %d", len(lm.Coef), len(lm.Classes)*lm.NumFeatures)
+	}
+	coef := make([][]float64, len(lm.Classes))
+	for i := range coef {
+		coef[i] = lm.Coef[i*lm.NumFeatures : (i+1)*lm.NumFeatures : (i+1)*lm.NumFeatures]
+	}
+
+	pipelines := make([]SerializedPipeline, len(lm.Pipelines))
+	for i, bp := range lm.Pipelines {
+		terms, err := d.lookup(bp.Terms)
+		if err != nil {
+			return nil, nil, nil, nil, fmt.Errorf("pipeline %q: %w", bp.Name, err)
+		}
+		p := SerializedPipeline{
+			Name:          bp.Name,
+			ExtractorType: bp.ExtractorType,
+			VecType:       bp.VecType,
+		}
+		switch bp.VecType {
+		case "dict":
+			p.DictVec = &vectorizer.DictVectorizer{FeatureNames: terms}
+		case "count":
+			p.CountVec = d.countVectorizer(bp, terms)
+		case "tfidf":
+			stopWords, err := d.lookup(bp.StopWords)
+			if err != nil {
+				return nil, nil, nil, nil, fmt.Errorf("pipeline %q: %w", bp.Name, err)
+			}
+			p.TfidfVec = &vectorizer.TfidfVectorizer{
+				CountVec: d.countVectorizer(bp, terms),
+				IDF:      bp.IDF,
+			}
+			if len(stopWords) > 0 {
+				p.TfidfVec.StopWords = make(map[string]bool, len(stopWords))
+				for _, w := range stopWords {
+					p.TfidfVec.StopWords[w] = true
+				}
+			}
+		default:
+			return nil, nil, nil, nil, fmt.Errorf("pipeline %q: unknown vectorizer type %q", bp.Name, bp.VecType)
+		}
+		pipelines[i] = p
+	}
+	return lm.Classes, coef, lm.Intercept, pipelines, nil
+}
+
+func (d binaryDecoder) countVectorizer(bp binaryPipeline, terms []string) *vectorizer.CountVectorizer {
+	cv := &vectorizer.CountVectorizer{
+		NgramRange: bp.NgramRange,
+		Binary:     bp.Binary,
+		Analyzer:   bp.Analyzer,
+		MinDF:      bp.MinDF,
+	}
+	cv.SetTerms(terms)
+	return cv
+}
+
+func (d binaryDecoder) crfModel(bc *binaryCRF) (*crf.Model, error) {
+	attrs, err := d.lookup(bc.Attributes)
+	if err != nil {
+		return nil, err
+	}
+	m := &crf.Model{
+		Labels:     &crf.Alphabet{ToStr: bc.Labels},
+		Attributes: &crf.Alphabet{ToStr: attrs},
+		NumLabels:  bc.NumLabels,
+	}
+	if len(bc.Transitions) != m.NumLabels*m.NumLabels {
+		return nil, fmt.Errorf("transitions have %d weights, want %d", len(bc.Transitions), m.NumLabels*m.NumLabels)
+	}
+	if len(bc.StateIndex) != len(bc.StateWeights) {
+		return nil, fmt.Errorf("state weights are truncated")
+	}
+	transOffset := m.TransOffset()
+	m.Weights = make([]float64, m.NumWeights())
+	for i, idx := range bc.StateIndex {
+		if int(idx) >= transOffset {
+			return nil, fmt.Errorf("state weight index %d out of range", idx)
+		}
+		m.Weights[idx] = bc.StateWeights[i]
+	}
+	copy(m.Weights[transOffset:], bc.Transitions)
+	return m, nil
+}
@@ -0,0 +1,75 @@
+package classifier
+
+import "math"
+
+// Unknown is the type given to forms and pages whose most likely type is
+// less probable than the requested minimum confidence.
+const Unknown = "unknown"
+
+// Search range of FitTemperature.
+const (
+	minTemperature = 0.05
+	maxTemperature = 20.0
+)
+
+// FitTemperature returns the temperature T that minimises the negative
+// log-likelihood of labels under softmax(logits / T). Fitted on held-out
+// predictions, dividing logits by T makes probabilities calibrated: of
+// the predictions made with probability p, about a fraction p is right.
+// Rows of logits may differ in length; labels index into their row. It
+// returns 1 when there is nothing to fit.
+func FitTemperature(logits [][]float64, labels []int) float64 {
+	if len(logits) == 0 {
+		return 1
+	}
+	nll := func(logT float64) float64 {
+		t := math.Exp(logT)
+		loss := 0.0
+		for i, row := range logits {
+			probs := softmax(scaleLogits(row, t))
+			loss -= math.Log(max(probs[labels[i]], 1e-15))
+		}
+		return loss
+	}
+
+	// Golden-section search on log T; the loss is unimodal in T.
+	const invPhi = 0.6180339887498949
+	a, b := math.Log(minTemperature), math.Log(maxTemperature)
+	c, d := b-invPhi*(b-a), a+invPhi*(b-a)
+	fc, fd := nll(c), nll(d)
+	for b-a > 1e-4 {
+		if fc < fd {
+			b, d, fd = d, c, fc
+			c = b - invPhi*(b-a)
+			fc = nll(c)
+		} else {
+			a, c, fc = c, d, fd
+			d = a + invPhi*(b-a)
+			fd = nll(d)
+		}
+	}
+	return math.Exp((a + b) / 2)
+}
+
+// scaleLogits divides logits by temperature. A temperature of 0 stands for
+// an uncalibrated model and leaves them unchanged.
+func scaleLogits(logits []float64, temperature float64) []float64 {
+	if temperature <= 0 || temperature == 1 {
+		return logits
+	}
+	scaled := make([]float64, len(logits))
+	for i, l := range logits {
+		scaled[i] = l / temperature
+	}
+	return scaled
+}
+
+// confidentLabel returns the most likely label of proba, or Unknown if its
+// probability is below minConfidence.
+func confidentLabel(proba map[string]float64, minConfidence float64) string {
+	label := argmax(proba)
+	if proba[label] < minConfidence {
+		return Unknown
+	}
+	return label
+}
@@ -1,6 +1,8 @@
 package classifier
 
 import (
+	"context"
+	"io"
 	"strings"
 
 	"github.com/PuerkitoBio/goquery"
@@ -12,111 +14,162 @@ type FormFieldClassifier struct {
 	FormModel  *FormTypeModel
 	FieldModel *FieldTypeModel
 	PageModel  *PageTypeModel
+	Meta       *ModelMeta
 }
 
-// ClassifyResult holds the classification result for a form.
+// ClassifyResult holds the classification result for a form. For pages,
+// Form is the page type and Types, set by a multi-label page model, lists
+// every type of the page.
 type ClassifyResult struct {
-	Form   string            `json:"form"`
-	Fields map[string]string `json:"fields,omitempty"`
+	Form         string            `json:"form"`
+	Types        []string          `json:"types,omitempty"`
+	Fields       map[string]string `json:"fields,omitempty"`
+	FieldDetails []FieldResult     `json:"field_details,omitempty"`
 }
 
 // ClassifyProbaResult holds probability-based classification results.
+// For pages, Types is as for ClassifyResult.
 type ClassifyProbaResult struct {
-	Form   map[string]float64            `json:"form"`
-	Fields map[string]map[string]float64 `json:"fields,omitempty"`
+	Form         map[string]float64            `json:"form"`
+	Types        []string                      `json:"types,omitempty"`
+	Fields       map[string]map[string]float64 `json:"fields,omitempty"`
+	FieldDetails []FieldResult                 `json:"field_details,omitempty"`
 }
 
 // Classify returns the form type and field types.
 func (c *FormFieldClassifier) Classify(form *goquery.Selection, fields bool) ClassifyResult {
-	formType := c.FormModel.Classify(form)
+	return c.classifyAs(form, c.FormModel.Classify(form), fields, nil)
+}
+
+// classifyAs is Classify for a form already known to be of formType. ids
+// counts the ids of the document, or is nil to count them when needed.
+func (c *FormFieldClassifier) classifyAs(form *goquery.Selection, formType string, fields bool, ids htmlutil.IDCounts) ClassifyResult {
 	result := ClassifyResult{Form: formType}
 	if fields && c.FieldModel != nil {
-		result.Fields = c.FieldModel.Classify(form, formType)
+		result.FieldDetails = c.FieldModel.classifyFields(form, formType, ids)
+		result.Fields = fieldTypeMap(result.FieldDetails)
 	}
 	return result
 }
 
 // ClassifyProba returns probabilities for form and field types.
 func (c *FormFieldClassifier) ClassifyProba(form *goquery.Selection, threshold float64, fields bool) ClassifyProbaResult {
+	result, _ := c.classifyProba(form, threshold, fields, nil)
+	return result
+}
+
+// classifyProba is ClassifyProba that also returns the most likely form
+// type, taken before thresholding. ids is as for classifyAs.
+func (c *FormFieldClassifier) classifyProba(form *goquery.Selection, threshold float64, fields bool, ids htmlutil.IDCounts) (ClassifyProbaResult, string) {
 	formProba := c.FormModel.ClassifyProba(form)
-	filtered := thresholdMap(formProba, threshold)
-	result := ClassifyProbaResult{Form: filtered}
+	formType := argmax(formProba)
+	result := ClassifyProbaResult{Form: thresholdMap(formProba, threshold)}
 
 	if fields && c.FieldModel != nil {
 		// Use most likely form type for field classification
-		bestFormType := ""
-		bestProb := -1.0
-		for cls, prob := range formProba {
-			if prob > bestProb {
-				bestProb = prob
-				bestFormType = cls
-			}
-		}
-		fieldProba := c.FieldModel.ClassifyProba(form, bestFormType)
-		result.Fields = make(map[string]map[string]float64)
-		for name, probs := range fieldProba {
-			result.Fields[name] = thresholdMap(probs, threshold)
+		details := c.FieldModel.classifyFieldsProba(form, formType, ids)
+		for i := range details {
+			details[i].Proba = thresholdMap(details[i].Proba, threshold)
 		}
+		result.FieldDetails = details
+		result.Fields = fieldProbaMap(details)
 	}
 
-	return result
+	return result, formType
 }
 
 // ClassifyPage classifies the page type using form results as features.
-func (c *FormFieldClassifier) ClassifyPage(doc *goquery.Document) string {
+// pageURL may be empty if the document was not fetched from a URL.
+func (c *FormFieldClassifier) ClassifyPage(doc *goquery.Document, pageURL string) string {
 	formResults := c.classifyFormsOnDoc(doc)
-	return c.PageModel.Classify(doc, formResults)
+	return c.PageModel.Classify(doc, formResults, pageURL)
 }
 
 // ClassifyPageProba returns page type probabilities.
-func (c *FormFieldClassifier) ClassifyPageProba(doc *goquery.Document, threshold float64) map[string]float64 {
+func (c *FormFieldClassifier) ClassifyPageProba(doc *goquery.Document, pageURL string, threshold float64) map[string]float64 {
 	formResults := c.classifyFormsOnDoc(doc)
-	proba := c.PageModel.ClassifyProba(doc, formResults)
+	proba := c.PageModel.ClassifyProba(doc, formResults, pageURL)
 	return thresholdMap(proba, threshold)
 }
 
+// PageOptions controls page extraction.
+type PageOptions struct {
+	Proba          bool    // return probabilities instead of labels
+	Threshold      float64 // minimum probability kept when Proba is set
+	ClassifyFields bool    // also classify the fields of each form
+	URL            string  // page URL, used by the "page url" pipeline
+	MinConfidence  float64 // form and page labels less probable than this become Unknown
+
+	// AdjustPageProba, if set, may modify the page type probabilities
+	// before a label is chosen or the threshold applied, e.g. to account
+	// for signals outside the HTML such as the HTTP response.
+	AdjustPageProba func(proba map[string]float64)
+}
+
 // ExtractPage classifies both the page type and forms from HTML.
-func (c *FormFieldClassifier) ExtractPage(htmlStr string, proba bool, threshold float64, classifyFields bool) ([]FormResult, ClassifyResult, ClassifyProbaResult, error) {
-	doc, err := htmlutil.LoadHTMLString(htmlStr)
+func (c *FormFieldClassifier) ExtractPage(htmlStr string, opts PageOptions) ([]FormResult, ClassifyResult, ClassifyProbaResult, error) {
+	return c.ExtractPageContext(context.Background(), strings.NewReader(htmlStr), opts)
+}
+
+// ExtractPageContext is like ExtractPage but reads HTML from r and stops
+// between forms once ctx is done, returning ctx.Err().
+func (c *FormFieldClassifier) ExtractPageContext(ctx context.Context, r io.Reader, opts PageOptions) ([]FormResult, ClassifyResult, ClassifyProbaResult, error) {
+	doc, err := ReadDocument(r)
 	if err != nil {
 		return nil, ClassifyResult{}, ClassifyProbaResult{}, err
 	}
+	return c.ExtractPageDocument(ctx, doc, opts)
+}
 
-	forms := htmlutil.GetForms(doc)
+// ExtractPageDocument classifies the page type and forms of a parsed
+// document. Each form is classified once; its type is reused as a page
+// type feature. The page features are extracted once for the page type
+// and its types.
+func (c *FormFieldClassifier) ExtractPageDocument(ctx context.Context, doc *Document, opts PageOptions) ([]FormResult, ClassifyResult, ClassifyProbaResult, error) {
+	forms := doc.Forms()
 	formResults := make([]FormResult, len(forms))
-	var classifyResults []ClassifyResult
+	classifyResults := make([]ClassifyResult, len(forms))
 
 	for i, form := range forms {
-		formResults[i].FormHTML, _ = form.Html()
-		if proba {
-			formResults[i].Proba = c.ClassifyProba(form, threshold, classifyFields)
-		} else {
-			formResults[i].Result = c.Classify(form, classifyFields)
+		if err := ctx.Err(); err != nil {
+			return nil, ClassifyResult{}, ClassifyProbaResult{}, err
 		}
-		classifyResults = append(classifyResults, c.Classify(form, false))
+		formResults[i], classifyResults[i].Form = c.classifyForm(form, opts, doc.IDs())
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, ClassifyResult{}, ClassifyProbaResult{}, err
 	}
 
 	var pageResult ClassifyResult
 	var pageProba ClassifyProbaResult
 	if c.PageModel != nil {
-		if proba {
-			pageProba = ClassifyProbaResult{
-				Form: c.PageModel.ClassifyProba(doc, classifyResults),
+		features := c.PageModel.extractFeatures(doc.Doc, classifyResults, opts.URL)
+		proba := c.PageModel.probaOf(features)
+		if opts.AdjustPageProba != nil {
+			opts.AdjustPageProba(proba)
+		}
+		label := confidentLabel(proba, opts.MinConfidence)
+		var types []string
+		if c.PageModel.MultiLabel != nil {
+			types = c.PageModel.typesOf(features)
+			if len(types) == 0 && label != Unknown {
+				types = []string{label}
 			}
-			pageProba.Form = thresholdMap(pageProba.Form, threshold)
+		}
+		if opts.Proba {
+			pageProba = ClassifyProbaResult{Form: thresholdMap(proba, opts.Threshold), Types: types}
 		} else {
-			pageResult = ClassifyResult{
-				Form: c.PageModel.Classify(doc, classifyResults),
-			}
+			pageResult = ClassifyResult{Form: label, Types: types}
 		}
 	}
 
 	return formResults, pageResult, pageProba, nil
 }
 
-// classifyFormsOnDoc runs form classification on all forms in a document.
+// classifyFormsOnDoc runs form classification on all forms in a document,
+// including virtual forms.
 func (c *FormFieldClassifier) classifyFormsOnDoc(doc *goquery.Document) []ClassifyResult {
-	forms := htmlutil.GetForms(doc)
+	forms := htmlutil.GetAllForms(doc)
 	results := make([]ClassifyResult, len(forms))
 	for i, form := range forms {
 		results[i] = c.Classify(form, false)
@@ -124,53 +177,78 @@ func (c *FormFieldClassifier) classifyFormsOnDoc(doc *goquery.Document) []Classi
 	return results
 }
 
-// ExtractForms extracts and classifies all forms from HTML.
-func (c *FormFieldClassifier) ExtractForms(htmlStr string, proba bool, threshold float64, classifyFields bool) ([]FormResult, error) {
-	doc, err := htmlutil.LoadHTMLString(htmlStr)
-	if err != nil {
-		return nil, err
-	}
-
-	forms := htmlutil.GetForms(doc)
-	results := make([]FormResult, len(forms))
-
-	for i, form := range forms {
-		results[i].FormHTML, _ = form.Html()
-		if proba {
-			results[i].Proba = c.ClassifyProba(form, threshold, classifyFields)
-		} else {
-			results[i].Result = c.Classify(form, classifyFields)
-		}
+// classifyForm classifies a single form and records how to locate it.
+// It also returns the most likely form type, even when the label is
+// Unknown. Only the form options of opts are used; ids counts the ids of
+// the document.
+func (c *FormFieldClassifier) classifyForm(form *goquery.Selection, opts PageOptions, ids htmlutil.IDCounts) (FormResult, string) {
+	var r FormResult
+	var formType string
+	r.FormHTML, _ = form.Html()
+	r.Selector = ids.CSSPath(form)
+	r.Virtual = htmlutil.IsVirtualForm(form)
+	switch {
+	case opts.Proba:
+		r.Proba, formType = c.classifyProba(form, opts.Threshold, opts.ClassifyFields, ids)
+	case opts.MinConfidence > 0:
+		proba := c.FormModel.ClassifyProba(form)
+		formType = argmax(proba)
+		r.Result = c.classifyAs(form, formType, opts.ClassifyFields, ids)
+		r.Result.Form = confidentLabel(proba, opts.MinConfidence)
+	default:
+		formType = c.FormModel.Classify(form)
+		r.Result = c.classifyAs(form, formType, opts.ClassifyFields, ids)
 	}
+	return r, formType
+}
 
-	return results, nil
+// ExtractForms extracts and classifies all forms from HTML, including
+// virtual forms built from controls outside any <form> element.
+func (c *FormFieldClassifier) ExtractForms(htmlStr string, proba bool, threshold float64, classifyFields bool) ([]FormResult, error) {
+	return c.ExtractFormsContext(context.Background(), strings.NewReader(htmlStr), proba, threshold, classifyFields)
 }
 
 // ExtractFormsFromReader extracts and classifies forms from an io.Reader.
-func (c *FormFieldClassifier) ExtractFormsFromReader(r *strings.Reader, proba bool, threshold float64, classifyFields bool) ([]FormResult, error) {
-	doc, err := htmlutil.LoadHTML(r)
+func (c *FormFieldClassifier) ExtractFormsFromReader(r io.Reader, proba bool, threshold float64, classifyFields bool) ([]FormResult, error) {
+	return c.ExtractFormsContext(context.Background(), r, proba, threshold, classifyFields)
+}
+
+// ExtractFormsContext extracts and classifies forms from r, stopping
+// between forms once ctx is done and returning ctx.Err().
+func (c *FormFieldClassifier) ExtractFormsContext(ctx context.Context, r io.Reader, proba bool, threshold float64, classifyFields bool) ([]FormResult, error) {
+	doc, err := ReadDocument(r)
 	if err != nil {
 		return nil, err
 	}
+	return c.ExtractFormsDocument(ctx, doc, proba, threshold, classifyFields)
+}
 
-	forms := htmlutil.GetForms(doc)
-	results := make([]FormResult, len(forms))
+// ExtractFormsDocument classifies the forms of a parsed document. Results
+// are in the order of doc.Forms().
+func (c *FormFieldClassifier) ExtractFormsDocument(ctx context.Context, doc *Document, proba bool, threshold float64, classifyFields bool) ([]FormResult, error) {
+	return c.ExtractFormsDocumentOptions(ctx, doc, PageOptions{Proba: proba, Threshold: threshold, ClassifyFields: classifyFields})
+}
 
+// ExtractFormsDocumentOptions is ExtractFormsDocument taking the form
+// options of opts (Proba, Threshold, ClassifyFields and MinConfidence).
+// The page type is not classified.
+func (c *FormFieldClassifier) ExtractFormsDocumentOptions(ctx context.Context, doc *Document, opts PageOptions) ([]FormResult, error) {
+	forms := doc.Forms()
+	results := make([]FormResult, len(forms))
 	for i, form := range forms {
-		results[i].FormHTML, _ = form.Html()
-		if proba {
-			results[i].Proba = c.ClassifyProba(form, threshold, classifyFields)
-		} else {
-			results[i].Result = c.Classify(form, classifyFields)
+		if err := ctx.Err(); err != nil {
+			return nil, err
 		}
+		results[i], _ = c.classifyForm(form, opts, doc.IDs())
 	}
-
 	return results, nil
 }
 
 // FormResult holds the result for a single form.
 type FormResult struct {
 	FormHTML string              `json:"form_html"`
+	Selector string              `json:"selector"`
+	Virtual  bool                `json:"virtual,omitempty"` // built from controls outside a <form>
 	Result   ClassifyResult      `json:"result,omitempty"`
 	Proba    ClassifyProbaResult `json:"proba,omitempty"`
 }
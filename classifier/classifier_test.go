@@ -1,9 +1,19 @@
 package classifier
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
 	"testing"
 
+	"github.com/PuerkitoBio/goquery"
+	"github.com/happyhackingspace/dit/crf"
 	"github.com/happyhackingspace/dit/internal/htmlutil"
 )
 
@@ -111,6 +121,93 @@ func TestElemFeatures(t *testing.T) {
 	}
 }
 
+func TestAttrFeatures(t *testing.T) {
+	html := `
+<html><body>
+<span id="user-hint">Your work email</span>
+<form>
+  <input type="text" name="f1" autocomplete="section-login username" aria-labelledby="user-hint"
+         required minlength="3" maxlength="254"/>
+  <input type="text" name="f2" inputmode="numeric" pattern="[0-9]{5}" aria-label="ZIP code" aria-required="true"/>
+</form>
+</body></html>`
+
+	doc, _ := htmlutil.LoadHTMLString(html)
+	forms := htmlutil.GetForms(doc)
+	fields := htmlutil.GetFieldsToAnnotate(forms[0])
+
+	user := ElemFeatures(fields[0], forms[0])
+	if ac, _ := user["autocomplete"].([]string); !slices.Equal(ac, []string{"section-login", "username"}) {
+		t.Errorf("autocomplete = %v", user["autocomplete"])
+	}
+	if lb, _ := user["aria-labelledby"].([]string); !slices.Contains(lb, "email") {
+		t.Errorf("aria-labelledby = %v", user["aria-labelledby"])
+	}
+	if user["required"] != true || user["minlength"] != "2-5" || user["maxlength"] != "65+" {
+		t.Errorf("required/minlength/maxlength = %v %v %v", user["required"], user["minlength"], user["maxlength"])
+	}
+
+	zip := ElemFeatures(fields[1], forms[0])
+	if zip["inputmode"] != "numeric" || zip["pattern"] != "digits" || zip["required"] != true {
+		t.Errorf("inputmode/pattern/required = %v %v %v", zip["inputmode"], zip["pattern"], zip["required"])
+	}
+	if al, _ := zip["aria-label"].([]string); !slices.Contains(al, "zip") {
+		t.Errorf("aria-label = %v", zip["aria-label"])
+	}
+
+	for pattern, want := range map[string]string{
+		`\d{3}-?\d{4}`:        "digits",
+		`[A-Za-z ]+`:          "letters",
+		`[^@]+@[^@]+\.[a-z]+`: "email",
+		`https?://.+`:         "url",
+		`(?=.*[A-Z]).{8,}`:    "other",
+	} {
+		if got := patternKind(pattern); got != want {
+			t.Errorf("patternKind(%q) = %q, want %q", pattern, got, want)
+		}
+	}
+}
+
+func TestOTPFeatures(t *testing.T) {
+	html := `
+<form>
+  <input type="text" name="code" autocomplete="one-time-code" inputmode="numeric" maxlength="6"/>
+</form>
+<form>
+  <input type="tel" name="d1" maxlength="1"/><input type="tel" name="d2" maxlength="1"/>
+  <input type="tel" name="d3" maxlength="1"/><input type="tel" name="d4" maxlength="1"/>
+  <input type="tel" name="d5" maxlength="1"/><input type="tel" name="d6" maxlength="1"/>
+</form>
+<form>
+  <input type="text" name="initial" maxlength="1"/><input type="text" name="city"/>
+</form>`
+
+	doc, _ := htmlutil.LoadHTMLString(html)
+	forms := htmlutil.GetForms(doc)
+
+	code := ElemFeatures(htmlutil.GetFieldsToAnnotate(forms[0])[0], forms[0])
+	if code["one-time-code"] != true || code["inputmode"] != "numeric" || code["maxlength"] != "6-8" {
+		t.Errorf("one-time code field: one-time-code/inputmode/maxlength = %v %v %v", code["one-time-code"], code["inputmode"], code["maxlength"])
+	}
+	for _, key := range []string{"inputmode-numeric", "maxlength-code"} {
+		if _, ok := code[key]; ok {
+			t.Errorf("one-time code field: %s duplicates an attribute feature", key)
+		}
+	}
+	digit := ElemFeatures(htmlutil.GetFieldsToAnnotate(forms[1])[0], forms[1])
+	if digit["split-digit"] != true {
+		t.Errorf("split digit field: split-digit = %v", digit["split-digit"])
+	}
+	initial := ElemFeatures(htmlutil.GetFieldsToAnnotate(forms[2])[0], forms[2])
+	if _, ok := initial["split-digit"]; ok {
+		t.Error("a lone single-character input is not a split code")
+	}
+
+	if f := (FormElements{}).ExtractDict(forms[1]); f["has split digit inputs"] != true {
+		t.Errorf("form features: %v", f)
+	}
+}
+
 func TestGetFormFeatures(t *testing.T) {
 	html := `
 <form>
@@ -152,3 +249,483 @@ func TestGetFormFeatures(t *testing.T) {
 		t.Errorf("bias = %v", feats[0]["bias"])
 	}
 }
+
+func TestPageURLFeatureAtInference(t *testing.T) {
+	urls := []string{
+		"https://a.example/account/login",
+		"https://b.example/account/login",
+		"https://c.example/blog/2024/post",
+		"https://d.example/blog/2024/post",
+	}
+	labels := []string{"login", "login", "blog", "blog"}
+	docs := make([]*goquery.Document, len(urls))
+	formResults := make([][]ClassifyResult, len(urls))
+	for i := range urls {
+		doc, err := htmlutil.LoadHTMLString("<html><head><title>Page</title></head><body>Hello</body></html>")
+		if err != nil {
+			t.Fatal(err)
+		}
+		docs[i] = doc
+	}
+
+	config := DefaultPageTypeTrainConfig()
+	config.MaxIter = 5
+	model := TrainPageType(docs, formResults, urls, labels, config)
+
+	withURL := model.extractFeatures(docs[0], nil, urls[0])
+	withoutURL := model.extractFeatures(docs[0], nil, "")
+	if withURL.Nnz() <= withoutURL.Nnz() {
+		t.Errorf("expected URL to add features at inference: with=%d without=%d", withURL.Nnz(), withoutURL.Nnz())
+	}
+}
+
+func TestClassifyFieldsKeepsRepeatedNames(t *testing.T) {
+	html := `<html><body><form>
+<input type="email" name="email"/>
+<input type="radio" name="plan" value="a"/>
+<input type="radio" name="plan" value="b"/>
+</form></body></html>`
+	doc, err := htmlutil.LoadHTMLString(html)
+	if err != nil {
+		t.Fatal(err)
+	}
+	form := htmlutil.GetForms(doc)[0]
+	elems := htmlutil.GetFieldsToAnnotate(form)
+
+	feats := GetFormFeatures(form, "login", elems)
+	seq := crf.TrainingSequence{Labels: []string{"email", "other", "other"}}
+	for _, f := range feats {
+		seq.Features = append(seq.Features, crf.FeaturesToAttributes(f))
+	}
+	config := crf.DefaultTrainerConfig()
+	config.MaxIterations = 5
+	model := TrainFieldType([]crf.TrainingSequence{seq}, config)
+
+	fields := model.ClassifyFields(form, "login")
+	if len(fields) != 3 {
+		t.Fatalf("got %d fields, want 3", len(fields))
+	}
+	for i, f := range fields {
+		if f.Index != i {
+			t.Errorf("fields[%d].Index = %d", i, f.Index)
+		}
+		if f.Tag != "input" || f.Type == "" {
+			t.Errorf("fields[%d] = %+v", i, f)
+		}
+		if got := doc.Find(f.Selector); got.Length() != 1 || got.Get(0) != elems[i].Get(0) {
+			t.Errorf("fields[%d].Selector %q does not locate the field", i, f.Selector)
+		}
+	}
+	if fields[1].Name != "plan" || fields[2].Name != "plan" || fields[1].InputType != "radio" {
+		t.Errorf("radio group not preserved: %+v", fields[1:])
+	}
+	if got := model.Classify(form, "login"); len(got) != 2 {
+		t.Errorf("Classify map has %d names, want 2", len(got))
+	}
+}
+
+// trainTinyClassifier trains form, field and page models on a handful of
+// pages, enough to exercise serialization and inference paths.
+func trainTinyClassifier(t *testing.T) (*FormFieldClassifier, []string) {
+	t.Helper()
+	pages := []string{
+		`<html><head><title>Sign in</title></head><body><form method="post" action="/login"><input type="text" name="user"/><input type="password" name="pass"/><input type="submit" value="Log in"/></form></body></html>`,
+		`<html><head><title>Login</title></head><body><form method="post" action="/session"><input type="email" name="email"/><input type="password" name="password"/><button>Sign in</button></form></body></html>`,
+		`<html><head><title>Search</title></head><body><form method="get" action="/search"><input type="search" name="q"/><input type="submit" value="Search"/></form></body></html>`,
+		`<html><head><title>Find</title></head><body><form method="get" action="/find"><input type="text" name="query"/><button>Go</button></form></body></html>`,
+	}
+	formLabels := []string{"login", "login", "search", "search"}
+	fieldLabels := [][]string{
+		{"username", "password", "submit"},
+		{"email", "password"},
+		{"search query", "submit"},
+		{"search query"},
+	}
+
+	var docs []*goquery.Document
+	var forms []*goquery.Selection
+	var seqs []crf.TrainingSequence
+	for i, p := range pages {
+		doc, err := htmlutil.LoadHTMLString(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		docs = append(docs, doc)
+		form := htmlutil.GetForms(doc)[0]
+		forms = append(forms, form)
+
+		elems := htmlutil.GetFieldsToAnnotate(form)
+		seq := crf.TrainingSequence{Labels: fieldLabels[i]}
+		for _, f := range GetFormFeatures(form, formLabels[i], elems) {
+			seq.Features = append(seq.Features, crf.FeaturesToAttributes(f))
+		}
+		seqs = append(seqs, seq)
+	}
+
+	formConfig := DefaultFormTypeTrainConfig()
+	formConfig.MaxIter = 20
+	formModel := TrainFormType(forms, formLabels, formConfig)
+
+	crfConfig := crf.DefaultTrainerConfig()
+	crfConfig.MaxIterations = 20
+	fieldModel := TrainFieldType(seqs, crfConfig)
+
+	formResults := make([][]ClassifyResult, len(docs))
+	for i, doc := range docs {
+		formResults[i] = []ClassifyResult{{Form: formModel.Classify(htmlutil.GetForms(doc)[0])}}
+	}
+	pageConfig := DefaultPageTypeTrainConfig()
+	pageConfig.MaxIter = 20
+	pageModel := TrainPageType(docs, formResults, make([]string, len(docs)), formLabels, pageConfig)
+
+	return &FormFieldClassifier{FormModel: formModel, FieldModel: fieldModel, PageModel: pageModel}, pages
+}
+
+func TestBinaryModelRoundTrip(t *testing.T) {
+	c, pages := trainTinyClassifier(t)
+
+	dir := t.TempDir()
+	jsonPath := filepath.Join(dir, "model.json")
+	binPath := filepath.Join(dir, "model.bin")
+	if err := c.SaveModel(jsonPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SaveModelBinary(binPath); err != nil {
+		t.Fatal(err)
+	}
+
+	fromJSON, err := LoadClassifier(jsonPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromBin, err := LoadClassifier(binPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fromBin.FieldModel.CRF.Attributes.ToID != nil || fromBin.FormModel.Pipelines[0].DictVec.FeatureIndex != nil {
+		t.Error("indexes built at load time, want them built on first use")
+	}
+
+	for _, p := range pages {
+		want, wantPage, _, err := fromJSON.ExtractPage(p, PageOptions{ClassifyFields: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, gotPage, _, err := fromBin.ExtractPage(p, PageOptions{ClassifyFields: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if gotPage.Form != wantPage.Form {
+			t.Errorf("page type = %q, want %q", gotPage.Form, wantPage.Form)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("form results differ:\n got %+v\nwant %+v", got, want)
+		}
+
+		wantProba, _ := fromJSON.ExtractForms(p, true, 0, true)
+		gotProba, _ := fromBin.ExtractForms(p, true, 0, true)
+		for i := range wantProba {
+			for cls, prob := range wantProba[i].Proba.Form {
+				if math.Abs(gotProba[i].Proba.Form[cls]-prob) > 1e-9 {
+					t.Errorf("form proba[%s] = %v, want %v", cls, gotProba[i].Proba.Form[cls], prob)
+				}
+			}
+		}
+	}
+}
+
+func TestModelMetaAndCompatibility(t *testing.T) {
+	c, _ := trainTinyClassifier(t)
+	c.Meta = &ModelMeta{FeatureVersion: FeatureVersion, DitVersion: "v1.2.3", DataHash: "abc"}
+
+	path := filepath.Join(t.TempDir(), "model.json")
+	if err := c.SaveModel(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadClassifier(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta := loaded.Meta
+	if meta == nil || meta.DitVersion != "v1.2.3" || meta.DataHash != "abc" || meta.CreatedAt.IsZero() {
+		t.Fatalf("meta not preserved: %+v", meta)
+	}
+	if meta.SchemaVersion != MetaSchemaVersion || len(meta.FormPipelines) != len(DefaultFeaturePipelines()) {
+		t.Errorf("meta not filled from models: %+v", meta)
+	}
+	if !reflect.DeepEqual(meta.FormClasses, c.FormModel.Classes) {
+		t.Errorf("FormClasses = %v, want %v", meta.FormClasses, c.FormModel.Classes)
+	}
+
+	// Models trained with other features are stale.
+	c.Meta = &ModelMeta{FeatureVersion: FeatureVersion - 1}
+	if err := c.SaveModelBinary(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadClassifier(path); !errors.Is(err, ErrIncompatibleModel) {
+		t.Errorf("stale feature version: LoadClassifier error = %v, want ErrIncompatibleModel", err)
+	}
+
+	// Legacy models that do not record a feature version still load.
+	c.Meta = nil
+	legacy, err := json.Marshal(UnifiedModel{FormModel: c.FormModel, FieldModel: c.FieldModel.CRF, PageModel: c.PageModel})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, legacy, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadClassifier(path); err != nil {
+		t.Errorf("legacy model without metadata: LoadClassifier error = %v", err)
+	}
+
+	c.FormModel.Pipelines[0].Name = "renamed"
+	if err := c.SaveModel(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadClassifier(path); !errors.Is(err, ErrIncompatibleModel) {
+		t.Errorf("LoadClassifier error = %v, want ErrIncompatibleModel", err)
+	}
+}
+
+func TestUnnamedFieldsKeepNamedContext(t *testing.T) {
+	c, _ := trainTinyClassifier(t)
+	doc, err := htmlutil.LoadHTMLString(`<form method="post" action="/login">
+<input type="text" name="user"/><span>Remember me</span><input type="checkbox"/>
+<input type="password" name="pass"/><input type="text" placeholder="Code"/></form>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	form := htmlutil.GetForms(doc)[0]
+
+	fields := c.FieldModel.ClassifyFieldsProba(form, "login")
+	if len(fields) != 4 || fields[1].Name != "" || fields[1].Index != 1 || fields[1].Type == "" {
+		t.Fatalf("fields = %+v, want 4 in document order with the unnamed ones labelled", fields)
+	}
+	// Named fields are labelled in the sequence training builds.
+	named := c.FieldModel.CRF.PredictMarginals(crfFeatures(form, "login", htmlutil.GetFieldsToAnnotate(form)))
+	for i, f := range []FieldResult{fields[0], fields[2]} {
+		for label, p := range named[i] {
+			if math.Abs(f.Proba[label]-p) > 1e-9 {
+				t.Errorf("%s: proba[%s] = %v, want %v from the named-only sequence", f.Name, label, f.Proba[label], p)
+			}
+		}
+	}
+}
+
+func TestExtractPageDocument(t *testing.T) {
+	c, pages := trainTinyClassifier(t)
+	for _, p := range pages {
+		doc, err := ParseDocument(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		forms, page, _, err := c.ExtractPageDocument(context.Background(), doc, PageOptions{ClassifyFields: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := c.ExtractForms(p, false, 0, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(forms, want) {
+			t.Errorf("forms from shared document differ from ExtractForms:\n got %+v\nwant %+v", forms, want)
+		}
+		if got, want := page.Form, c.ClassifyPage(doc.Doc, ""); got != want {
+			t.Errorf("page type %q, want %q", got, want)
+		}
+		if first, again := doc.Forms(), doc.Forms(); len(first) != 1 || first[0] != again[0] {
+			t.Error("Forms() should be computed once and cached")
+		}
+		if doc.HTML() != p {
+			t.Error("HTML() should return the parsed source")
+		}
+	}
+}
+
+func TestExplainDocument(t *testing.T) {
+	c, pages := trainTinyClassifier(t)
+	doc, err := ParseDocument(pages[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp, err := c.ExplainDocument(context.Background(), doc, ExplainOptions{Features: 1 << 20, Classes: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exp.Forms) != 1 {
+		t.Fatalf("got %d form explanations, want 1", len(exp.Forms))
+	}
+	form := doc.Forms()[0]
+	classes := exp.Forms[0].Type
+	if classes[0].Class != c.FormModel.Classify(form) {
+		t.Errorf("top class %q, want %q", classes[0].Class, c.FormModel.Classify(form))
+	}
+
+	// With every contribution listed, bias plus scores gives back the logits.
+	logits := make([]float64, len(classes))
+	for i, ce := range classes {
+		logits[i] = ce.Bias
+		for _, contrib := range slices.Concat(ce.Positive, ce.Negative) {
+			if contrib.Pipeline == "" || contrib.Feature == "" {
+				t.Errorf("unnamed contribution %+v", contrib)
+			}
+			logits[i] += contrib.Score
+		}
+	}
+	proba := c.FormModel.ClassifyProba(form)
+	for i, p := range softmax(logits) {
+		if math.Abs(p-proba[classes[i].Class]) > 1e-9 || math.Abs(classes[i].Probability-p) > 1e-9 {
+			t.Errorf("class %q: probability %v from contributions, %v reported, %v from ClassifyProba",
+				classes[i].Class, p, classes[i].Probability, proba[classes[i].Class])
+		}
+	}
+
+	fields := c.FieldModel.ClassifyFields(form, classes[0].Class)
+	if len(exp.Forms[0].Fields) != len(fields) {
+		t.Fatalf("got %d field explanations, want %d", len(exp.Forms[0].Fields), len(fields))
+	}
+	for i, f := range exp.Forms[0].Fields {
+		if f.Type != fields[i].Type || len(f.Positive)+len(f.Negative) == 0 {
+			t.Errorf("field %d explanation = %+v, want type %q", i, f, fields[i].Type)
+		}
+	}
+	if got, want := exp.Page[0].Class, c.ClassifyPage(doc.Doc, ""); got != want {
+		t.Errorf("page class %q, want %q", got, want)
+	}
+
+	exp, err = c.ExplainDocument(context.Background(), doc, ExplainOptions{Features: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ce := exp.Forms[0].Type[0]; len(exp.Forms[0].Type) != 2 || len(ce.Positive) > 1 || len(ce.Negative) > 1 {
+		t.Errorf("limits not applied: %+v", exp.Forms[0].Type)
+	}
+
+	// Feature names are computed once per model and reused.
+	names := c.FormModel.featureNames()
+	if len(names) != len(c.FormModel.Coef[0]) || &c.FormModel.featureNames()[0] != &names[0] {
+		t.Errorf("form feature names: %d for %d features, or not cached", len(names), len(c.FormModel.Coef[0]))
+	}
+	if names := c.PageModel.featureNames(); len(names) != len(c.PageModel.Coef[0]) {
+		t.Errorf("page feature names: %d for %d features", len(names), len(c.PageModel.Coef[0]))
+	}
+}
+
+func TestCalibration(t *testing.T) {
+	// Confident predictions that are right only half the time need a
+	// temperature above 1; ones that are always right, below 1.
+	overconfident := [][]float64{{4, 0}, {4, 0}, {0, 4}, {0, 4}}
+	if temp := FitTemperature(overconfident, []int{0, 1, 1, 0}); temp <= 1 {
+		t.Errorf("temperature for overconfident logits = %v, want > 1", temp)
+	}
+	if temp := FitTemperature(overconfident, []int{0, 0, 1, 1}); temp >= 1 {
+		t.Errorf("temperature for accurate logits = %v, want < 1", temp)
+	}
+	if temp := FitTemperature(nil, nil); temp != 1 {
+		t.Errorf("temperature without data = %v, want 1", temp)
+	}
+
+	c, pages := trainTinyClassifier(t)
+	form := htmlutil.GetForms(mustLoad(t, pages[0]))[0]
+	before := c.FormModel.ClassifyProba(form)
+	c.FormModel.Temperature = 3
+	after := c.FormModel.ClassifyProba(form)
+	best := argmax(before)
+	if argmax(after) != best || after[best] >= before[best] {
+		t.Errorf("temperature 3 should soften %v, got %v", before, after)
+	}
+
+	var buf strings.Builder
+	if err := c.WriteBinary(&buf); err != nil {
+		t.Fatal(err)
+	}
+	fromBin, err := ReadBinary(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fromBin.FormModel.Temperature != 3 {
+		t.Errorf("binary model temperature = %v, want 3", fromBin.FormModel.Temperature)
+	}
+
+	forms, page, _, err := c.ExtractPage(pages[0], PageOptions{ClassifyFields: true, MinConfidence: 1.01})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Form != Unknown || forms[0].Result.Form != Unknown {
+		t.Errorf("types below MinConfidence: page %q, form %q, want %q", page.Form, forms[0].Result.Form, Unknown)
+	}
+	want, err := c.ExtractForms(pages[0], false, 0, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(forms[0].Result.FieldDetails, want[0].Result.FieldDetails) {
+		t.Errorf("fields of an unknown form should use the most likely form type:\n got %+v\nwant %+v", forms[0].Result.FieldDetails, want[0].Result.FieldDetails)
+	}
+}
+
+func TestMultiLabel(t *testing.T) {
+	scores := []float64{0.875, 0.75, 0.5, 0.25, 0.125}
+	if got := bestF1Threshold(scores, []int{1, 1, 0, 1, 0}); got != 0.1875 {
+		t.Errorf("bestF1Threshold = %v, want 0.1875", got)
+	}
+	if got := bestF1Threshold(scores, []int{1, 1, 0, 0, 0}); got != 0.625 {
+		t.Errorf("bestF1Threshold = %v, want 0.625", got)
+	}
+
+	c, pages := trainTinyClassifier(t)
+	var docs []*goquery.Document
+	var formResults [][]ClassifyResult
+	for _, p := range pages {
+		doc := mustLoad(t, p)
+		docs = append(docs, doc)
+		formResults = append(formResults, []ClassifyResult{{Form: c.FormModel.Classify(htmlutil.GetForms(doc)[0])}})
+	}
+	types := [][]string{{"login"}, {"login", "search"}, {"search"}, {"search"}}
+	config := DefaultPageTypeTrainConfig()
+	config.MaxIter = 20
+	c.PageModel.TrainMultiLabel(docs, formResults, make([]string, len(docs)), types, config)
+
+	ml := c.PageModel.MultiLabel
+	if ml == nil || len(ml.Classes) != 2 || len(ml.Thresholds) != 2 {
+		t.Fatalf("multi-label model = %+v, want 2 classes with thresholds", ml)
+	}
+	for i, p := range pages {
+		got := c.PageModel.ClassifyTypes(docs[i], formResults[i], "")
+		_, page, _, err := c.ExtractPage(p, PageOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Types) == 0 || (len(got) > 0 && !reflect.DeepEqual(page.Types, got)) {
+			t.Errorf("page %d: Types = %v, ClassifyTypes = %v", i, page.Types, got)
+		}
+		_, _, proba, err := c.ExtractPage(p, PageOptions{Proba: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(proba.Types, page.Types) {
+			t.Errorf("page %d: proba Types = %v, want %v", i, proba.Types, page.Types)
+		}
+	}
+
+	var buf strings.Builder
+	if err := c.WriteBinary(&buf); err != nil {
+		t.Fatal(err)
+	}
+	fromBin, err := ReadBinary(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fromBin.PageModel.MultiLabel, ml) {
+		t.Errorf("binary multi-label model = %+v, want %+v", fromBin.PageModel.MultiLabel, ml)
+	}
+}
+
+func mustLoad(t *testing.T, html string) *goquery.Document {
+	t.Helper()
+	doc, err := htmlutil.LoadHTMLString(html)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
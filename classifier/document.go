@@ -0,0 +1,74 @@
+package classifier
+
+import (
+	"io"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/happyhackingspace/dit/internal/htmlutil"
+)
+
+// Document is an HTML page parsed once and shared by the form, field, page
+// and captcha stages. The form list and the serialized HTML are computed on
+// first use and cached, so a Document must not be used concurrently.
+type Document struct {
+	Doc *goquery.Document
+
+	source    string
+	hasSource bool
+	forms     []*goquery.Selection
+	formsDone bool
+	ids       htmlutil.IDCounts
+}
+
+// NewDocument wraps an already parsed document.
+func NewDocument(doc *goquery.Document) *Document {
+	return &Document{Doc: doc}
+}
+
+// ParseDocument parses an HTML string, keeping the source for text-based
+// detectors.
+func ParseDocument(htmlStr string) (*Document, error) {
+	doc, err := htmlutil.LoadHTMLString(htmlStr)
+	if err != nil {
+		return nil, err
+	}
+	return &Document{Doc: doc, source: htmlStr, hasSource: true}, nil
+}
+
+// ReadDocument reads and parses HTML from r.
+func ReadDocument(r io.Reader) (*Document, error) {
+	var b strings.Builder
+	if _, err := io.Copy(&b, r); err != nil {
+		return nil, err
+	}
+	return ParseDocument(b.String())
+}
+
+// Forms returns the <form> elements and virtual forms of the document,
+// in the order they are classified.
+func (d *Document) Forms() []*goquery.Selection {
+	if !d.formsDone {
+		d.forms = htmlutil.GetAllForms(d.Doc)
+		d.formsDone = true
+	}
+	return d.forms
+}
+
+// IDs returns the id counts of the document, for building selectors.
+func (d *Document) IDs() htmlutil.IDCounts {
+	if d.ids == nil {
+		d.ids = htmlutil.CountIDs(d.Doc.Selection)
+	}
+	return d.ids
+}
+
+// HTML returns the page source, or the serialized document when it was
+// not parsed from a string.
+func (d *Document) HTML() string {
+	if !d.hasSource {
+		d.source, _ = goquery.OuterHtml(d.Doc.Selection)
+		d.hasSource = true
+	}
+	return d.source
+}
@@ -0,0 +1,245 @@
+package classifier
+
+import (
+	"cmp"
+	"context"
+	"slices"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/happyhackingspace/dit/internal/htmlutil"
+	"github.com/happyhackingspace/dit/internal/vectorizer"
+)
+
+// Defaults of ExplainOptions.
+const (
+	DefaultExplainFeatures = 10 // contributions listed per sign
+	DefaultExplainClasses  = 3  // most probable classes explained
+)
+
+// Contribution is the share of one feature in a class score: Value, the
+// feature value, times Weight, the model weight of the feature for the
+// class. Pipeline names the feature pipeline of the form and page models
+// and is empty for field attributes.
+type Contribution struct {
+	Pipeline string  `json:"pipeline,omitempty"`
+	Feature  string  `json:"feature"`
+	Value    float64 `json:"value"`
+	Weight   float64 `json:"weight"`
+	Score    float64 `json:"score"`
+}
+
+// ClassExplanation lists the features that pushed a class up (Positive,
+// highest first) and down (Negative, lowest first). The class logit is
+// Bias plus the scores of all active features, of which only the top ones
+// are listed.
+type ClassExplanation struct {
+	Class       string         `json:"class"`
+	Probability float64        `json:"probability"`
+	Bias        float64        `json:"bias"`
+	Positive    []Contribution `json:"positive,omitempty"`
+	Negative    []Contribution `json:"negative,omitempty"`
+}
+
+// FieldExplanation lists the CRF state attributes of a field that count
+// most for and against its predicted type. Transition weights between
+// neighbouring fields are not included.
+type FieldExplanation struct {
+	FieldResult
+	Positive []Contribution `json:"positive,omitempty"`
+	Negative []Contribution `json:"negative,omitempty"`
+}
+
+// FormExplanation explains the form type and field types of one form.
+type FormExplanation struct {
+	Selector string             `json:"selector"`
+	Virtual  bool               `json:"virtual,omitempty"`
+	Type     []ClassExplanation `json:"type"`
+	Fields   []FieldExplanation `json:"fields,omitempty"`
+}
+
+// Explanation explains the predictions for a page: the page type classes
+// and each form, in the order of Document.Forms.
+type Explanation struct {
+	Page  []ClassExplanation `json:"page,omitempty"`
+	Forms []FormExplanation  `json:"forms"`
+}
+
+// ExplainOptions controls ExplainDocument.
+type ExplainOptions struct {
+	URL      string // page URL, used by the "page url" pipeline
+	Features int    // contributions per sign; 0 means DefaultExplainFeatures
+	Classes  int    // classes per prediction; 0 means DefaultExplainClasses, < 0 all
+}
+
+// ExplainDocument explains the page type, form type and field type
+// predictions for doc. Probabilities are those of the models alone, before
+// any adjustment for response signals.
+func (c *FormFieldClassifier) ExplainDocument(ctx context.Context, doc *Document, opts ExplainOptions) (*Explanation, error) {
+	top := cmp.Or(opts.Features, DefaultExplainFeatures)
+	numClasses := cmp.Or(opts.Classes, DefaultExplainClasses)
+
+	forms := doc.Forms()
+	out := &Explanation{Forms: make([]FormExplanation, len(forms))}
+	classifyResults := make([]ClassifyResult, len(forms))
+	for i, form := range forms {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		classes := c.FormModel.Explain(form, top)
+		formType := classes[0].Class
+		classifyResults[i].Form = formType
+		out.Forms[i] = FormExplanation{
+			Selector: doc.IDs().CSSPath(form),
+			Virtual:  htmlutil.IsVirtualForm(form),
+			Type:     firstClasses(classes, numClasses),
+		}
+		if c.FieldModel != nil {
+			out.Forms[i].Fields = c.FieldModel.explain(form, formType, top, doc.IDs())
+		}
+	}
+	if c.PageModel != nil {
+		out.Page = firstClasses(c.PageModel.Explain(doc.Doc, classifyResults, opts.URL, top), numClasses)
+	}
+	return out, nil
+}
+
+func firstClasses(classes []ClassExplanation, n int) []ClassExplanation {
+	if n < 0 || n >= len(classes) {
+		return classes
+	}
+	return classes[:n]
+}
+
+// Explain returns the top contributions to each form type, most probable
+// type first.
+func (m *FormTypeModel) Explain(form *goquery.Selection, top int) []ClassExplanation {
+	return explainLinear(m.extractFeatures(form), m.Classes, m.Coef, m.Intercept, m.Temperature, m.featureNames(), top)
+}
+
+// featureNames returns the names of the feature vector columns, computed
+// once per model.
+func (m *FormTypeModel) featureNames() []featureName {
+	m.namesOnce.Do(func() { m.names = pipelineFeatureNames(m.Pipelines) })
+	return m.names
+}
+
+// Explain returns the top contributions to each page type, most probable
+// type first.
+func (m *PageTypeModel) Explain(doc *goquery.Document, formResults []ClassifyResult, pageURL string, top int) []ClassExplanation {
+	return explainLinear(m.extractFeatures(doc, formResults, pageURL), m.Classes, m.Coef, m.Intercept, m.Temperature, m.featureNames(), top)
+}
+
+// featureNames returns the names of the feature vector columns, computed
+// once per model.
+func (m *PageTypeModel) featureNames() []featureName {
+	m.namesOnce.Do(func() { m.names = pipelineFeatureNames(m.Pipelines) })
+	return m.names
+}
+
+// Explain returns, for each field ClassifyFields would return, the top
+// state attribute contributions to its predicted type.
+func (m *FieldTypeModel) Explain(form *goquery.Selection, formType string, top int) []FieldExplanation {
+	return m.explain(form, formType, top, htmlutil.CountIDs(form))
+}
+
+// explain is Explain with the ids of the document counted by ids.
+func (m *FieldTypeModel) explain(form *goquery.Selection, formType string, top int, ids htmlutil.IDCounts) []FieldExplanation {
+	fieldElems, labels, features := labelFields(form, formType, m.CRF.Predict)
+	if len(fieldElems) == 0 {
+		return nil
+	}
+
+	results := make([]FieldExplanation, 0, len(fieldElems))
+	for i, elem := range fieldElems {
+		r := FieldExplanation{FieldResult: newFieldResult(elem, i, ids)}
+		r.Type = labels[i]
+		var contribs []Contribution
+		for attr, val := range features[i] {
+			if w := m.CRF.StateWeight(attr, labels[i]); w != 0 && val != 0 {
+				contribs = append(contribs, Contribution{Feature: attr, Value: val, Weight: w, Score: val * w})
+			}
+		}
+		r.Positive, r.Negative = topContributions(contribs, top)
+		results = append(results, r)
+	}
+	return results
+}
+
+// featureName locates a column of the concatenated feature vector.
+type featureName struct {
+	pipeline, feature string
+}
+
+// pipelineFeatureNames names the columns of the feature vector built from
+// pipelines, in the order extractFeatures concatenates them.
+func pipelineFeatureNames(pipelines []SerializedPipeline) []featureName {
+	var out []featureName
+	for _, p := range pipelines {
+		var names []string
+		switch p.VecType {
+		case "dict":
+			names = p.DictVec.Names()
+		case "count":
+			names = p.CountVec.Names()
+		case "tfidf":
+			names = p.TfidfVec.Names()
+		}
+		for _, n := range names {
+			out = append(out, featureName{p.Name, n})
+		}
+	}
+	return out
+}
+
+// explainLinear splits the logits of a multinomial logistic regression
+// into per-feature contributions. Classes are sorted by probability, which
+// is calibrated with temperature; contributions are to the raw logits.
+func explainLinear(x vectorizer.SparseVector, classes []string, coef [][]float64, intercept []float64, temperature float64, names []featureName, top int) []ClassExplanation {
+	logits := make([]float64, len(classes))
+	for c := range classes {
+		logits[c] = x.Dot(coef[c]) + intercept[c]
+	}
+	probs := softmax(scaleLogits(logits, temperature))
+
+	out := make([]ClassExplanation, len(classes))
+	for c, cls := range classes {
+		var contribs []Contribution
+		for i, idx := range x.Indices {
+			if idx >= len(coef[c]) || coef[c][idx] == 0 || x.Values[i] == 0 {
+				continue
+			}
+			contrib := Contribution{Value: x.Values[i], Weight: coef[c][idx], Score: x.Values[i] * coef[c][idx]}
+			if idx < len(names) {
+				contrib.Pipeline, contrib.Feature = names[idx].pipeline, names[idx].feature
+			}
+			contribs = append(contribs, contrib)
+		}
+		out[c] = ClassExplanation{Class: cls, Probability: probs[c], Bias: intercept[c]}
+		out[c].Positive, out[c].Negative = topContributions(contribs, top)
+	}
+	slices.SortStableFunc(out, func(a, b ClassExplanation) int {
+		return cmp.Compare(b.Probability, a.Probability)
+	})
+	return out
+}
+
+// topContributions returns up to top contributions with a positive score,
+// highest first, and up to top with a negative score, lowest first.
+func topContributions(contribs []Contribution, top int) (positive, negative []Contribution) {
+	slices.SortFunc(contribs, func(a, b Contribution) int {
+		return cmp.Or(cmp.Compare(b.Score, a.Score), cmp.Compare(a.Feature, b.Feature))
+	})
+	for _, c := range contribs {
+		if c.Score <= 0 || len(positive) == top {
+			break
+		}
+		positive = append(positive, c)
+	}
+	for i := len(contribs) - 1; i >= 0 && len(negative) < top; i-- {
+		if contribs[i].Score >= 0 {
+			break
+		}
+		negative = append(negative, contribs[i])
+	}
+	return positive, negative
+}
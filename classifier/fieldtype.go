@@ -1,6 +1,9 @@
 package classifier
 
 import (
+	"slices"
+	"strings"
+
 	"github.com/PuerkitoBio/goquery"
 	"github.com/happyhackingspace/dit/crf"
 	"github.com/happyhackingspace/dit/internal/htmlutil"
@@ -11,59 +14,195 @@ type FieldTypeModel struct {
 	CRF *crf.Model
 }
 
-// Classify returns field types for a form given the form type.
-func (m *FieldTypeModel) Classify(form *goquery.Selection, formType string) map[string]string {
-	fieldElems := htmlutil.GetFieldsToAnnotate(form)
+// FieldResult describes a single classified field and how to locate it.
+type FieldResult struct {
+	Name      string             `json:"name,omitempty"`
+	ID        string             `json:"id,omitempty"`
+	Tag       string             `json:"tag"`
+	InputType string             `json:"input_type,omitempty"`
+	Index     int                `json:"index"` // position among the form's classified fields
+	Selector  string             `json:"selector"`
+	XPath     string             `json:"xpath"`
+	Type      string             `json:"type"` // predicted field type
+	Proba     map[string]float64 `json:"proba,omitempty"`
+}
+
+// Key returns the field's name, falling back to its id and then its
+// selector for unnamed fields.
+func (f FieldResult) Key() string {
+	switch {
+	case f.Name != "":
+		return f.Name
+	case f.ID != "":
+		return "#" + f.ID
+	}
+	return f.Selector
+}
+
+// newFieldResult fills the locating attributes of a field element. ids
+// counts the ids of its document.
+func newFieldResult(elem *goquery.Selection, index int, ids htmlutil.IDCounts) FieldResult {
+	name, _ := elem.Attr("name")
+	id, _ := elem.Attr("id")
+	tag := goquery.NodeName(elem)
+	inputType, exists := elem.Attr("type")
+	inputType = strings.ToLower(strings.TrimSpace(inputType))
+	if tag == "input" && (!exists || inputType == "") {
+		inputType = "text"
+	}
+	return FieldResult{
+		Name:      name,
+		ID:        id,
+		Tag:       tag,
+		InputType: inputType,
+		Index:     index,
+		Selector:  ids.CSSPath(elem),
+		XPath:     htmlutil.XPath(elem),
+	}
+}
+
+// labelFields runs predict over the CRF sequences of the fields to
+// classify and returns each field with its output and CRF attributes.
+//
+// The named fields form one sequence, as in training. Training data only
+// labels named fields, so each unnamed input is labelled in a sequence of
+// its own: the named fields plus that input. Adding every unnamed input to
+// one sequence would change the position and surrounding text features,
+// and the transitions, of the named fields around them.
+func labelFields[T any](form *goquery.Selection, formType string, predict func([]map[string]float64) []T) ([]*goquery.Selection, []T, []map[string]float64) {
+	fieldElems := htmlutil.GetFieldsToClassify(form)
 	if len(fieldElems) == 0 {
-		return nil
+		return nil, nil, nil
+	}
+	named := htmlutil.GetFieldsToAnnotate(form)
+	var namedAttrs []map[string]float64
+	var namedOut []T
+	if len(named) > 0 {
+		namedAttrs = crfFeatures(form, formType, named)
+		namedOut = predict(namedAttrs)
 	}
 
-	rawFeatures := GetFormFeatures(form, formType, fieldElems)
+	out := make([]T, len(fieldElems))
+	attrs := make([]map[string]float64, len(fieldElems))
+	j := 0 // named fields before the current one
+	for i, elem := range fieldElems {
+		if j < len(named) && named[j].Get(0) == elem.Get(0) {
+			out[i], attrs[i] = namedOut[j], namedAttrs[j]
+			j++
+			continue
+		}
+		seq := slices.Concat(named[:j], []*goquery.Selection{elem}, named[j:])
+		seqAttrs := crfFeatures(form, formType, seq)
+		out[i], attrs[i] = predict(seqAttrs)[j], seqAttrs[j]
+	}
+	return fieldElems, out, attrs
+}
 
-	// Convert to CRF attributes
-	crfFeatures := make([]map[string]float64, len(rawFeatures))
+// crfFeatures extracts CRF attributes for the given fields of a form.
+func crfFeatures(form *goquery.Selection, formType string, fieldElems []*goquery.Selection) []map[string]float64 {
+	rawFeatures := GetFormFeatures(form, formType, fieldElems)
+	features := make([]map[string]float64, len(rawFeatures))
 	for i, feat := range rawFeatures {
-		crfFeatures[i] = crf.FeaturesToAttributes(feat)
+		features[i] = crf.FeaturesToAttributes(feat)
 	}
+	return features
+}
 
-	// Predict
-	labels := m.CRF.Predict(crfFeatures)
+// ClassifyFields returns one result per visible field, in document order.
+// Unnamed inputs are included; unnamed buttons are not.
+func (m *FieldTypeModel) ClassifyFields(form *goquery.Selection, formType string) []FieldResult {
+	return m.classifyFields(form, formType, nil)
+}
 
-	// Map labels back to field names
-	result := make(map[string]string, len(fieldElems))
+// classifyFields is ClassifyFields with the ids of the document counted
+// by ids, or counted here if ids is nil.
+func (m *FieldTypeModel) classifyFields(form *goquery.Selection, formType string, ids htmlutil.IDCounts) []FieldResult {
+	fieldElems, labels, _ := labelFields(form, formType, m.CRF.Predict)
+	if len(fieldElems) == 0 {
+		return nil
+	}
+	if ids == nil {
+		ids = htmlutil.CountIDs(form)
+	}
+
+	results := make([]FieldResult, len(fieldElems))
 	for i, elem := range fieldElems {
-		name, _ := elem.Attr("name")
-		if i < len(labels) {
-			result[name] = labels[i]
-		}
+		results[i] = newFieldResult(elem, i, ids)
+		results[i].Type = labels[i]
 	}
-	return result
+	return results
 }
 
-// ClassifyProba returns field type probabilities for a form.
-func (m *FieldTypeModel) ClassifyProba(form *goquery.Selection, formType string) map[string]map[string]float64 {
-	fieldElems := htmlutil.GetFieldsToAnnotate(form)
+// ClassifyFieldsProba is like ClassifyFields but also returns the marginal
+// probability of each field type. Type holds the most likely label.
+func (m *FieldTypeModel) ClassifyFieldsProba(form *goquery.Selection, formType string) []FieldResult {
+	return m.classifyFieldsProba(form, formType, nil)
+}
+
+// classifyFieldsProba is ClassifyFieldsProba with the ids of the document
+// counted by ids, or counted here if ids is nil.
+func (m *FieldTypeModel) classifyFieldsProba(form *goquery.Selection, formType string, ids htmlutil.IDCounts) []FieldResult {
+	fieldElems, marginals, _ := labelFields(form, formType, m.CRF.PredictMarginals)
 	if len(fieldElems) == 0 {
 		return nil
 	}
+	if ids == nil {
+		ids = htmlutil.CountIDs(form)
+	}
 
-	rawFeatures := GetFormFeatures(form, formType, fieldElems)
+	results := make([]FieldResult, len(fieldElems))
+	for i, elem := range fieldElems {
+		results[i] = newFieldResult(elem, i, ids)
+		results[i].Proba = marginals[i]
+		results[i].Type = argmax(marginals[i])
+	}
+	return results
+}
 
-	crfFeatures := make([]map[string]float64, len(rawFeatures))
-	for i, feat := range rawFeatures {
-		crfFeatures[i] = crf.FeaturesToAttributes(feat)
+// Classify returns field types for a form given the form type, keyed by
+// FieldResult.Key. Fields sharing a name collapse to the last one; use
+// ClassifyFields to keep every field.
+func (m *FieldTypeModel) Classify(form *goquery.Selection, formType string) map[string]string {
+	return fieldTypeMap(m.ClassifyFields(form, formType))
+}
+
+// ClassifyProba returns field type probabilities for a form, keyed by FieldResult.Key.
+func (m *FieldTypeModel) ClassifyProba(form *goquery.Selection, formType string) map[string]map[string]float64 {
+	return fieldProbaMap(m.ClassifyFieldsProba(form, formType))
+}
+
+func fieldTypeMap(fields []FieldResult) map[string]string {
+	if len(fields) == 0 {
+		return nil
+	}
+	result := make(map[string]string, len(fields))
+	for _, f := range fields {
+		result[f.Key()] = f.Type
 	}
+	return result
+}
 
-	marginals := m.CRF.PredictMarginals(crfFeatures)
+func fieldProbaMap(fields []FieldResult) map[string]map[string]float64 {
+	if len(fields) == 0 {
+		return nil
+	}
+	result := make(map[string]map[string]float64, len(fields))
+	for _, f := range fields {
+		result[f.Key()] = f.Proba
+	}
+	return result
+}
 
-	result := make(map[string]map[string]float64, len(fieldElems))
-	for i, elem := range fieldElems {
-		name, _ := elem.Attr("name")
-		if i < len(marginals) {
-			result[name] = marginals[i]
+func argmax(m map[string]float64) string {
+	best := ""
+	bestProb := -1.0
+	for k, v := range m {
+		if v > bestProb || (v == bestProb && k < best) {
+			best = k
+			bestProb = v
 		}
 	}
-	return result
+	return best
 }
 
 // TrainFieldType trains a CRF model for field type classification.
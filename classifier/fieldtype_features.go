@@ -1,6 +1,7 @@
 package classifier
 
 import (
+	"regexp"
 	"strings"
 
 	"github.com/PuerkitoBio/goquery"
@@ -74,9 +75,110 @@ func ElemFeatures(elem *goquery.Selection, form *goquery.Selection) map[string]a
 		feat["option-num-pattern"] = patterns
 	}
 
+	attrFeatures(elem, form, feat)
+	otpFeatures(elem, form, feat)
+	paymentFeatures(elem, feat)
 	return feat
 }
 
+// paymentFeatures adds the kind of card data a field asks for and whether
+// it belongs to a billing or shipping address.
+func paymentFeatures(elem *goquery.Selection, feat map[string]any) {
+	if kind := htmlutil.CardFieldKind(elem); kind != "" {
+		feat["card-field"] = kind
+	}
+	if section := htmlutil.AddressSection(elem); section != "" {
+		feat["address-section"] = section
+	}
+}
+
+// attrFeatures adds the hints browsers and password managers use:
+// autocomplete tokens, input mode, pattern, length limits, ARIA labels and
+// whether the field is required.
+func attrFeatures(elem, form *goquery.Selection, feat map[string]any) {
+	if ac := normalizeAttr(elem, "autocomplete"); ac != "" {
+		feat["autocomplete"] = strings.Fields(ac)
+	}
+	if mode := normalizeAttr(elem, "inputmode"); mode != "" {
+		feat["inputmode"] = mode
+	}
+	if pattern, ok := elem.Attr("pattern"); ok && pattern != "" {
+		feat["pattern"] = patternKind(pattern)
+	}
+	if n := htmlutil.MinLength(elem); n > 0 {
+		feat["minlength"] = lengthBucket(n)
+	}
+	if n := htmlutil.MaxLength(elem); n > 0 {
+		feat["maxlength"] = lengthBucket(n)
+	}
+	if aria := normalizeAttr(elem, "aria-label"); aria != "" {
+		feat["aria-label"] = textutil.Tokenize(aria)
+	}
+	if text := htmlutil.LabelledByText(elem, form); text != "" {
+		feat["aria-labelledby"] = textutil.Tokenize(textutil.Normalize(text))
+	}
+	if _, ok := elem.Attr("required"); ok || normalizeAttr(elem, "aria-required") == "true" {
+		feat["required"] = true
+	}
+}
+
+var (
+	digitsPattern = regexp.MustCompile(`^\^?(\\d|\[0-9\]|[{}0-9,+*?. -]|\\[.-]|\(|\)|\|)+\$?$`)
+	alphaPattern  = regexp.MustCompile(`^\^?(\[[a-z \-]+\]|[{}0-9,+*?])+\$?$`)
+)
+
+// patternKind reduces a pattern attribute to a coarse shape: digits only,
+// letters only, an email or URL shape, or anything else.
+func patternKind(pattern string) string {
+	p := strings.ToLower(strings.TrimSpace(pattern))
+	switch {
+	case digitsPattern.MatchString(p):
+		return "digits"
+	case alphaPattern.MatchString(p):
+		return "letters"
+	case strings.Contains(p, "@"):
+		return "email"
+	case strings.Contains(p, "https?") || strings.Contains(p, "://"):
+		return "url"
+	}
+	return "other"
+}
+
+// lengthBucket groups length limits into ranges that separate single
+// characters, short codes, one-time codes (6 to 8 characters), typical
+// identifiers and free text.
+func lengthBucket(n int) string {
+	switch {
+	case n == 1:
+		return "1"
+	case n <= 5:
+		return "2-5"
+	case n <= 8:
+		return "6-8"
+	case n <= 16:
+		return "9-16"
+	case n <= 64:
+		return "17-64"
+	}
+	return "65+"
+}
+
+// minSplitDigits is the number of single-character inputs from which a
+// form is taken to split a one-time code into one box per digit.
+const minSplitDigits = 4
+
+// otpFeatures adds one-time code features: the one-time-code autocomplete
+// token and split digit boxes. Numeric input modes and code-sized maxlength
+// are covered by the inputmode and maxlength features of attrFeatures.
+func otpFeatures(elem, form *goquery.Selection, feat map[string]any) {
+	if htmlutil.IsOneTimeCodeInput(elem) {
+		feat["one-time-code"] = true
+	}
+	if htmlutil.MaxLength(elem) == 1 && htmlutil.IsDigitInput(elem) && htmlutil.CountDigitInputs(form) >= minSplitDigits {
+		feat["split-digit"] = true
+	}
+}
+
 // GetFormFeatures extracts CRF feature sequences for a form.
 func GetFormFeatures(form *goquery.Selection, formType string, fieldElems []*goquery.Selection) []map[string]any {
 	if fieldElems == nil {
@@ -2,6 +2,7 @@ package classifier
 
 import (
 	"math"
+	"sync"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/happyhackingspace/dit/internal/vectorizer"
@@ -13,6 +14,9 @@ type FormTypeModel struct {
 	Coef      [][]float64          `json:"coef"`      // [numClasses][numFeatures]
 	Intercept []float64            `json:"intercept"` // [numClasses]
 	Pipelines []SerializedPipeline `json:"pipelines"`
+	// Temperature divides the logits before the softmax to calibrate the
+	// probabilities; 0 means uncalibrated.
+	Temperature float64 `json:"temperature,omitempty"`
 
 	// Runtime state (not serialized directly)
 	dictVecs  []*vectorizer.DictVectorizer
@@ -20,6 +24,9 @@ type FormTypeModel struct {
 	tfidfVecs []*vectorizer.TfidfVectorizer
 	vecTypes  []string
 	vecDims   []int
+
+	namesOnce sync.Once
+	names     []featureName // feature vector column names, for Explain
 }
 
 // SerializedPipeline holds the serialized state of a feature pipeline.
@@ -48,24 +55,27 @@ func (m *FormTypeModel) Classify(form *goquery.Selection) string {
 
 // ClassifyProba returns probabilities for each form type.
 func (m *FormTypeModel) ClassifyProba(form *goquery.Selection) map[string]float64 {
-	features := m.extractFeatures(form)
-
-	// Compute logits: logits[c] = dot(coef[c], features) + intercept[c]
-	numClasses := len(m.Classes)
-	logits := make([]float64, numClasses)
-	for c := range numClasses {
-		logits[c] = features.Dot(m.Coef[c]) + m.Intercept[c]
-	}
-
-	// Softmax
-	probs := softmax(logits)
-	result := make(map[string]float64, numClasses)
+	probs := softmax(scaleLogits(m.Logits(form), m.Temperature))
+	result := make(map[string]float64, len(m.Classes))
 	for c, cls := range m.Classes {
 		result[cls] = probs[c]
 	}
 	return result
 }
 
+// Logits returns the uncalibrated score of each class, in the order of
+// Classes.
+func (m *FormTypeModel) Logits(form *goquery.Selection) []float64 {
+	features := m.extractFeatures(form)
+
+	// logits[c] = dot(coef[c], features) + intercept[c]
+	logits := make([]float64, len(m.Classes))
+	for c := range m.Classes {
+		logits[c] = features.Dot(m.Coef[c]) + m.Intercept[c]
+	}
+	return logits
+}
+
 // extractFeatures runs all pipelines and concatenates feature vectors.
 func (m *FormTypeModel) extractFeatures(form *goquery.Selection) vectorizer.SparseVector {
 	pipelines := DefaultFeaturePipelines()
@@ -41,6 +41,9 @@ func (f FormElements) ExtractDict(form *goquery.Selection) map[string]any {
 		"exactly two <input type=text>":     counts["text"] == 2,
 		"3 or more <input type=text>":       counts["text"] >= 3,
 		"<form method":                      htmlutil.GetFormMethod(form),
+		"has one-time-code input":           form.Find(`input[autocomplete*="one-time-code"]`).Length() > 0,
+		"has split digit inputs":            htmlutil.CountDigitInputs(form) >= minSplitDigits,
+		"has card number input":             htmlutil.HasCardInput(form),
 	}
 }
 
@@ -0,0 +1,136 @@
+package classifier
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// MetaSchemaVersion is the current version of the ModelMeta layout.
+const MetaSchemaVersion = 1
+
+// FeatureVersion identifies the feature extraction of this build: the form
+// element keys, the page type features and the CRF field attributes.
+// Pipeline names do not change when a pipeline gains a feature, so bump
+// this whenever the features of an existing input change; models trained
+// with another version are rejected.
+//
+//	1: feature extraction when models started recording it
+//	2: one-time-code, split-digit and card-number form elements, "2fa
+//	   challenge" page type, autocomplete, inputmode, pattern, length,
+//	   ARIA and payment field attributes
+const FeatureVersion = 2
+
+// ErrIncompatibleModel is returned when a model was trained with a feature
+// pipeline set or feature version that differs from the one compiled into
+// this build.
+var ErrIncompatibleModel = errors.New("incompatible model")
+
+// ModelMeta describes how a model was produced.
+type ModelMeta struct {
+	SchemaVersion  int                `json:"schema_version"`
+	FeatureVersion int                `json:"feature_version,omitempty"` // FeatureVersion at training time
+	DitVersion     string             `json:"dit_version,omitempty"`
+	CreatedAt      time.Time          `json:"created_at"`
+	DataHash       string             `json:"data_hash,omitempty"` // SHA-256 of the training annotations
+	FormPipelines  []string           `json:"form_pipelines,omitempty"`
+	PagePipelines  []string           `json:"page_pipelines,omitempty"`
+	FormClasses    []string           `json:"form_classes,omitempty"`
+	FieldClasses   []string           `json:"field_classes,omitempty"`
+	PageClasses    []string           `json:"page_classes,omitempty"`
+	Eval           map[string]float64 `json:"eval,omitempty"` // cross-validation scores
+}
+
+// Describe returns the classifier metadata, with pipeline names and class
+// lists taken from the models themselves. Fields recorded at training time
+// (version, feature version, data hash, eval scores) are kept from c.Meta
+// when present; a classifier without metadata is taken to be freshly
+// trained with the FeatureVersion of this build.
+func (c *FormFieldClassifier) Describe() *ModelMeta {
+	meta := &ModelMeta{SchemaVersion: MetaSchemaVersion, FeatureVersion: FeatureVersion}
+	if c.Meta != nil {
+		*meta = *c.Meta
+		meta.SchemaVersion = MetaSchemaVersion
+	}
+	meta.FormPipelines, meta.FormClasses = nil, nil
+	meta.PagePipelines, meta.PageClasses = nil, nil
+	meta.FieldClasses = nil
+
+	if c.FormModel != nil {
+		meta.FormPipelines = pipelineNames(c.FormModel.Pipelines)
+		meta.FormClasses = slices.Clone(c.FormModel.Classes)
+	}
+	if c.PageModel != nil {
+		meta.PagePipelines = pipelineNames(c.PageModel.Pipelines)
+		meta.PageClasses = slices.Clone(c.PageModel.Classes)
+	}
+	if c.FieldModel != nil && c.FieldModel.CRF != nil {
+		meta.FieldClasses = slices.Clone(c.FieldModel.CRF.Labels.ToStr)
+	}
+	return meta
+}
+
+// CheckCompatibility verifies that the models were built with the feature
+// pipelines and FeatureVersion of this build. Feature vectors are laid out
+// by pipeline position and feature name, so any difference would silently
+// corrupt predictions. Models without a recorded feature version predate
+// the check; they are loaded with a warning.
+func (c *FormFieldClassifier) CheckCompatibility() error {
+	switch {
+	case c.Meta == nil || c.Meta.FeatureVersion == 0:
+		slog.Warn("Model does not record its feature version; retrain it if predictions look off", "want", FeatureVersion)
+	case c.Meta.FeatureVersion != FeatureVersion:
+		return fmt.Errorf("%w: feature version %d, want %d; retrain the model with this build", ErrIncompatibleModel, c.Meta.FeatureVersion, FeatureVersion)
+	}
+
+	if c.FormModel != nil {
+		var want []string
+		for _, p := range DefaultFeaturePipelines() {
+			want = append(want, p.Name)
+		}
+		if got := pipelineNames(c.FormModel.Pipelines); !slices.Equal(got, want) {
+			return fmt.Errorf("%w: form pipelines %q, want %q", ErrIncompatibleModel, got, want)
+		}
+	}
+	if c.PageModel != nil {
+		var want []string
+		for _, p := range DefaultPageFeaturePipelines() {
+			want = append(want, p.Name)
+		}
+		if got := pipelineNames(c.PageModel.Pipelines); !slices.Equal(got, want) {
+			return fmt.Errorf("%w: page pipelines %q, want %q", ErrIncompatibleModel, got, want)
+		}
+	}
+	if c.Meta == nil {
+		return nil
+	}
+
+	if c.Meta.SchemaVersion > MetaSchemaVersion {
+		slog.Warn("Model metadata is newer than this build", "schema_version", c.Meta.SchemaVersion, "supported", MetaSchemaVersion)
+	}
+	actual := c.Describe()
+	if c.Meta.FormPipelines != nil && !slices.Equal(c.Meta.FormPipelines, actual.FormPipelines) ||
+		c.Meta.PagePipelines != nil && !slices.Equal(c.Meta.PagePipelines, actual.PagePipelines) {
+		slog.Warn("Model metadata does not match its pipelines; the file may have been edited")
+	}
+	return nil
+}
+
+// metaForSave returns the metadata written alongside the models.
+func (c *FormFieldClassifier) metaForSave() *ModelMeta {
+	meta := c.Describe()
+	if meta.CreatedAt.IsZero() {
+		meta.CreatedAt = time.Now().UTC()
+	}
+	return meta
+}
+
+func pipelineNames(pipelines []SerializedPipeline) []string {
+	names := make([]string, len(pipelines))
+	for i, p := range pipelines {
+		names[i] = p.Name
+	}
+	return names
+}
@@ -1,8 +1,10 @@
 package classifier
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 
@@ -14,6 +16,7 @@ type UnifiedModel struct {
 	FormModel  *FormTypeModel `json:"form_model"`
 	FieldModel *crf.Model     `json:"field_model"`
 	PageModel  *PageTypeModel `json:"page_model"`
+	Meta       *ModelMeta     `json:"meta,omitempty"`
 }
 
 // SaveModel saves the classifier to disk.
@@ -21,6 +24,7 @@ func (c *FormFieldClassifier) SaveModel(path string) error {
 	um := UnifiedModel{
 		FormModel: c.FormModel,
 		PageModel: c.PageModel,
+		Meta:      c.metaForSave(),
 	}
 	if c.FieldModel != nil {
 		um.FieldModel = c.FieldModel.CRF
@@ -39,21 +43,44 @@ func (c *FormFieldClassifier) SaveModel(path string) error {
 	return os.WriteFile(path, data, 0644)
 }
 
-// LoadClassifier loads a FormFieldClassifier from disk.
+// LoadClassifier loads a FormFieldClassifier from disk. Both the JSON and
+// the binary model formats are accepted; the format is detected from the
+// file header. Models built with a different feature pipeline set are
+// rejected with ErrIncompatibleModel.
 func LoadClassifier(path string) (*FormFieldClassifier, error) {
-	data, err := os.ReadFile(path)
+	f, err := os.Open(path)
 	if err != nil {
 		return nil, fmt.Errorf("read model: %w", err)
 	}
+	defer func() { _ = f.Close() }()
 
+	r := bufio.NewReaderSize(f, 1<<20)
+	var c *FormFieldClassifier
+	if header, _ := r.Peek(len(binaryMagic)); IsBinaryModel(header) {
+		c, err = ReadBinary(r)
+	} else {
+		c, err = readJSON(r)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := c.CheckCompatibility(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// readJSON reads a classifier in the JSON model format from r.
+func readJSON(r io.Reader) (*FormFieldClassifier, error) {
 	var um UnifiedModel
-	if err := json.Unmarshal(data, &um); err != nil {
+	if err := json.NewDecoder(r).Decode(&um); err != nil {
 		return nil, fmt.Errorf("unmarshal model: %w", err)
 	}
 
 	c := &FormFieldClassifier{
 		FormModel: um.FormModel,
 		PageModel: um.PageModel,
+		Meta:      um.Meta,
 	}
 
 	if um.FormModel != nil {
@@ -0,0 +1,192 @@
+package classifier
+
+import (
+	"cmp"
+	"math"
+	"slices"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/happyhackingspace/dit/internal/vectorizer"
+)
+
+// MultiLabelModel is a one-vs-rest page type model: a binary logistic
+// regression per type over the features of the page type model, each with
+// its own decision threshold, so that a page can have several types.
+type MultiLabelModel struct {
+	Classes    []string    `json:"classes"`
+	Coef       [][]float64 `json:"coef"`      // [numClasses][numFeatures]
+	Intercept  []float64   `json:"intercept"` // [numClasses]
+	Thresholds []float64   `json:"thresholds"`
+}
+
+// multiLabelFolds is the number of folds whose held-out probabilities tune
+// the thresholds. Folds are contiguous, which keeps pages of a domain
+// together since the page storage yields them sorted by domain.
+const multiLabelFolds = 3
+
+// defaultMultiLabelThreshold is used for types too rare to tune.
+const defaultMultiLabelThreshold = 0.5
+
+// TrainMultiLabel fits the one-vs-rest model of m on the features of the
+// already trained m. labels lists the types of each page.
+func (m *PageTypeModel) TrainMultiLabel(docs []*goquery.Document, formResults [][]ClassifyResult, urls []string, labels [][]string, config PageTypeTrainConfig) {
+	if len(docs) == 0 {
+		return
+	}
+	x := make([]vectorizer.SparseVector, len(docs))
+	for i, doc := range docs {
+		x[i] = m.extractFeatures(doc, formResults[i], urls[i])
+	}
+
+	ml := &MultiLabelModel{}
+	for _, types := range labels {
+		for _, t := range types {
+			if !slices.Contains(ml.Classes, t) {
+				ml.Classes = append(ml.Classes, t)
+			}
+		}
+	}
+	for _, cls := range ml.Classes {
+		y := make([]int, len(labels))
+		for i, types := range labels {
+			if slices.Contains(types, cls) {
+				y[i] = 1
+			}
+		}
+		coef, intercept := trainBinaryLogReg(x, y, config)
+		ml.Coef = append(ml.Coef, coef)
+		ml.Intercept = append(ml.Intercept, intercept)
+		ml.Thresholds = append(ml.Thresholds, tuneThreshold(x, y, config))
+	}
+	m.MultiLabel = ml
+}
+
+// ClassifyTypes returns every type whose one-vs-rest probability reaches
+// its threshold, most probable first. It returns nil if m has no
+// multi-label model or no type passes.
+func (m *PageTypeModel) ClassifyTypes(doc *goquery.Document, formResults []ClassifyResult, pageURL string) []string {
+	if m.MultiLabel == nil {
+		return nil
+	}
+	return m.typesOf(m.extractFeatures(doc, formResults, pageURL))
+}
+
+// typesOf is ClassifyTypes for the features of a page, as extracted for
+// the single-label model.
+func (m *PageTypeModel) typesOf(features vectorizer.SparseVector) []string {
+	ml := m.MultiLabel
+	if ml == nil {
+		return nil
+	}
+	type scored struct {
+		class string
+		p     float64
+	}
+	var passed []scored
+	for c, cls := range ml.Classes {
+		if p := sigmoid(features.Dot(ml.Coef[c]) + ml.Intercept[c]); p >= ml.Thresholds[c] {
+			passed = append(passed, scored{cls, p})
+		}
+	}
+	slices.SortStableFunc(passed, func(a, b scored) int { return cmp.Compare(b.p, a.p) })
+	var types []string
+	for _, s := range passed {
+		types = append(types, s.class)
+	}
+	return types
+}
+
+// trainBinaryLogReg trains a logistic regression of y (0 or 1) on x as a
+// two-class softmax model, whose weight differences are the binary
+// weights. Classes are balanced if config.BalanceClass is set.
+func trainBinaryLogReg(x []vectorizer.SparseVector, y []int, config PageTypeTrainConfig) ([]float64, float64) {
+	reg := config.C
+	if reg <= 0 {
+		reg = 5.0
+	}
+	var sampleWeights []float64
+	if config.BalanceClass {
+		var counts [2]int
+		for _, yi := range y {
+			counts[yi]++
+		}
+		sampleWeights = make([]float64, len(y))
+		for i, yi := range y {
+			sampleWeights[i] = float64(len(y)) / (2 * float64(counts[yi]))
+		}
+	}
+	coef, intercept := trainLogReg(x, y, 2, x[0].Dim, reg, config.MaxIter, sampleWeights)
+	w := make([]float64, len(coef[1]))
+	for i := range w {
+		w[i] = coef[1][i] - coef[0][i]
+	}
+	return w, intercept[1] - intercept[0]
+}
+
+// tuneThreshold returns the threshold that maximises F1 on the held-out
+// probabilities of models trained on the other folds.
+func tuneThreshold(x []vectorizer.SparseVector, y []int, config PageTypeTrainConfig) float64 {
+	n := len(x)
+	var scores []float64
+	var targets []int
+	for k := range multiLabelFolds {
+		lo, hi := k*n/multiLabelFolds, (k+1)*n/multiLabelFolds
+		trainX := slices.Concat(x[:lo], x[hi:])
+		trainY := slices.Concat(y[:lo], y[hi:])
+		if lo == hi || !slices.Contains(trainY, 0) || !slices.Contains(trainY, 1) {
+			continue
+		}
+		coef, intercept := trainBinaryLogReg(trainX, trainY, config)
+		for i := lo; i < hi; i++ {
+			scores = append(scores, sigmoid(x[i].Dot(coef)+intercept))
+			targets = append(targets, y[i])
+		}
+	}
+	return bestF1Threshold(scores, targets)
+}
+
+// bestF1Threshold returns the threshold on scores that maximises F1 for
+// targets, halfway between the lowest score predicted positive and the
+// next one down.
+func bestF1Threshold(scores []float64, targets []int) float64 {
+	positives := 0
+	for _, t := range targets {
+		positives += t
+	}
+	if positives == 0 {
+		return defaultMultiLabelThreshold
+	}
+
+	order := make([]int, len(scores))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortFunc(order, func(a, b int) int { return cmp.Compare(scores[b], scores[a]) })
+
+	best, bestF1 := defaultMultiLabelThreshold, -1.0
+	tp, fp := 0, 0
+	for rank, i := range order {
+		if targets[i] == 1 {
+			tp++
+		} else {
+			fp++
+		}
+		// Only cut between distinct scores.
+		if rank+1 < len(order) && scores[order[rank+1]] == scores[i] {
+			continue
+		}
+		f1 := 2 * float64(tp) / float64(2*tp+fp+(positives-tp))
+		if f1 > bestF1 {
+			bestF1 = f1
+			best = scores[i] / 2
+			if rank+1 < len(order) {
+				best = (scores[i] + scores[order[rank+1]]) / 2
+			}
+		}
+	}
+	return best
+}
+
+func sigmoid(z float64) float64 {
+	return 1 / (1 + math.Exp(-z))
+}
@@ -1,6 +1,8 @@
 package classifier
 
 import (
+	"sync"
+
 	"github.com/PuerkitoBio/goquery"
 	"github.com/happyhackingspace/dit/internal/vectorizer"
 )
@@ -11,12 +13,20 @@ type PageTypeModel struct {
 	Coef      [][]float64          `json:"coef"`
 	Intercept []float64            `json:"intercept"`
 	Pipelines []SerializedPipeline `json:"pipelines"`
+	// Temperature divides the logits before the softmax to calibrate the
+	// probabilities; 0 means uncalibrated.
+	Temperature float64 `json:"temperature,omitempty"`
+	// MultiLabel, if set, predicts every type of a page (ClassifyTypes).
+	MultiLabel *MultiLabelModel `json:"multi_label,omitempty"`
 
 	// Runtime state (not serialized)
 	dictVecs  []*vectorizer.DictVectorizer
 	tfidfVecs []*vectorizer.TfidfVectorizer
 	vecTypes  []string
 	vecDims   []int
+
+	namesOnce sync.Once
+	names     []featureName // feature vector column names, for Explain
 }
 
 // PageTypeTrainConfig holds training configuration for the page type model.
@@ -36,9 +46,10 @@ func DefaultPageTypeTrainConfig() PageTypeTrainConfig {
 	}
 }
 
-// Classify returns the predicted page type.
-func (m *PageTypeModel) Classify(doc *goquery.Document, formResults []ClassifyResult) string {
-	proba := m.ClassifyProba(doc, formResults)
+// Classify returns the predicted page type. pageURL feeds the "page url"
+// pipeline and may be empty when the page was not fetched from a URL.
+func (m *PageTypeModel) Classify(doc *goquery.Document, formResults []ClassifyResult, pageURL string) string {
+	proba := m.ClassifyProba(doc, formResults, pageURL)
 	bestClass := ""
 	bestProb := -1.0
 	for cls, prob := range proba {
@@ -51,35 +62,48 @@ func (m *PageTypeModel) Classify(doc *goquery.Document, formResults []ClassifyRe
 }
 
 // ClassifyProba returns probabilities for each page type.
-func (m *PageTypeModel) ClassifyProba(doc *goquery.Document, formResults []ClassifyResult) map[string]float64 {
-	features := m.extractFeatures(doc, formResults)
-
-	numClasses := len(m.Classes)
-	logits := make([]float64, numClasses)
-	for c := range numClasses {
-		logits[c] = features.Dot(m.Coef[c]) + m.Intercept[c]
-	}
+func (m *PageTypeModel) ClassifyProba(doc *goquery.Document, formResults []ClassifyResult, pageURL string) map[string]float64 {
+	return m.probaOf(m.extractFeatures(doc, formResults, pageURL))
+}
 
-	probs := softmax(logits)
-	result := make(map[string]float64, numClasses)
+// probaOf is ClassifyProba for the features of a page.
+func (m *PageTypeModel) probaOf(features vectorizer.SparseVector) map[string]float64 {
+	probs := softmax(scaleLogits(m.logitsOf(features), m.Temperature))
+	result := make(map[string]float64, len(m.Classes))
 	for c, cls := range m.Classes {
 		result[cls] = probs[c]
 	}
 	return result
 }
 
+// Logits returns the uncalibrated score of each class, in the order of
+// Classes.
+func (m *PageTypeModel) Logits(doc *goquery.Document, formResults []ClassifyResult, pageURL string) []float64 {
+	return m.logitsOf(m.extractFeatures(doc, formResults, pageURL))
+}
+
+// logitsOf is Logits for the features of a page.
+func (m *PageTypeModel) logitsOf(features vectorizer.SparseVector) []float64 {
+	logits := make([]float64, len(m.Classes))
+	for c := range m.Classes {
+		logits[c] = features.Dot(m.Coef[c]) + m.Intercept[c]
+	}
+	return logits
+}
+
 // extractFeatures runs all page pipelines and concatenates feature vectors.
-func (m *PageTypeModel) extractFeatures(doc *goquery.Document, formResults []ClassifyResult) vectorizer.SparseVector {
+func (m *PageTypeModel) extractFeatures(doc *goquery.Document, formResults []ClassifyResult, pageURL string) vectorizer.SparseVector {
 	pipelines := DefaultPageFeaturePipelines()
 	vectors := make([]vectorizer.SparseVector, len(pipelines))
 
 	for i, pipe := range pipelines {
+		extractor := withPageURL(pipe.Extractor, pageURL)
 		switch m.vecTypes[i] {
 		case "dict":
-			feats := pipe.Extractor.ExtractDict(doc, formResults)
+			feats := extractor.ExtractDict(doc, formResults)
 			vectors[i] = m.dictVecs[i].Transform(feats)
 		case "tfidf":
-			text := pipe.Extractor.ExtractString(doc, formResults)
+			text := extractor.ExtractString(doc, formResults)
 			vectors[i] = m.tfidfVecs[i].Transform(text)
 		}
 	}
@@ -128,7 +152,6 @@ func TrainPageType(docs []*goquery.Document, formResults [][]ClassifyResult, url
 			VecType:       pipe.VecType,
 		}
 
-		// Inject URL into PageURLExtractor
 		extractor := pipe.Extractor
 
 		switch pipe.VecType {
@@ -152,12 +175,7 @@ func TrainPageType(docs []*goquery.Document, formResults [][]ClassifyResult, url
 			tv := vectorizer.NewTfidfVectorizer(pipe.NgramRange, pipe.MinDF, pipe.Binary, pipe.Analyzer, stopWords)
 			corpus := make([]string, len(docs))
 			for j, doc := range docs {
-				// Handle URL extractor specially
-				if _, ok := extractor.(PageURLExtractor); ok {
-					corpus[j] = PageURLExtractor{URL: urls[j]}.ExtractString(doc, formResults[j])
-				} else {
-					corpus[j] = extractor.ExtractString(doc, formResults[j])
-				}
+				corpus[j] = withPageURL(extractor, urls[j]).ExtractString(doc, formResults[j])
 			}
 			vecs := tv.FitTransform(corpus)
 			allVectors[i] = vecs
@@ -230,6 +248,15 @@ func TrainPageType(docs []*goquery.Document, formResults [][]ClassifyResult, url
 	return model
 }
 
+// withPageURL injects the document URL into a PageURLExtractor.
+// Other extractors are returned unchanged.
+func withPageURL(e PageFeatureExtractor, pageURL string) PageFeatureExtractor {
+	if _, ok := e.(PageURLExtractor); ok {
+		return PageURLExtractor{URL: pageURL}
+	}
+	return e
+}
+
 func pageExtractorTypeName(e PageFeatureExtractor) string {
 	switch e.(type) {
 	case PageStructureExtractor:
@@ -0,0 +1,160 @@
+package dit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/happyhackingspace/dit/classifier"
+)
+
+// DefaultMaxInputSize is the input size limit used by the Context methods
+// when Options.MaxInputSize is zero (10MB).
+const DefaultMaxInputSize = 10 << 20
+
+// ErrInputTooLarge is returned when the input exceeds Options.MaxInputSize.
+var ErrInputTooLarge = errors.New("dit: input too large")
+
+// Options configures the Context extraction methods.
+type Options struct {
+	// URL is the page URL, used as a page type feature and to resolve
+	// form actions.
+	URL string
+	// Threshold drops probabilities below it in the Proba methods.
+	Threshold float64
+	// MaxInputSize limits how many bytes are read from the input.
+	// Zero means DefaultMaxInputSize; a negative value disables the limit.
+	MaxInputSize int64
+	// Timeout bounds the whole call, in addition to any ctx deadline.
+	Timeout time.Duration
+	// Response is the HTTP response the page was served with, if known.
+	// Its headers, cookies and status code are combined with the HTML to
+	// detect bot protection and the "captcha" and "waf_block" page types.
+	Response *Response
+	// MinConfidence, when positive, reports page and form types whose
+	// probability is below it as "unknown" instead of a likely wrong
+	// guess. It applies to the label methods; the Proba methods return
+	// all probabilities. Fields are still classified with the most likely
+	// form type.
+	MinConfidence float64
+	// Explain attaches an Explanation of the predictions to page results.
+	// It classifies the page a second time, keeping track of feature
+	// contributions.
+	Explain bool
+}
+
+// ExtractPageTypeContext reads HTML from r and classifies the page type and
+// its forms. It returns ErrInputTooLarge for oversized input and ctx.Err()
+// (wrapped) once ctx is done or opts.Timeout has elapsed. opts may be nil.
+func (c *Classifier) ExtractPageTypeContext(ctx context.Context, r io.Reader, opts *Options) (*PageResult, error) {
+	ctx, cancel, doc, o, err := prepareInput(ctx, r, opts)
+	defer cancel()
+	if err != nil {
+		return nil, err
+	}
+	page, err := c.extractPage(ctx, doc, o.URL, o.Response, o.MinConfidence)
+	if err != nil || !o.Explain {
+		return page, err
+	}
+	page.Explanation, err = c.explain(ctx, doc, o.URL)
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// ExtractPageTypeProbaContext is the probability variant of
+// ExtractPageTypeContext.
+func (c *Classifier) ExtractPageTypeProbaContext(ctx context.Context, r io.Reader, opts *Options) (*PageResultProba, error) {
+	ctx, cancel, doc, o, err := prepareInput(ctx, r, opts)
+	defer cancel()
+	if err != nil {
+		return nil, err
+	}
+	page, err := c.extractPageProba(ctx, doc, o.URL, o.Threshold, o.Response)
+	if err != nil || !o.Explain {
+		return page, err
+	}
+	page.Explanation, err = c.explain(ctx, doc, o.URL)
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// ExtractFormsContext reads HTML from r and classifies all of its forms,
+// honouring ctx and the limits in opts like ExtractPageTypeContext.
+func (c *Classifier) ExtractFormsContext(ctx context.Context, r io.Reader, opts *Options) ([]FormResult, error) {
+	ctx, cancel, doc, o, err := prepareInput(ctx, r, opts)
+	defer cancel()
+	if err != nil {
+		return nil, err
+	}
+	return c.extractForms(ctx, doc, o.URL, o.MinConfidence)
+}
+
+// ExtractFormsProbaContext is the probability variant of ExtractFormsContext.
+func (c *Classifier) ExtractFormsProbaContext(ctx context.Context, r io.Reader, opts *Options) ([]FormResultProba, error) {
+	ctx, cancel, doc, o, err := prepareInput(ctx, r, opts)
+	defer cancel()
+	if err != nil {
+		return nil, err
+	}
+	return c.extractFormsProba(ctx, doc, o.URL, o.Threshold)
+}
+
+// prepareInput applies the per-call timeout, reads the input within the
+// size limit and parses it. The returned cancel func must always be called.
+func prepareInput(ctx context.Context, r io.Reader, opts *Options) (context.Context, context.CancelFunc, *classifier.Document, Options, error) {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	cancel := context.CancelFunc(func() {})
+	if o.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, o.Timeout)
+	}
+
+	limit := o.MaxInputSize
+	if limit == 0 {
+		limit = DefaultMaxInputSize
+	}
+	html, err := readInput(ctx, r, limit)
+	if err != nil {
+		return ctx, cancel, nil, o, err
+	}
+	doc, err := parseDocument(html)
+	if err != nil {
+		return ctx, cancel, nil, o, err
+	}
+	return ctx, cancel, doc, o, nil
+}
+
+// readInput reads r until EOF, failing once more than limit bytes have
+// been read (limit < 0 means no limit) or ctx is done.
+func readInput(ctx context.Context, r io.Reader, limit int64) (string, error) {
+	if limit >= 0 {
+		r = io.LimitReader(r, limit+1)
+	}
+	var b strings.Builder
+	buf := make([]byte, 32<<10)
+	for {
+		if err := ctx.Err(); err != nil {
+			return "", fmt.Errorf("dit: %w", err)
+		}
+		n, err := r.Read(buf)
+		b.Write(buf[:n])
+		if limit >= 0 && int64(b.Len()) > limit {
+			return "", fmt.Errorf("%w (limit %d bytes)", ErrInputTooLarge, limit)
+		}
+		if err == io.EOF {
+			return b.String(), nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("dit: read input: %w", err)
+		}
+	}
+}
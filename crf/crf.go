@@ -1,10 +1,18 @@
 // Package crf implements a linear-chain Conditional Random Field.
 package crf
 
-// Alphabet maps between string labels/attributes and integer IDs.
+import (
+	"encoding/json"
+	"sync"
+)
+
+// Alphabet maps between string labels/attributes and integer IDs. An
+// alphabet given only ToStr builds ToID on first use.
 type Alphabet struct {
 	ToID  map[string]int `json:"to_id"`
 	ToStr []string       `json:"to_str"`
+
+	indexOnce sync.Once
 }
 
 // NewAlphabet creates an empty alphabet.
@@ -14,8 +22,33 @@ func NewAlphabet() *Alphabet {
 	}
 }
 
+// index builds ToID from ToStr if it is missing.
+func (a *Alphabet) index() {
+	a.indexOnce.Do(func() {
+		if a.ToID != nil {
+			return
+		}
+		a.ToID = make(map[string]int, len(a.ToStr))
+		for i, s := range a.ToStr {
+			a.ToID[s] = i
+		}
+	})
+}
+
+// MarshalJSON implements json.Marshaler, writing ToID even if it has not
+// been built yet.
+func (a *Alphabet) MarshalJSON() ([]byte, error) {
+	a.index()
+	type alias struct {
+		ToID  map[string]int `json:"to_id"`
+		ToStr []string       `json:"to_str"`
+	}
+	return json.Marshal(alias{ToID: a.ToID, ToStr: a.ToStr})
+}
+
 // Add adds a string to the alphabet if not already present, returns its ID.
 func (a *Alphabet) Add(s string) int {
+	a.index()
 	if id, ok := a.ToID[s]; ok {
 		return id
 	}
@@ -27,6 +60,7 @@ func (a *Alphabet) Add(s string) int {
 
 // Get returns the ID for a string, or -1 if not found.
 func (a *Alphabet) Get(s string) int {
+	a.index()
 	if id, ok := a.ToID[s]; ok {
 		return id
 	}
@@ -72,6 +106,20 @@ func (m *Model) StateFeatureIndex(attrID, labelID int) int {
 	return attrID*m.NumLabels + labelID
 }
 
+// StateWeight returns the weight of attr for label, or 0 if either is
+// unknown to the model.
+func (m *Model) StateWeight(attr, label string) float64 {
+	attrID, labelID := m.Attributes.Get(attr), m.Labels.Get(label)
+	if attrID < 0 || labelID < 0 {
+		return 0
+	}
+	idx := m.StateFeatureIndex(attrID, labelID)
+	if idx >= len(m.Weights) {
+		return 0
+	}
+	return m.Weights[idx]
+}
+
 // TransFeatureIndex returns the weight index for a transition feature.
 func (m *Model) TransFeatureIndex(fromLabelID, toLabelID int) int {
 	return m.TransOffset() + fromLabelID*m.NumLabels + toLabelID
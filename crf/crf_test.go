@@ -1,6 +1,7 @@
 package crf
 
 import (
+	"encoding/json"
 	"math"
 	"testing"
 )
@@ -20,6 +21,18 @@ func TestAlphabet(t *testing.T) {
 	if a.Get("missing") != -1 {
 		t.Error("Get missing should return -1")
 	}
+
+	lazy := &Alphabet{ToStr: []string{"a", "b"}}
+	data, err := json.Marshal(lazy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"to_id":{"a":0,"b":1},"to_str":["a","b"]}`; string(data) != want {
+		t.Errorf("lazy alphabet JSON = %s, want %s", data, want)
+	}
+	if lazy.Get("b") != 1 || lazy.Add("c") != 2 {
+		t.Errorf("lazy alphabet IDs: b=%d c=%d", lazy.Get("b"), lazy.Get("c"))
+	}
 }
 
 func TestFeaturesToAttributes(t *testing.T) {
@@ -187,3 +200,38 @@ func TestModelSaveLoad(t *testing.T) {
 		}
 	}
 }
+
+func TestModelPrune(t *testing.T) {
+	model := NewModel()
+	model.Labels.Add("A")
+	model.Labels.Add("B")
+	model.Attributes.Add("bias")
+	model.Attributes.Add("unused")
+	model.Attributes.Add("word=x")
+	model.NumLabels = 2
+	model.Weights = []float64{
+		0.5, -0.5, // bias
+		0, 0, // unused
+		0, 1.5, // word=x
+		0.1, 0.2, 0.3, 0.4, // transitions
+	}
+
+	pruned := model.Prune()
+	if pruned.Attributes.Size() != 2 || pruned.Attributes.Get("unused") != -1 {
+		t.Fatalf("attributes = %v, want [bias word=x]", pruned.Attributes.ToStr)
+	}
+	if len(pruned.Weights) != pruned.NumWeights() {
+		t.Fatalf("weights length = %d, want %d", len(pruned.Weights), pruned.NumWeights())
+	}
+
+	seq := []map[string]float64{{"bias": 1, "word=x": 1, "unused": 1}, {"bias": 1}}
+	want := model.PredictMarginals(seq)
+	got := pruned.PredictMarginals(seq)
+	for i := range want {
+		for label, p := range want[i] {
+			if math.Abs(got[i][label]-p) > 1e-12 {
+				t.Errorf("marginal[%d][%s] = %v, want %v", i, label, got[i][label], p)
+			}
+		}
+	}
+}
@@ -40,3 +40,39 @@ func UnmarshalModel(data []byte) (*Model, error) {
 	}
 	return &model, nil
 }
+
+// Prune returns a copy of the model without attributes whose state weights
+// are all zero. Such attributes never affect a prediction, and L1
+// regularization leaves many of them behind.
+func (m *Model) Prune() *Model {
+	L := m.NumLabels
+	pruned := NewModel()
+	pruned.NumLabels = L
+	for _, label := range m.Labels.ToStr {
+		pruned.Labels.Add(label)
+	}
+
+	var state []float64
+	for attrID, attr := range m.Attributes.ToStr {
+		row := m.Weights[attrID*L : (attrID+1)*L]
+		if !hasNonZero(row) {
+			continue
+		}
+		pruned.Attributes.Add(attr)
+		state = append(state, row...)
+	}
+
+	pruned.Weights = make([]float64, 0, len(state)+L*L)
+	pruned.Weights = append(pruned.Weights, state...)
+	pruned.Weights = append(pruned.Weights, m.Weights[m.TransOffset():min(m.NumWeights(), len(m.Weights))]...)
+	return pruned
+}
+
+func hasNonZero(ws []float64) bool {
+	for _, w := range ws {
+		if w != 0 {
+			return true
+		}
+	}
+	return false
+}
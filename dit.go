@@ -17,55 +17,136 @@ package dit
 import (
 	"context"
 	"fmt"
-	"io"
 	"log/slog"
-	"net/http"
 	"os"
 	"path/filepath"
+	"runtime/debug"
 	"time"
 
+	"github.com/PuerkitoBio/goquery"
+	"github.com/happyhackingspace/dit/auth"
 	"github.com/happyhackingspace/dit/captcha"
 	"github.com/happyhackingspace/dit/classifier"
+	"github.com/happyhackingspace/dit/endpoint"
+	"github.com/happyhackingspace/dit/internal/download"
 	"github.com/happyhackingspace/dit/internal/htmlutil"
+	"github.com/happyhackingspace/dit/payment"
+	"github.com/happyhackingspace/dit/protection"
 )
 
 // downloadTimeout bounds the total time spent fetching the model.
 const downloadTimeout = 1 * time.Minute
 
-// ModelURL is the canonical download location for the pretrained model.
-const ModelURL = "https://huggingface.co/datasets/happyhackingspace/dit/resolve/main/model.json"
+// modulePath is the import path of this module, used to find its version.
+const modulePath = "github.com/happyhackingspace/dit"
+
+// ModelURL is the default download location for the pretrained model.
+// DIT_MODEL_URL overrides the base URL it is published under.
+const ModelURL = download.DefaultBaseURL + "/model.json"
 
 // Classifier wraps the form and field type classification models.
 type Classifier struct {
 	fc *classifier.FormFieldClassifier
 }
 
+// FieldResult describes a classified field: its name, id, tag, input type,
+// position in the form, a CSS selector and XPath locating it in the page,
+// and the predicted field type.
+type FieldResult = classifier.FieldResult
+
 // FormResult holds the classification result for a single form.
+// Fields maps field names (or the id or selector of unnamed fields) to
+// types; FieldDetails lists every classified field in document order,
+// including repeated names. Virtual forms are groups of controls found
+// outside any <form> element, located by Selector. CaptchaLayer and
+// CaptchaEvidence tell which detector found the CAPTCHA and what matched.
+// The embedded FormEndpoint describes where and how the form is submitted.
 type FormResult struct {
-	Type    string            `json:"type"`
-	Captcha string            `json:"captcha_type,omitempty"`
-	Fields  map[string]string `json:"fields,omitempty"`
+	Type            string            `json:"type"`
+	Captcha         string            `json:"captcha_type,omitempty"`
+	CaptchaLayer    string            `json:"captcha_layer,omitempty"`
+	CaptchaEvidence string            `json:"captcha_evidence,omitempty"`
+	Selector        string            `json:"selector,omitempty"`
+	Virtual         bool              `json:"virtual,omitempty"`
+	Fields          map[string]string `json:"fields,omitempty"`
+	FieldDetails    []FieldResult     `json:"field_details,omitempty"`
+	FormEndpoint
 }
 
 // FormResultProba holds probability-based classification results for a single form.
 type FormResultProba struct {
-	Type    map[string]float64            `json:"type"`
-	Captcha string                        `json:"captcha_type,omitempty"`
-	Fields  map[string]map[string]float64 `json:"fields,omitempty"`
+	Type            map[string]float64            `json:"type"`
+	Captcha         string                        `json:"captcha_type,omitempty"`
+	CaptchaLayer    string                        `json:"captcha_layer,omitempty"`
+	CaptchaEvidence string                        `json:"captcha_evidence,omitempty"`
+	Selector        string                        `json:"selector,omitempty"`
+	Virtual         bool                          `json:"virtual,omitempty"`
+	Fields          map[string]map[string]float64 `json:"fields,omitempty"`
+	FieldDetails    []FieldResult                 `json:"field_details,omitempty"`
+	FormEndpoint
 }
 
-// PageResult holds the page type classification result.
+// FormEndpoint is the submission target of a form: the resolved action
+// URL, method, encoding and hidden fields, the CSRF token candidates among
+// them, and whether the form posts cross-origin or over plain HTTP.
+type FormEndpoint = endpoint.Endpoint
+
+// CaptchaResult is a CAPTCHA or bot-protection provider detected on a
+// page, with a confidence score and the signals that matched.
+type CaptchaResult = captcha.Result
+
+// ProtectionResult is the WAF or bot-management vendor fronting a page,
+// with the evidence that identified it.
+type ProtectionResult = protection.Result
+
+// AuthMethod is a sign-in method offered by a page besides a password:
+// an OAuth or SSO identity provider, SAML, or passkeys.
+type AuthMethod = auth.Method
+
+// PaymentResult lists the hosted payment integrations of a page (Stripe
+// Elements, Braintree hosted fields, Adyen secured fields and the like)
+// and the card inputs it renders itself.
+type PaymentResult = payment.Result
+
+// PageResult holds the page type classification result. Captcha is the
+// first CAPTCHA found in a form, or on the page outside any form; Captchas
+// lists every provider detected on the page, most confident first.
+// Protection is the most likely WAF vendor, if any was recognised.
+// AuthMethods lists identity-provider, SAML and passkey sign-in options.
+// Payment is set when the page embeds a hosted payment provider or has
+// card inputs; Payment.CollectsCardData tells whether it takes card data
+// directly. Explanation is only set when requested with Options.Explain.
+// Types, set when the model has a multi-label page model, lists every type
+// of the page, most probable first, e.g. a waf_block that is also a
+// captcha.
 type PageResult struct {
-	Type    string       `json:"type"`
-	Captcha string       `json:"captcha_type,omitempty"`
-	Forms   []FormResult `json:"forms,omitempty"`
+	Type            string            `json:"type"`
+	Types           []string          `json:"types,omitempty"`
+	Captcha         string            `json:"captcha_type,omitempty"`
+	CaptchaLayer    string            `json:"captcha_layer,omitempty"`
+	CaptchaEvidence string            `json:"captcha_evidence,omitempty"`
+	Captchas        []CaptchaResult   `json:"captchas,omitempty"`
+	Protection      *ProtectionResult `json:"protection,omitempty"`
+	AuthMethods     []AuthMethod      `json:"auth_methods,omitempty"`
+	Payment         *PaymentResult    `json:"payment,omitempty"`
+	Forms           []FormResult      `json:"forms,omitempty"`
+	Explanation     *Explanation      `json:"explanation,omitempty"`
 }
 
 // PageResultProba holds probability-based page type classification results.
+// Types is as for PageResult.
 type PageResultProba struct {
-	Type    map[string]float64 `json:"type"`
-	Captcha string             `json:"captcha_type,omitempty"`
-	Forms   []FormResultProba  `json:"forms,omitempty"`
+	Type            map[string]float64 `json:"type"`
+	Types           []string           `json:"types,omitempty"`
+	Captcha         string             `json:"captcha_type,omitempty"`
+	CaptchaLayer    string             `json:"captcha_layer,omitempty"`
+	CaptchaEvidence string             `json:"captcha_evidence,omitempty"`
+	Captchas        []CaptchaResult    `json:"captchas,omitempty"`
+	Protection      *ProtectionResult  `json:"protection,omitempty"`
+	AuthMethods     []AuthMethod       `json:"auth_methods,omitempty"`
+	Payment         *PaymentResult     `json:"payment,omitempty"`
+	Forms           []FormResultProba  `json:"forms,omitempty"`
+	Explanation     *Explanation       `json:"explanation,omitempty"`
 }
 
 // New loads the classifier from "model.json", searching the current directory
@@ -79,51 +160,25 @@ func New() (*Classifier, error) {
 	}
 
 	dest := filepath.Join(ModelDir(), "model.json")
-	slog.Info("Model not found, downloading", "url", ModelURL, "dest", dest)
+	slog.Info("Model not found, downloading", "url", download.New().URL("model.json"), "dest", dest)
 	if err := Download(dest); err != nil {
 		return nil, fmt.Errorf("dit: %w", err)
 	}
 	return Load(dest)
 }
 
-// Download fetches the pretrained model from ModelURL and writes it to dest,
-// creating parent directories as needed. A partial file is removed on error.
+// Download fetches the pretrained model to dest, creating parent
+// directories as needed. The model is verified against the published
+// SHA-256 manifest and written atomically; an interrupted download is
+// resumed on the next call. Set DIT_MODEL_URL to download from a mirror.
 func Download(dest string) error {
-	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
-		return fmt.Errorf("create model dir: %w", err)
-	}
-
 	ctx, cancel := context.WithTimeout(context.Background(), downloadTimeout)
 	defer cancel()
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ModelURL, nil)
-	if err != nil {
-		return fmt.Errorf("download model: %w", err)
-	}
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("download model: %w", err)
-	}
-	defer func() { _ = resp.Body.Close() }()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("download model: HTTP %d", resp.StatusCode)
-	}
-
-	f, err := os.Create(dest)
-	if err != nil {
-		return fmt.Errorf("create model file: %w", err)
-	}
 
-	written, err := io.Copy(f, resp.Body)
+	written, err := download.New().Download(ctx, "model.json", dest)
 	if err != nil {
-		_ = f.Close()
-		_ = os.Remove(dest)
 		return fmt.Errorf("download model: %w", err)
 	}
-	if err := f.Close(); err != nil {
-		return fmt.Errorf("close model file: %w", err)
-	}
-
 	slog.Info("Model downloaded", "size", fmt.Sprintf("%.1fMB", float64(written)/1024/1024))
 	return nil
 }
@@ -170,7 +225,52 @@ func FindModel(name string) (string, error) {
 	return "", fmt.Errorf("model.json not found")
 }
 
-// Load loads a trained classifier from a model file.
+// Unknown is the type reported for forms and pages whose most likely type
+// is below Options.MinConfidence.
+const Unknown = classifier.Unknown
+
+// ModelMeta describes how a model was produced: dit version, feature
+// version, training data hash, feature pipelines, class lists and
+// evaluation scores.
+type ModelMeta = classifier.ModelMeta
+
+// ErrIncompatibleModel is returned by Load when a model was trained with a
+// different feature pipeline set or feature version than this version of
+// dit uses. Models that predate feature versions load with a warning.
+var ErrIncompatibleModel = classifier.ErrIncompatibleModel
+
+// Version returns the dit module version this program was built with, or
+// "devel" when it is unknown.
+func Version() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "devel"
+	}
+	if info.Main.Path == modulePath {
+		if v := info.Main.Version; v != "" && v != "(devel)" {
+			return v
+		}
+		return "devel"
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			return dep.Version
+		}
+	}
+	return "devel"
+}
+
+// Meta returns the model metadata. Pipelines and classes are always
+// reported; training details are only present for models saved with them.
+func (c *Classifier) Meta() *ModelMeta {
+	if c.fc == nil {
+		return nil
+	}
+	return c.fc.Describe()
+}
+
+// Load loads a trained classifier from a model file in either the JSON or
+// the binary format.
 func Load(path string) (*Classifier, error) {
 	fc, err := classifier.LoadClassifier(path)
 	if err != nil {
@@ -190,38 +290,46 @@ func (c *Classifier) Save(path string) error {
 	return nil
 }
 
+// SaveBinary writes the classifier in the compact binary model format,
+// which loads faster and is smaller than JSON.
+func (c *Classifier) SaveBinary(path string) error {
+	if c.fc == nil {
+		return fmt.Errorf("dit: classifier not initialized")
+	}
+	if err := c.fc.SaveModelBinary(path); err != nil {
+		return fmt.Errorf("dit: %w", err)
+	}
+	return nil
+}
+
 // ExtractForms extracts and classifies all forms in the given HTML string.
 // Returns an empty slice (not nil) if no forms are found.
 func (c *Classifier) ExtractForms(html string) ([]FormResult, error) {
-	if c.fc == nil || c.fc.FormModel == nil {
-		return nil, fmt.Errorf("dit: classifier not initialized")
+	doc, err := parseDocument(html)
+	if err != nil {
+		return nil, err
 	}
+	return c.extractForms(context.Background(), doc, "", 0)
+}
 
-	results, err := c.fc.ExtractForms(html, false, 0, true)
-	if err != nil {
-		return nil, fmt.Errorf("dit: %w", err)
+func (c *Classifier) extractForms(ctx context.Context, doc *classifier.Document, pageURL string, minConfidence float64) ([]FormResult, error) {
+	if c.fc == nil || c.fc.FormModel == nil {
+		return nil, fmt.Errorf("dit: classifier not initialized")
 	}
 
-	doc, err := htmlutil.LoadHTMLString(html)
+	results, err := c.fc.ExtractFormsDocumentOptions(ctx, doc, classifier.PageOptions{
+		ClassifyFields: true,
+		MinConfidence:  minConfidence,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("dit: %w", err)
 	}
-	forms := htmlutil.GetForms(doc)
 
+	captchas := formCaptchas(doc)
+	endpoints := formEndpoints(doc, pageURL)
 	out := make([]FormResult, len(results))
-	detector := &captcha.CaptchaDetector{}
 	for i, r := range results {
-		capStr := ""
-		if i < len(forms) {
-			if ct := detector.DetectInForm(forms[i]); ct != captcha.CaptchaTypeNone {
-				capStr = string(ct)
-			}
-		}
-		out[i] = FormResult{
-			Type:    r.Result.Form,
-			Captcha: capStr,
-			Fields:  r.Result.Fields,
-		}
+		out[i] = newFormResult(r, captchas[i], endpoints[i])
 	}
 	return out, nil
 }
@@ -229,59 +337,173 @@ func (c *Classifier) ExtractForms(html string) ([]FormResult, error) {
 // ExtractFormsProba extracts forms and returns classification probabilities.
 // Probabilities below threshold are omitted.
 func (c *Classifier) ExtractFormsProba(html string, threshold float64) ([]FormResultProba, error) {
+	doc, err := parseDocument(html)
+	if err != nil {
+		return nil, err
+	}
+	return c.extractFormsProba(context.Background(), doc, "", threshold)
+}
+
+func (c *Classifier) extractFormsProba(ctx context.Context, doc *classifier.Document, pageURL string, threshold float64) ([]FormResultProba, error) {
 	if c.fc == nil || c.fc.FormModel == nil {
 		return nil, fmt.Errorf("dit: classifier not initialized")
 	}
 
-	results, err := c.fc.ExtractForms(html, true, threshold, true)
+	results, err := c.fc.ExtractFormsDocument(ctx, doc, true, threshold, true)
 	if err != nil {
 		return nil, fmt.Errorf("dit: %w", err)
 	}
 
-	doc, err := htmlutil.LoadHTMLString(html)
+	captchas := formCaptchas(doc)
+	endpoints := formEndpoints(doc, pageURL)
+	out := make([]FormResultProba, len(results))
+	for i, r := range results {
+		out[i] = newFormResultProba(r, captchas[i], endpoints[i])
+	}
+	return out, nil
+}
+
+// parseDocument parses html once for all classification stages.
+func parseDocument(html string) (*classifier.Document, error) {
+	doc, err := classifier.ParseDocument(html)
 	if err != nil {
 		return nil, fmt.Errorf("dit: %w", err)
 	}
-	forms := htmlutil.GetForms(doc)
+	return doc, nil
+}
 
-	out := make([]FormResultProba, len(results))
+func newFormResult(r classifier.FormResult, c captcha.Detection, ep FormEndpoint) FormResult {
+	out := FormResult{
+		Type:         r.Result.Form,
+		Selector:     r.Selector,
+		Virtual:      r.Virtual,
+		Fields:       r.Result.Fields,
+		FieldDetails: r.Result.FieldDetails,
+		FormEndpoint: ep,
+	}
+	out.Captcha, out.CaptchaLayer, out.CaptchaEvidence = captchaFields(c)
+	return out
+}
+
+func newFormResultProba(r classifier.FormResult, c captcha.Detection, ep FormEndpoint) FormResultProba {
+	out := FormResultProba{
+		Type:         r.Proba.Form,
+		Selector:     r.Selector,
+		Virtual:      r.Virtual,
+		Fields:       r.Proba.Fields,
+		FieldDetails: r.Proba.FieldDetails,
+		FormEndpoint: ep,
+	}
+	out.Captcha, out.CaptchaLayer, out.CaptchaEvidence = captchaFields(c)
+	return out
+}
+
+// formCaptchas runs CAPTCHA detection on each form of doc, in the order of
+// doc.Forms().
+func formCaptchas(doc *classifier.Document) []captcha.Detection {
 	detector := &captcha.CaptchaDetector{}
-	for i, r := range results {
-		capStr := ""
-		if i < len(forms) {
-			if ct := detector.DetectInForm(forms[i]); ct != captcha.CaptchaTypeNone {
-				capStr = string(ct)
-			}
-		}
-		out[i] = FormResultProba{
-			Type:    r.Proba.Form,
-			Captcha: capStr,
-			Fields:  r.Proba.Fields,
-		}
+	forms := doc.Forms()
+	out := make([]captcha.Detection, len(forms))
+	for i, f := range forms {
+		out[i] = detector.Detect(f)
 	}
-	return out, nil
+	return out
+}
+
+// formEndpoints analyzes the submission endpoint of each form of doc, in
+// the order of doc.Forms().
+func formEndpoints(doc *classifier.Document, pageURL string) []FormEndpoint {
+	forms := doc.Forms()
+	out := make([]FormEndpoint, len(forms))
+	for i, f := range forms {
+		out[i] = endpoint.Analyze(doc.Doc, f, pageURL)
+	}
+	return out
 }
 
 // detectPageCaptcha detects page-level CAPTCHA by first checking each form
 // and falling back to a full-HTML scan.
-func detectPageCaptcha(htmlStr string) string {
-	doc, err := htmlutil.LoadHTMLString(htmlStr)
-	if err == nil {
-		detector := &captcha.CaptchaDetector{}
-		for _, f := range htmlutil.GetForms(doc) {
-			if ct := detector.DetectInForm(f); ct != captcha.CaptchaTypeNone {
-				return string(ct)
-			}
+func detectPageCaptcha(doc *classifier.Document, forms []captcha.Detection) captcha.Detection {
+	for _, d := range forms {
+		if d.Found() {
+			return d
 		}
 	}
-	if ct := captcha.DetectCaptchaInHTML(htmlStr); ct != captcha.CaptchaTypeNone {
-		return string(ct)
+	return captcha.DetectInHTML(doc.HTML())
+}
+
+// captchaFields flattens a detection into the result fields, leaving them
+// empty when nothing was found.
+func captchaFields(d captcha.Detection) (typ, layer, evidence string) {
+	if !d.Found() {
+		return "", "", ""
 	}
-	return ""
+	return string(d.Type), string(d.Layer), d.Evidence
+}
+
+// cardFieldKinds maps the card field types of the field model to the card
+// field kinds of the payment package.
+var cardFieldKinds = map[string]string{
+	"card number":        htmlutil.CardNumber,
+	"card expiry date":   htmlutil.CardExpiry,
+	"card expiry month":  htmlutil.CardExpiryMonth,
+	"card expiry year":   htmlutil.CardExpiryYear,
+	"card security code": htmlutil.CardCVC,
+	"cardholder name":    htmlutil.CardName,
+	"iban":               htmlutil.CardIBAN,
+}
+
+// detectPayment runs payment detection on doc and adds the fields the
+// field model classified as card data.
+func detectPayment(doc *classifier.Document, pageURL string, fields []FieldResult) *PaymentResult {
+	r := payment.Detect(doc.Doc, pageURL)
+	for _, f := range fields {
+		kind, ok := cardFieldKinds[f.Type]
+		if !ok {
+			continue
+		}
+		if r == nil {
+			r = &PaymentResult{}
+		}
+		r.AddCardField(payment.CardField{Kind: kind, Name: f.Name, Selector: f.Selector})
+	}
+	return r
 }
 
 // ExtractPageType classifies the page type and all forms in the HTML.
 func (c *Classifier) ExtractPageType(html string) (*PageResult, error) {
+	return c.ExtractPageTypeFromURL(html, "")
+}
+
+// ExtractPageTypeFromURL classifies the page type and all forms in the HTML
+// fetched from pageURL. The URL is used as a page type feature, matching
+// what the model saw during training.
+func (c *Classifier) ExtractPageTypeFromURL(html, pageURL string) (*PageResult, error) {
+	doc, err := parseDocument(html)
+	if err != nil {
+		return nil, err
+	}
+	return c.extractPage(context.Background(), doc, pageURL, nil, 0)
+}
+
+// ClassifyDocument classifies the page type and all forms of an already
+// parsed document, e.g. one obtained from a crawler. The document URL, if
+// set, is used as a page type feature.
+func (c *Classifier) ClassifyDocument(doc *goquery.Document) (*PageResult, error) {
+	if doc == nil {
+		return nil, fmt.Errorf("dit: nil document")
+	}
+	pageURL := ""
+	if doc.Url != nil {
+		pageURL = doc.Url.String()
+	}
+	return c.extractPage(context.Background(), classifier.NewDocument(doc), pageURL, nil, 0)
+}
+
+// extractPage classifies a page and its forms. resp, if not nil, adds
+// response signals to CAPTCHA detection and the page type. Types less
+// probable than minConfidence are reported as unknown.
+func (c *Classifier) extractPage(ctx context.Context, doc *classifier.Document, pageURL string, resp *Response, minConfidence float64) (*PageResult, error) {
 	if c.fc == nil || c.fc.FormModel == nil {
 		return nil, fmt.Errorf("dit: classifier not initialized")
 	}
@@ -289,28 +511,59 @@ func (c *Classifier) ExtractPageType(html string) (*PageResult, error) {
 		return nil, fmt.Errorf("dit: page model not available")
 	}
 
-	formResults, pageResult, _, err := c.fc.ExtractPage(html, false, 0, true)
+	captchas := formCaptchas(doc)
+	pageCaptcha := detectPageCaptcha(doc, captchas)
+	providers := captcha.DetectAllWithResponse(doc.Doc.Selection, resp)
+
+	formResults, pageResult, _, err := c.fc.ExtractPageDocument(ctx, doc, classifier.PageOptions{
+		ClassifyFields:  true,
+		URL:             pageURL,
+		MinConfidence:   minConfidence,
+		AdjustPageProba: protectionAdjuster(resp, providers, pageCaptcha),
+	})
 	if err != nil {
 		return nil, fmt.Errorf("dit: %w", err)
 	}
 
 	forms := make([]FormResult, len(formResults))
+	endpoints := formEndpoints(doc, pageURL)
 	for i, r := range formResults {
-		forms[i] = FormResult{
-			Type:   r.Result.Form,
-			Fields: r.Result.Fields,
-		}
+		forms[i] = newFormResult(r, captchas[i], endpoints[i])
 	}
 
-	return &PageResult{
-		Type:    pageResult.Form,
-		Captcha: detectPageCaptcha(html),
-		Forms:   forms,
-	}, nil
+	var fields []FieldResult
+	for _, f := range forms {
+		fields = append(fields, f.FieldDetails...)
+	}
+	result := &PageResult{
+		Type:        pageResult.Form,
+		Types:       pageResult.Types,
+		Captchas:    providers,
+		Protection:  detectProtection(doc, resp),
+		AuthMethods: auth.Detect(doc.Doc, pageURL),
+		Payment:     detectPayment(doc, pageURL, fields),
+		Forms:       forms,
+	}
+	result.Captcha, result.CaptchaLayer, result.CaptchaEvidence = captchaFields(pageCaptcha)
+	return result, nil
 }
 
 // ExtractPageTypeProba classifies the page type with probabilities.
 func (c *Classifier) ExtractPageTypeProba(html string, threshold float64) (*PageResultProba, error) {
+	return c.ExtractPageTypeProbaFromURL(html, "", threshold)
+}
+
+// ExtractPageTypeProbaFromURL classifies the page type with probabilities,
+// using pageURL as a page type feature.
+func (c *Classifier) ExtractPageTypeProbaFromURL(html, pageURL string, threshold float64) (*PageResultProba, error) {
+	doc, err := parseDocument(html)
+	if err != nil {
+		return nil, err
+	}
+	return c.extractPageProba(context.Background(), doc, pageURL, threshold, nil)
+}
+
+func (c *Classifier) extractPageProba(ctx context.Context, doc *classifier.Document, pageURL string, threshold float64, resp *Response) (*PageResultProba, error) {
 	if c.fc == nil || c.fc.FormModel == nil {
 		return nil, fmt.Errorf("dit: classifier not initialized")
 	}
@@ -318,22 +571,40 @@ func (c *Classifier) ExtractPageTypeProba(html string, threshold float64) (*Page
 		return nil, fmt.Errorf("dit: page model not available")
 	}
 
-	formResults, _, pageProba, err := c.fc.ExtractPage(html, true, threshold, true)
+	captchas := formCaptchas(doc)
+	pageCaptcha := detectPageCaptcha(doc, captchas)
+	providers := captcha.DetectAllWithResponse(doc.Doc.Selection, resp)
+
+	formResults, _, pageProba, err := c.fc.ExtractPageDocument(ctx, doc, classifier.PageOptions{
+		Proba:           true,
+		Threshold:       threshold,
+		ClassifyFields:  true,
+		URL:             pageURL,
+		AdjustPageProba: protectionAdjuster(resp, providers, pageCaptcha),
+	})
 	if err != nil {
 		return nil, fmt.Errorf("dit: %w", err)
 	}
 
 	forms := make([]FormResultProba, len(formResults))
+	endpoints := formEndpoints(doc, pageURL)
 	for i, r := range formResults {
-		forms[i] = FormResultProba{
-			Type:   r.Proba.Form,
-			Fields: r.Proba.Fields,
-		}
+		forms[i] = newFormResultProba(r, captchas[i], endpoints[i])
 	}
 
-	return &PageResultProba{
-		Type:    pageProba.Form,
-		Captcha: detectPageCaptcha(html),
-		Forms:   forms,
-	}, nil
+	var fields []FieldResult
+	for _, f := range forms {
+		fields = append(fields, f.FieldDetails...)
+	}
+	result := &PageResultProba{
+		Type:        pageProba.Form,
+		Types:       pageProba.Types,
+		Captchas:    providers,
+		Protection:  detectProtection(doc, resp),
+		AuthMethods: auth.Detect(doc.Doc, pageURL),
+		Payment:     detectPayment(doc, pageURL, fields),
+		Forms:       forms,
+	}
+	result.Captcha, result.CaptchaLayer, result.CaptchaEvidence = captchaFields(pageCaptcha)
+	return result, nil
 }
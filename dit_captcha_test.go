@@ -1,6 +1,8 @@
 package dit
 
 import (
+	"math"
+	"net/http"
 	"testing"
 
 	"github.com/happyhackingspace/dit/captcha"
@@ -358,3 +360,99 @@ func TestDetectCaptchaInHTMLNone(t *testing.T) {
 		t.Errorf("expected none, got %s", ct)
 	}
 }
+
+// TestPageCaptchaPerForm checks that page-level detection keeps one result
+// per form and reports the form that carries the CAPTCHA.
+func TestPageCaptchaPerForm(t *testing.T) {
+	doc, err := parseDocument(`<html><body>
+<form action="/search"><input name="q"/></form>
+<form action="/login"><input name="user"/><input type="password" name="pass"/>
+<div class="cf-turnstile" data-sitekey="x"></div></form>
+</body></html>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	captchas := formCaptchas(doc)
+	if len(captchas) != 2 {
+		t.Fatalf("got %d detections, want 2", len(captchas))
+	}
+	if captchas[0].Found() {
+		t.Errorf("search form: unexpected %+v", captchas[0])
+	}
+	typ, layer, evidence := captchaFields(captchas[1])
+	if typ != "turnstile" || layer != "class" || evidence != "cf-turnstile" {
+		t.Errorf("login form: got %q %q %q", typ, layer, evidence)
+	}
+	if page := detectPageCaptcha(doc, captchas); page != captchas[1] {
+		t.Errorf("page captcha = %+v, want %+v", page, captchas[1])
+	}
+}
+
+func TestProtectionAdjuster(t *testing.T) {
+	header := http.Header{}
+	header.Set("CF-Mitigated", "challenge")
+	blocked := &Response{StatusCode: http.StatusForbidden, Header: header}
+	providers := captcha.DetectResponse(blocked)
+
+	proba := map[string]float64{"login": 0.6, "waf_block": 0.1, "captcha": 0.1, "other": 0.2}
+	adjust := protectionAdjuster(blocked, providers, captcha.Detection{Type: captcha.CaptchaTypeNone})
+	if adjust == nil {
+		t.Fatal("expected an adjustment for a blocked Cloudflare response")
+	}
+	adjust(proba)
+	if argmax := maxKey(proba); argmax != "waf_block" {
+		t.Errorf("top class = %q, want waf_block (%v)", argmax, proba)
+	}
+	sum := 0.0
+	for _, p := range proba {
+		sum += p
+	}
+	if math.Abs(sum-1) > 1e-9 {
+		t.Errorf("probabilities sum to %v", sum)
+	}
+
+	proba = map[string]float64{"login": 0.6, "waf_block": 0.1, "captcha": 0.3}
+	protectionAdjuster(blocked, providers, captcha.Detection{Type: captcha.CaptchaTypeTurnstile})(proba)
+	if argmax := maxKey(proba); argmax != "captcha" {
+		t.Errorf("top class = %q, want captcha when the page shows one", argmax)
+	}
+
+	ok := &Response{StatusCode: http.StatusOK, Header: header}
+	if protectionAdjuster(ok, captcha.DetectResponse(ok), captcha.Detection{}) != nil {
+		t.Error("a 200 response should not change the page type")
+	}
+	if protectionAdjuster(nil, nil, captcha.Detection{}) != nil {
+		t.Error("a nil response should not change the page type")
+	}
+
+	// An origin error behind Cloudflare carries cf-ray and __cf_bm like
+	// any other proxied response; it is not a block page.
+	proxied := http.Header{}
+	proxied.Set("Server", "cloudflare")
+	proxied.Set("CF-RAY", "8a1b2c3d4e5f-AMS")
+	proxied.Add("Set-Cookie", "__cf_bm=abc; Path=/; HttpOnly")
+	origin403 := &Response{StatusCode: http.StatusForbidden, Header: proxied}
+	if protectionAdjuster(origin403, captcha.DetectResponse(origin403), captcha.Detection{}) != nil {
+		t.Error("a 403 with only cf-ray should not change the page type")
+	}
+
+	doc, err := htmlutil.LoadHTMLString(`<html><head><title>Home</title></head><body><p>Welcome</p></body></html>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := &Response{StatusCode: http.StatusOK, Header: proxied}
+	if found := captcha.DetectAllWithResponse(doc.Selection, page); len(found) != 0 {
+		t.Errorf("captchas on a plain 200 page behind Cloudflare = %+v, want none", found)
+	}
+}
+
+func maxKey(m map[string]float64) string {
+	best, bestP := "", -1.0
+	for k, p := range m {
+		if p > bestP {
+			best, bestP = k, p
+		}
+	}
+	return best
+}
@@ -2,12 +2,16 @@ package dit
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/PuerkitoBio/goquery"
 )
 
 const loginFormHTML = `<html><body>
@@ -178,4 +182,56 @@ func TestClassifierNotInitialized(t *testing.T) {
 	if err == nil {
 		t.Error("expected error for uninitialized classifier")
 	}
+	if _, err := c.Explain(loginFormHTML, ""); err == nil {
+		t.Error("expected Explain error for uninitialized classifier")
+	}
+}
+
+func TestContextOptions(t *testing.T) {
+	c := &Classifier{}
+
+	_, err := c.ExtractFormsContext(context.Background(), strings.NewReader(loginFormHTML), &Options{MaxInputSize: 16})
+	if !errors.Is(err, ErrInputTooLarge) {
+		t.Errorf("oversized input: got %v, want ErrInputTooLarge", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = c.ExtractPageTypeContext(ctx, strings.NewReader(loginFormHTML), nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled context: got %v, want context.Canceled", err)
+	}
+
+	html, err := readInput(context.Background(), strings.NewReader(loginFormHTML), int64(len(loginFormHTML)))
+	if err != nil || html != loginFormHTML {
+		t.Errorf("input at the limit: got %d bytes, %v", len(html), err)
+	}
+}
+
+func TestClassifyDocument(t *testing.T) {
+	modelPath := "model.json"
+	if _, err := os.Stat(modelPath); os.IsNotExist(err) {
+		t.Skip("model.json not found, skipping")
+	}
+
+	c, err := Load(modelPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(loginFormHTML))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := c.ClassifyDocument(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := c.ExtractPageType(loginFormHTML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Type != want.Type || len(got.Forms) != len(want.Forms) {
+		t.Errorf("ClassifyDocument = %+v, want %+v", got, want)
+	}
 }
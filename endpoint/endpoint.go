@@ -0,0 +1,162 @@
+// Package endpoint describes where and how a form is submitted: the
+// resolved action URL, the method and encoding, the hidden fields sent
+// along with the user's input, and whether the form posts to another
+// origin or over plain HTTP. Hidden fields are labelled as CSRF tokens,
+// server-side state, nonces, timestamps or JavaScript challenge tokens, so
+// that a replayed submission knows which values to fetch afresh.
+package endpoint
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/happyhackingspace/dit/internal/htmlutil"
+)
+
+// Form encodings.
+const (
+	EnctypeURLEncoded = "application/x-www-form-urlencoded"
+	EnctypeMultipart  = "multipart/form-data"
+	EnctypePlain      = "text/plain"
+)
+
+// HiddenField is a hidden input submitted with the form. Kind and
+// Confidence are set by ClassifyHidden; fields of any kind carry values
+// that must be fetched afresh before the form is replayed.
+type HiddenField struct {
+	Name       string     `json:"name"`
+	Value      string     `json:"value"`
+	Kind       HiddenKind `json:"kind,omitempty"`
+	Confidence float64    `json:"confidence,omitempty"` // 0..1
+}
+
+// Endpoint is the submission target of a form. Action is absolute when the
+// page URL or a <base href> is known, and is the page URL itself when the
+// form has no action. Virtual forms, which have no <form> element and are
+// submitted by script, only report their hidden fields.
+type Endpoint struct {
+	Action       string        `json:"action,omitempty"`
+	Method       string        `json:"method,omitempty"` // GET, POST or DIALOG
+	Enctype      string        `json:"enctype,omitempty"`
+	HiddenFields []HiddenField `json:"hidden_fields,omitempty"`
+	CSRFTokens   []string      `json:"csrf_tokens,omitempty"` // names of hidden fields likely to be CSRF tokens
+	CrossOrigin  bool          `json:"cross_origin,omitempty"`
+	Insecure     bool          `json:"insecure,omitempty"` // submitted over plain HTTP
+}
+
+// Analyze returns the endpoint of form, a <form> element or virtual form of
+// doc. pageURL, if set, resolves the action and is the origin the action
+// is compared against.
+func Analyze(doc *goquery.Document, form *goquery.Selection, pageURL string) Endpoint {
+	var ep Endpoint
+	ep.HiddenFields = hiddenFields(doc, form)
+	for _, f := range ep.HiddenFields {
+		if f.Kind == KindCSRF {
+			ep.CSRFTokens = append(ep.CSRFTokens, f.Name)
+		}
+	}
+	if htmlutil.IsVirtualForm(form) {
+		return ep
+	}
+
+	ep.Method = method(form)
+	ep.Enctype = enctype(form)
+	action := strings.TrimSpace(htmlutil.GetFormAction(form))
+	if action == "" {
+		ep.Action = pageURL
+	} else {
+		ep.Action = htmlutil.ResolveURL(htmlutil.BaseURL(doc, pageURL), action)
+	}
+
+	target, err := url.Parse(ep.Action)
+	if err != nil {
+		return ep
+	}
+	page, _ := url.Parse(pageURL)
+	if target.IsAbs() {
+		ep.Insecure = strings.EqualFold(target.Scheme, "http")
+		if page != nil && page.IsAbs() {
+			ep.CrossOrigin = !sameOrigin(page, target)
+		}
+	}
+	return ep
+}
+
+// method returns the upper-cased submission method, GET when missing or
+// invalid as browsers do.
+func method(form *goquery.Selection) string {
+	switch m := htmlutil.GetFormMethod(form); m {
+	case "post", "dialog":
+		return strings.ToUpper(m)
+	}
+	return "GET"
+}
+
+// enctype returns the form encoding, defaulting to URL encoding for
+// missing or unknown values as browsers do.
+func enctype(form *goquery.Selection) string {
+	switch e := strings.ToLower(strings.TrimSpace(form.AttrOr("enctype", ""))); e {
+	case EnctypeMultipart, EnctypePlain:
+		return e
+	}
+	return EnctypeURLEncoded
+}
+
+// hiddenFields returns the named, enabled hidden inputs of form, including
+// those outside it that name it in their form attribute, classified by
+// ClassifyHidden. Empty fields that inline scripts refer to are taken to be
+// filled in by a JavaScript challenge.
+func hiddenFields(doc *goquery.Document, form *goquery.Selection) []HiddenField {
+	inputs := form.Find(`input[type=hidden i]`)
+	if id := form.AttrOr("id", ""); id != "" && !htmlutil.IsVirtualForm(form) {
+		doc.Find(`input[type=hidden i][form]`).Each(func(_ int, s *goquery.Selection) {
+			if s.AttrOr("form", "") == id {
+				inputs = inputs.AddSelection(s)
+			}
+		})
+	}
+	var out []HiddenField
+	var scripts *string
+	inputs.Each(func(_ int, s *goquery.Selection) {
+		name := s.AttrOr("name", "")
+		if name == "" {
+			return
+		}
+		if _, disabled := s.Attr("disabled"); disabled {
+			return
+		}
+		f := HiddenField{Name: name, Value: s.AttrOr("value", "")}
+		f.Kind, f.Confidence = ClassifyHidden(f.Name, f.Value)
+		if f.Kind == "" && f.Value == "" {
+			if scripts == nil {
+				text := inlineScripts(doc)
+				scripts = &text
+			}
+			if filledByScript(*scripts, name, s.AttrOr("id", "")) {
+				f.Kind, f.Confidence = KindChallenge, scriptFilledConfidence
+			}
+		}
+		out = append(out, f)
+	})
+	return out
+}
+
+func sameOrigin(a, b *url.URL) bool {
+	return strings.EqualFold(a.Scheme, b.Scheme) && strings.EqualFold(hostPort(a), hostPort(b))
+}
+
+// hostPort returns the host of u with the scheme's default port made
+// explicit, so that example.com and example.com:443 compare equal.
+func hostPort(u *url.URL) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "https":
+		return u.Hostname() + ":443"
+	case "http":
+		return u.Hostname() + ":80"
+	}
+	return u.Host
+}
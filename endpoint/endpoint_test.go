@@ -0,0 +1,109 @@
+package endpoint_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/happyhackingspace/dit/endpoint"
+	"github.com/happyhackingspace/dit/internal/htmlutil"
+)
+
+func TestAnalyze(t *testing.T) {
+	doc, err := htmlutil.LoadHTMLString(`<html><head><base href="/app/"></head><body>
+<form id="login" action="session" method="Post" enctype="multipart/form-data">
+  <input type="hidden" name="authenticity_token" value="x">
+  <input type="hidden" name="return_to" value="/dashboard">
+  <input type="HIDDEN" name="sig" value="b4Xk9QzP2mN7vR1tY8wL3sJ6">
+  <input type="hidden" value="unnamed">
+  <input type="hidden" name="off" value="1" disabled>
+  <input name="login"><input type="password" name="password">
+</form>
+<input type="hidden" form="login" name="locale" value="en">
+<form action="http://partner.example.net/search"><input name="q"></form>
+<form><input name="q"></form>
+</body></html>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forms := htmlutil.GetForms(doc)
+	const page = "https://example.com/login"
+
+	ep := endpoint.Analyze(doc, forms[0], page)
+	if ep.Action != "https://example.com/app/session" || ep.Method != "POST" || ep.Enctype != endpoint.EnctypeMultipart {
+		t.Errorf("login endpoint = %s %s %s", ep.Method, ep.Action, ep.Enctype)
+	}
+	wantHidden := []endpoint.HiddenField{
+		{Name: "authenticity_token", Value: "x", Kind: endpoint.KindCSRF, Confidence: 0.95},
+		{Name: "return_to", Value: "/dashboard"},
+		{Name: "sig", Value: "b4Xk9QzP2mN7vR1tY8wL3sJ6", Kind: endpoint.KindCSRF, Confidence: 0.6},
+		{Name: "locale", Value: "en"},
+	}
+	if !reflect.DeepEqual(ep.HiddenFields, wantHidden) {
+		t.Errorf("hidden fields = %+v, want %+v", ep.HiddenFields, wantHidden)
+	}
+	if want := []string{"authenticity_token", "sig"}; !reflect.DeepEqual(ep.CSRFTokens, want) {
+		t.Errorf("CSRF tokens = %v, want %v", ep.CSRFTokens, want)
+	}
+	if ep.CrossOrigin || ep.Insecure {
+		t.Errorf("same-origin HTTPS form flagged: %+v", ep)
+	}
+
+	ep = endpoint.Analyze(doc, forms[1], page)
+	if ep.Method != "GET" || ep.Enctype != endpoint.EnctypeURLEncoded || !ep.CrossOrigin || !ep.Insecure {
+		t.Errorf("partner endpoint = %+v", ep)
+	}
+
+	ep = endpoint.Analyze(doc, forms[2], page)
+	if ep.Action != page {
+		t.Errorf("form without action: Action = %q, want the page URL", ep.Action)
+	}
+	if ep = endpoint.Analyze(doc, forms[2], ""); ep.Action != "" || ep.CrossOrigin || ep.Insecure {
+		t.Errorf("form without action and page URL = %+v", ep)
+	}
+}
+
+func TestClassifyHidden(t *testing.T) {
+	tests := []struct {
+		name, value string
+		want        endpoint.HiddenKind
+	}{
+		{"__VIEWSTATE", "/wEPDwUKMTY1NDU2MTA1MmRk", endpoint.KindState},
+		{"__EVENTVALIDATION", "/wEdAAKZ", endpoint.KindState},
+		{"__RequestVerificationToken", "CfDJ8", endpoint.KindCSRF},
+		{"csrfmiddlewaretoken", "abc", endpoint.KindCSRF},
+		{"my_csrf_field", "", endpoint.KindCSRF},
+		{"_wpnonce", "a1b2c3d4e5", endpoint.KindNonce},
+		{"g-recaptcha-response", "", endpoint.KindChallenge},
+		{"ts", "1760620000", endpoint.KindTimestamp},
+		{"t", "dark", ""},
+		{"rendered", "1760620000123", endpoint.KindTimestamp},
+		{"request", "123e4567-e89b-12d3-a456-426614174000", endpoint.KindNonce},
+		{"redirect", "/account/settings", ""},
+		{"remember", "1", ""},
+		{"lang", "en-US", ""},
+	}
+	for _, tt := range tests {
+		if got, _ := endpoint.ClassifyHidden(tt.name, tt.value); got != tt.want {
+			t.Errorf("ClassifyHidden(%q, %q) = %q, want %q", tt.name, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestScriptFilledHiddenField(t *testing.T) {
+	doc, err := htmlutil.LoadHTMLString(`<form action="/login" method="post">
+<input type="hidden" name="bd" id="bdata" value="">
+<input type="hidden" name="next" value="">
+<input name="user"><input type="password" name="pass">
+</form>
+<script>document.getElementById("bdata").value = collect();</script>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ep := endpoint.Analyze(doc, htmlutil.GetForms(doc)[0], "")
+	if got := ep.HiddenFields[0].Kind; got != endpoint.KindChallenge {
+		t.Errorf("script-filled field kind = %q, want challenge", got)
+	}
+	if got := ep.HiddenFields[1].Kind; got != "" {
+		t.Errorf("static field kind = %q, want none", got)
+	}
+}
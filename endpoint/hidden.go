@@ -0,0 +1,170 @@
+package endpoint
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// HiddenKind is the role of a hidden field whose value changes between
+// requests.
+type HiddenKind string
+
+// Hidden field kinds.
+const (
+	KindCSRF      HiddenKind = "csrf"      // anti-CSRF token
+	KindState     HiddenKind = "state"     // server-side view or flow state (__VIEWSTATE, javax.faces.ViewState)
+	KindNonce     HiddenKind = "nonce"     // single-use nonce, OAuth state or login ticket
+	KindTimestamp HiddenKind = "timestamp" // time the form was rendered
+	KindChallenge HiddenKind = "challenge" // CAPTCHA response or bot-detection token filled in by script
+)
+
+// hiddenRule labels fields whose name matches re.
+type hiddenRule struct {
+	kind       HiddenKind
+	confidence float64
+	re         *regexp.Regexp
+}
+
+// hiddenRules are tried in order against the field name; the first match
+// wins. Exact names of well-known frameworks come before generic patterns.
+var hiddenRules = []hiddenRule{
+	{KindState, 0.95, regexp.MustCompile(`^(__viewstate|__viewstategenerator|__viewstateencrypted|__eventvalidation|__previouspage|javax\.faces\.viewstate|execution|form_build_id)$`)},
+	{KindCSRF, 0.95, regexp.MustCompile(`^(authenticity_token|__requestverificationtoken|csrfmiddlewaretoken|csrf_token|csrftoken|_csrf|_csrf_token|csrf|xsrf|_xsrf|_token|yii_csrf_token|form_key|form_token|anticsrf)$`)},
+	{KindChallenge, 0.9, regexp.MustCompile(`^(g-recaptcha-response|h-captcha-response|cf-turnstile-response|cf_chl_\w+|jschl_vc|jschl_answer|fc-token|arkose[\w-]*|sensor_data|_px\w*|iobb|ioblackbox|bm-verify|x-kpsdk-\w+)$`)},
+	{KindNonce, 0.9, regexp.MustCompile(`^(_wpnonce|nonce|_nonce|lt|state|oauth_state|login_challenge)$`)},
+	{KindTimestamp, 0.85, regexp.MustCompile(`^(timestamp|ts|_ts|time|_time|form_time|formtime|t|_t|rendered_at|loaded_at)$`)},
+	{KindCSRF, 0.8, regexp.MustCompile(`csrf|xsrf|anti.?forgery|verification.?token|request.?token|authenticity`)},
+	{KindChallenge, 0.75, regexp.MustCompile(`captcha|recaptcha|turnstile|challenge|fingerprint|device.?id|bot.?detect|blackbox`)},
+	{KindNonce, 0.75, regexp.MustCompile(`nonce`)},
+	{KindTimestamp, 0.7, regexp.MustCompile(`timestamp|time.?stamp|_time$|_at$`)},
+}
+
+// Confidences of labels inferred from the value alone or from scripts.
+const (
+	tokenValueConfidence   = 0.6
+	epochValueConfidence   = 0.6
+	uuidValueConfidence    = 0.5
+	scriptFilledConfidence = 0.5
+)
+
+// minTokenLength is the length from which a random-looking hidden value is
+// taken to be a token.
+const minTokenLength = 16
+
+var (
+	tokenValue = regexp.MustCompile(`^[A-Za-z0-9+/=_\-.:]+$`)
+	uuidValue  = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	digits     = regexp.MustCompile(`^\d+$`)
+)
+
+// ClassifyHidden labels a hidden field from its name and value. It returns
+// "" and 0 for fields that look static, such as redirect targets, locale or
+// feature flags, which can be replayed as they are.
+func ClassifyHidden(name, value string) (HiddenKind, float64) {
+	lower := strings.ToLower(name)
+	for _, r := range hiddenRules {
+		if r.re.MatchString(lower) {
+			if r.kind == KindTimestamp && value != "" && !isTimestamp(value) {
+				continue
+			}
+			return r.kind, r.confidence
+		}
+	}
+	switch {
+	case isEpoch(value):
+		return KindTimestamp, epochValueConfidence
+	case uuidValue.MatchString(value):
+		return KindNonce, uuidValueConfidence
+	case isRandomToken(value):
+		return KindCSRF, tokenValueConfidence
+	}
+	return "", 0
+}
+
+// isRandomToken reports whether value is long and random-looking.
+func isRandomToken(value string) bool {
+	return len(value) >= minTokenLength && len(value) <= 512 && tokenValue.MatchString(value) && entropy(value) >= 3.5
+}
+
+// isTimestamp reports whether value is a Unix time or an RFC 3339 date.
+func isTimestamp(value string) bool {
+	if isEpoch(value) {
+		return true
+	}
+	_, err := time.Parse(time.RFC3339, value)
+	return err == nil
+}
+
+// isEpoch reports whether value is a Unix time in seconds or milliseconds
+// between 2001 and 2100.
+func isEpoch(value string) bool {
+	if !digits.MatchString(value) {
+		return false
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return false
+	}
+	switch len(value) {
+	case 13:
+		n /= 1000
+	case 10:
+	default:
+		return false
+	}
+	return n >= 978307200 && n < 4102444800
+}
+
+// entropy returns the Shannon entropy of s in bits per byte.
+func entropy(s string) float64 {
+	var counts [256]int
+	for i := 0; i < len(s); i++ {
+		counts[s[i]]++
+	}
+	h := 0.0
+	for _, c := range counts {
+		if c > 0 {
+			p := float64(c) / float64(len(s))
+			h -= p * math.Log2(p)
+		}
+	}
+	return h
+}
+
+// inlineScripts returns the text of the inline scripts of doc.
+func inlineScripts(doc *goquery.Document) string {
+	var b strings.Builder
+	doc.Find("script:not([src])").Each(func(_ int, s *goquery.Selection) {
+		b.WriteString(s.Text())
+		b.WriteByte('\n')
+	})
+	return b.String()
+}
+
+// minScriptRefLength is the shortest name or id looked up in scripts;
+// shorter ones match too much unrelated code.
+const minScriptRefLength = 3
+
+// filledByScript reports whether scripts refer to the field by name or id
+// as a quoted string or in a selector.
+func filledByScript(scripts, name, id string) bool {
+	if scripts == "" {
+		return false
+	}
+	for _, ident := range []string{name, id} {
+		if len(ident) < minScriptRefLength {
+			continue
+		}
+		for _, ref := range []string{`"` + ident + `"`, `'` + ident + `'`, "#" + ident, "name=" + ident, `name="` + ident, `name='` + ident} {
+			if strings.Contains(scripts, ref) {
+				return true
+			}
+		}
+	}
+	return false
+}
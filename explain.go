@@ -0,0 +1,50 @@
+package dit
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/happyhackingspace/dit/classifier"
+)
+
+// Explanation lists, for the page type, each form type and each field
+// type, the features that weighed most for and against the prediction,
+// named after the model's feature pipelines and vocabularies.
+type Explanation = classifier.Explanation
+
+// Contribution is one feature's share of a class score.
+type Contribution = classifier.Contribution
+
+// Explain explains the page type, form type and field type predictions
+// for the HTML fetched from pageURL, which may be empty.
+func (c *Classifier) Explain(html, pageURL string) (*Explanation, error) {
+	doc, err := parseDocument(html)
+	if err != nil {
+		return nil, err
+	}
+	return c.explain(context.Background(), doc, pageURL)
+}
+
+// ExplainContext reads HTML from r and explains its predictions like
+// Explain, honouring ctx and the limits in opts like
+// ExtractPageTypeContext.
+func (c *Classifier) ExplainContext(ctx context.Context, r io.Reader, opts *Options) (*Explanation, error) {
+	ctx, cancel, doc, o, err := prepareInput(ctx, r, opts)
+	defer cancel()
+	if err != nil {
+		return nil, err
+	}
+	return c.explain(ctx, doc, o.URL)
+}
+
+func (c *Classifier) explain(ctx context.Context, doc *classifier.Document, pageURL string) (*Explanation, error) {
+	if c.fc == nil || c.fc.FormModel == nil {
+		return nil, fmt.Errorf("dit: classifier not initialized")
+	}
+	exp, err := c.fc.ExplainDocument(ctx, doc, classifier.ExplainOptions{URL: pageURL})
+	if err != nil {
+		return nil, fmt.Errorf("dit: %w", err)
+	}
+	return exp, nil
+}
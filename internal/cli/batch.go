@@ -0,0 +1,262 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/happyhackingspace/dit"
+)
+
+// maxJSONLLineBytes bounds a single JSONL record (HTML is embedded inline).
+const maxJSONLLineBytes = 64 << 20
+
+// batchOptions controls batch classification.
+type batchOptions struct {
+	workers  int
+	proba    bool
+	classify dit.Options // URL and Response are set per job
+	fetch    fetchOptions
+}
+
+// batchJob is a single classification target. If html is empty, the target
+// is fetched (URL) or read (file) by the worker.
+type batchJob struct {
+	target string
+	url    string
+	html   string
+}
+
+// batchRecord is one line of batch output.
+type batchRecord struct {
+	Target string `json:"target"`
+	URL    string `json:"url,omitempty"`
+	Result any    `json:"result,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// jsonlInput is one line of JSONL input.
+type jsonlInput struct {
+	URL  string `json:"url"`
+	HTML string `json:"html"`
+}
+
+// runBatch classifies every job produced by produce using a bounded worker
+// pool sharing one classifier, and streams one JSON record per line to out
+// in completion order.
+func runBatch(cl *dit.Classifier, produce func(jobs chan<- batchJob) error, out io.Writer, opts batchOptions) error {
+	workers := opts.workers
+	if workers <= 0 {
+		workers = 1
+	}
+
+	jobs := make(chan batchJob, workers)
+	records := make(chan batchRecord, workers)
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				records <- classifyBatchJob(cl, job, opts)
+			}
+		}()
+	}
+
+	produceErr := make(chan error, 1)
+	go func() {
+		defer close(jobs)
+		produceErr <- produce(jobs)
+	}()
+
+	go func() {
+		wg.Wait()
+		close(records)
+	}()
+
+	start := time.Now()
+	enc := json.NewEncoder(out)
+	total, failed := 0, 0
+	var writeErr error
+	for rec := range records {
+		total++
+		if rec.Error != "" {
+			failed++
+		}
+		if writeErr == nil {
+			writeErr = enc.Encode(rec)
+		}
+	}
+	slog.Info("Batch completed", "targets", total, "errors", failed, "duration", time.Since(start))
+
+	if err := <-produceErr; err != nil {
+		return err
+	}
+	if writeErr != nil {
+		return fmt.Errorf("write output: %w", writeErr)
+	}
+	return nil
+}
+
+func classifyBatchJob(cl *dit.Classifier, job batchJob, opts batchOptions) batchRecord {
+	rec := batchRecord{Target: job.target, URL: job.url}
+
+	htmlContent := job.html
+	var resp *dit.Response
+	if htmlContent == "" {
+		var err error
+		htmlContent, resp, err = fetchHTML(job.target, opts.fetch)
+		if err != nil {
+			rec.Error = err.Error()
+			return rec
+		}
+	}
+
+	classifyOpts := opts.classify
+	classifyOpts.URL, classifyOpts.Response = job.url, resp
+	result, err := classifyHTML(cl, htmlContent, &classifyOpts, opts.proba)
+	if err != nil {
+		rec.Error = err.Error()
+		return rec
+	}
+	rec.Result = result
+	return rec
+}
+
+// classifyHTML classifies the page and its forms, falling back to form-only
+// classification when the model has no page classifier. opts.Response, if
+// known, adds the response headers and status to bot-protection detection.
+func classifyHTML(cl *dit.Classifier, htmlContent string, opts *dit.Options, proba bool) (any, error) {
+	ctx := context.Background()
+	opts.MaxInputSize = -1
+	if proba {
+		if page, err := cl.ExtractPageTypeProbaContext(ctx, strings.NewReader(htmlContent), opts); err == nil {
+			return page, nil
+		}
+		return cl.ExtractFormsProbaContext(ctx, strings.NewReader(htmlContent), opts)
+	}
+	if page, err := cl.ExtractPageTypeContext(ctx, strings.NewReader(htmlContent), opts); err == nil {
+		return page, nil
+	}
+	return cl.ExtractFormsContext(ctx, strings.NewReader(htmlContent), opts)
+}
+
+// openInput opens path for reading, treating "-" as stdin.
+func openInput(path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	return f, nil
+}
+
+// targetListProducer yields one job per non-empty, non-comment line of path.
+// Each line is a URL or a file path.
+func targetListProducer(path string) func(chan<- batchJob) error {
+	return func(jobs chan<- batchJob) error {
+		r, err := openInput(path)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = r.Close() }()
+
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			job := batchJob{target: line}
+			if isURL(line) {
+				job.url = line
+			}
+			jobs <- job
+		}
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("read %s: %w", path, err)
+		}
+		return nil
+	}
+}
+
+// jsonlProducer yields one job per {"url": ..., "html": ...} line of path.
+// Records without html are fetched from url.
+func jsonlProducer(path string) func(chan<- batchJob) error {
+	return func(jobs chan<- batchJob) error {
+		r, err := openInput(path)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = r.Close() }()
+
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxJSONLLineBytes)
+		lineNo := 0
+		for scanner.Scan() {
+			lineNo++
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue
+			}
+			var in jsonlInput
+			if err := json.Unmarshal([]byte(line), &in); err != nil {
+				slog.Warn("Skipping invalid JSONL record", "line", lineNo, "error", err)
+				continue
+			}
+			target := in.URL
+			if target == "" {
+				target = fmt.Sprintf("%s:%d", path, lineNo)
+			}
+			if in.HTML == "" && !isURL(in.URL) {
+				slog.Warn("Skipping JSONL record without html or url", "line", lineNo)
+				continue
+			}
+			jobs <- batchJob{target: target, url: in.URL, html: in.HTML}
+		}
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("read %s: %w", path, err)
+		}
+		return nil
+	}
+}
+
+// dirProducer yields one job per .html/.htm file in dir, descending into
+// subdirectories when recursive is set.
+func dirProducer(dir string, recursive bool) func(chan<- batchJob) error {
+	return func(jobs chan<- batchJob) error {
+		return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if path != dir && !recursive {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			switch strings.ToLower(filepath.Ext(path)) {
+			case ".html", ".htm":
+				jobs <- batchJob{target: path}
+			}
+			return nil
+		})
+	}
+}
+
+func isDir(path string) bool {
+	fi, err := os.Stat(path)
+	return err == nil && fi.IsDir()
+}
@@ -0,0 +1,170 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/happyhackingspace/dit"
+)
+
+// collect runs produce and returns its jobs.
+func collect(t *testing.T, produce func(chan<- batchJob) error) []batchJob {
+	t.Helper()
+	jobs := make(chan batchJob)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(jobs)
+		errc <- produce(jobs)
+	}()
+	var out []batchJob
+	for job := range jobs {
+		out = append(out, job)
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestRunBatchWorkers(t *testing.T) {
+	const workers = 4
+	// Every request waits until all workers are fetching at once, so the
+	// batch only completes quickly if the jobs really run in parallel.
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	all := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+			if maxInFlight == workers {
+				close(all)
+			}
+		}
+		mu.Unlock()
+		select {
+		case <-all:
+		case <-time.After(5 * time.Second):
+		}
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		_, _ = w.Write([]byte("<html><body><form><input name=q></form></body></html>"))
+	}))
+	defer srv.Close()
+
+	missing := filepath.Join(t.TempDir(), "missing.html")
+	var targets []string
+	for i := range 2 * workers {
+		targets = append(targets, srv.URL+"/page"+strconv.Itoa(i))
+	}
+	targets = append(targets, missing)
+	produce := func(jobs chan<- batchJob) error {
+		for _, target := range targets {
+			job := batchJob{target: target}
+			if isURL(target) {
+				job.url = target
+			}
+			jobs <- job
+		}
+		return nil
+	}
+
+	var out bytes.Buffer
+	// An uninitialized classifier fails every classification, which the
+	// records must report per target.
+	if err := runBatch(&dit.Classifier{}, produce, &out, batchOptions{workers: workers}); err != nil {
+		t.Fatal(err)
+	}
+	if maxInFlight != workers {
+		t.Errorf("max concurrent fetches = %d, want %d", maxInFlight, workers)
+	}
+
+	var got []string
+	for line := range strings.Lines(out.String()) {
+		var rec batchRecord
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("invalid record %q: %v", line, err)
+		}
+		got = append(got, rec.Target)
+		switch {
+		case rec.Result != nil:
+			t.Errorf("%s: unexpected result %v", rec.Target, rec.Result)
+		case rec.Target == missing && !strings.Contains(rec.Error, "read file"):
+			t.Errorf("%s: error = %q, want a read error", rec.Target, rec.Error)
+		case rec.Target != missing && !strings.Contains(rec.Error, "not initialized"):
+			t.Errorf("%s: error = %q, want a classifier error", rec.Target, rec.Error)
+		}
+		if isURL(rec.Target) && rec.URL != rec.Target {
+			t.Errorf("%s: url = %q", rec.Target, rec.URL)
+		}
+	}
+	slices.Sort(got)
+	slices.Sort(targets)
+	if !slices.Equal(got, targets) {
+		t.Errorf("record targets = %q, want %q", got, targets)
+	}
+}
+
+func TestDirProducer(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.html", "b.HTM", "notes.txt", "sub/c.html", "sub/deep/d.htm"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("<html></html>"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	targets := func(recursive bool) []string {
+		var out []string
+		for _, job := range collect(t, dirProducer(dir, recursive)) {
+			rel, err := filepath.Rel(dir, job.target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out = append(out, filepath.ToSlash(rel))
+		}
+		return out
+	}
+	if got, want := targets(false), []string{"a.html", "b.HTM"}; !slices.Equal(got, want) {
+		t.Errorf("non-recursive = %q, want %q", got, want)
+	}
+	if got, want := targets(true), []string{"a.html", "b.HTM", "sub/c.html", "sub/deep/d.htm"}; !slices.Equal(got, want) {
+		t.Errorf("recursive = %q, want %q", got, want)
+	}
+}
+
+func TestJSONLProducer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pages.jsonl")
+	input := `{"url": "https://example.com/login", "html": "<form></form>"}
+not json
+{"html": "<p>inline</p>"}
+{"url": "not-a-url"}
+{"url": "https://example.com/fetch"}
+`
+	if err := os.WriteFile(path, []byte(input), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	want := []batchJob{
+		{target: "https://example.com/login", url: "https://example.com/login", html: "<form></form>"},
+		{target: path + ":3", html: "<p>inline</p>"},
+		{target: "https://example.com/fetch", url: "https://example.com/fetch"},
+	}
+	if got := collect(t, jsonlProducer(path)); !slices.Equal(got, want) {
+		t.Errorf("jobs = %+v, want %+v", got, want)
+	}
+}
@@ -50,9 +50,12 @@ func (c *CLI) setupCommands() {
 
 	c.rootCmd.AddCommand(c.newTrainCommand())
 	c.rootCmd.AddCommand(c.newRunCommand())
+	c.rootCmd.AddCommand(c.newTemplateCommand())
 	c.rootCmd.AddCommand(c.newEvaluateCommand())
 	c.rootCmd.AddCommand(c.newUpCommand())
 	c.rootCmd.AddCommand(c.newDataCommand())
+	c.rootCmd.AddCommand(c.newServeCommand())
+	c.rootCmd.AddCommand(c.newModelCommand())
 }
 
 // Run executes the CLI and returns any error.
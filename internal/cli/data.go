@@ -3,20 +3,20 @@ package cli
 import (
 	"archive/tar"
 	"compress/gzip"
+	"context"
 	"fmt"
 	"io"
 	"log/slog"
-	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 
-	"github.com/happyhackingspace/dit"
+	"github.com/happyhackingspace/dit/internal/download"
 	"github.com/spf13/cobra"
 )
 
-const hfDataURL = "https://huggingface.co/datasets/happyhackingspace/dit/resolve/main/data.tar.gz"
+const dataArchiveName = "data.tar.gz"
 
 func (c *CLI) newDataCommand() *cobra.Command {
 	dataCmd := &cobra.Command{
@@ -28,16 +28,21 @@ func (c *CLI) newDataCommand() *cobra.Command {
 	}
 
 	var downloadDataFolder string
+	var skipVerify bool
 	downloadCmd := &cobra.Command{
 		Use:   "download",
 		Short: "Download training data and model from Hugging Face",
 		Example: `  dit data download
-  dit data download --data-folder data`,
+  dit data download --data-folder data
+
+  # Mirror whose SHA256SUMS manifest does not list every file
+  DIT_MODEL_URL=https://mirror.example/dit dit data download --skip-verify`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return dataDownload(downloadDataFolder)
+			return dataDownload(downloadDataFolder, skipVerify)
 		},
 	}
 	downloadCmd.Flags().StringVar(&downloadDataFolder, "data-folder", "data", "Destination folder for training data")
+	downloadCmd.Flags().BoolVar(&skipVerify, "skip-verify", false, "Allow files missing from the published checksum manifest")
 
 	var uploadDataFolder string
 	uploadCmd := &cobra.Command{
@@ -55,22 +60,30 @@ func (c *CLI) newDataCommand() *cobra.Command {
 	return dataCmd
 }
 
-func dataDownload(dataFolder string) error {
-	slog.Info("Downloading training data", "url", hfDataURL)
-	resp, err := http.Get(hfDataURL)
-	if err != nil {
+func dataDownload(dataFolder string, skipVerify bool) error {
+	ctx := context.Background()
+	client := download.New()
+	client.SkipVerify = client.SkipVerify || skipVerify
+
+	// Keep the archive at a stable path so an interrupted download resumes.
+	archive := filepath.Join(os.TempDir(), "dit-"+dataArchiveName)
+	slog.Info("Downloading training data", "url", client.URL(dataArchiveName))
+	if _, err := client.Download(ctx, dataArchiveName, archive); err != nil {
 		return fmt.Errorf("download data: %w", err)
 	}
-	defer func() { _ = resp.Body.Close() }()
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("download data: HTTP %d", resp.StatusCode)
+	defer func() { _ = os.Remove(archive) }()
+
+	af, err := os.Open(archive)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", archive, err)
 	}
+	defer func() { _ = af.Close() }()
 
 	if err := os.RemoveAll(dataFolder); err != nil {
 		return fmt.Errorf("remove existing %s: %w", dataFolder, err)
 	}
 
-	gr, err := gzip.NewReader(resp.Body)
+	gr, err := gzip.NewReader(af)
 	if err != nil {
 		return fmt.Errorf("gzip reader: %w", err)
 	}
@@ -116,28 +129,12 @@ func dataDownload(dataFolder string) error {
 	}
 	slog.Info("Training data extracted", "files", count, "folder", dataFolder)
 
-	slog.Info("Downloading model", "url", dit.ModelURL)
-	modelResp, err := http.Get(dit.ModelURL)
+	slog.Info("Downloading model", "url", client.URL("model.json"))
+	written, err := client.Download(ctx, "model.json", "model.json")
 	if err != nil {
 		return fmt.Errorf("download model: %w", err)
 	}
-	defer func() { _ = modelResp.Body.Close() }()
-	if modelResp.StatusCode != http.StatusOK {
-		return fmt.Errorf("download model: HTTP %d", modelResp.StatusCode)
-	}
-
-	mf, err := os.Create("model.json")
-	if err != nil {
-		return fmt.Errorf("create model.json: %w", err)
-	}
-	written, err := io.Copy(mf, modelResp.Body)
-	if err != nil {
-		_ = mf.Close()
-		return fmt.Errorf("write model.json: %w", err)
-	}
-	_ = mf.Close()
 	slog.Info("Model downloaded", "size", fmt.Sprintf("%.1fMB", float64(written)/1024/1024))
-
 	return nil
 }
 
@@ -146,7 +143,16 @@ func dataUpload(dataFolder string) error {
 		return fmt.Errorf("huggingface-cli not found in PATH; install with: pip install huggingface_hub")
 	}
 
-	tarPath := "data.tar.gz"
+	// Files not uploaded this time keep their published checksums.
+	manifest, err := download.New().Manifest(context.Background())
+	if err != nil {
+		return err
+	}
+	if manifest == nil {
+		manifest = make(download.Manifest)
+	}
+
+	tarPath := dataArchiveName
 	slog.Info("Creating archive", "source", dataFolder, "dest", tarPath)
 
 	tf, err := os.Create(tarPath)
@@ -214,6 +220,7 @@ func dataUpload(dataFolder string) error {
 		return fmt.Errorf("upload data folder: %w", err)
 	}
 
+	published := []string{tarPath}
 	if _, err := os.Stat("model.json"); err == nil {
 		slog.Info("Uploading model.json")
 		cmd = exec.Command("huggingface-cli", "upload", "happyhackingspace/dit", "model.json", "model.json", "--repo-type", "dataset")
@@ -222,6 +229,29 @@ func dataUpload(dataFolder string) error {
 		if err := cmd.Run(); err != nil {
 			return fmt.Errorf("upload model.json: %w", err)
 		}
+		published = append(published, "model.json")
+	}
+
+	if err := manifest.Add(published...); err != nil {
+		return fmt.Errorf("write %s: %w", download.ManifestName, err)
+	}
+	mf, err := os.Create(download.ManifestName)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", download.ManifestName, err)
+	}
+	if err := manifest.Write(mf); err != nil {
+		_ = mf.Close()
+		return fmt.Errorf("write %s: %w", download.ManifestName, err)
+	}
+	if err := mf.Close(); err != nil {
+		return fmt.Errorf("write %s: %w", download.ManifestName, err)
+	}
+	slog.Info("Uploading " + download.ManifestName)
+	cmd = exec.Command("huggingface-cli", "upload", "happyhackingspace/dit", download.ManifestName, download.ManifestName, "--repo-type", "dataset")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("upload %s: %w", download.ManifestName, err)
 	}
 
 	slog.Info("Upload complete")
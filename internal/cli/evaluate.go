@@ -13,6 +13,7 @@ import (
 func (c *CLI) newEvaluateCommand() *cobra.Command {
 	var dataFolder string
 	var cvFolds int
+	var multiLabel bool
 
 	cmd := &cobra.Command{
 		Use:     "evaluate",
@@ -22,8 +23,9 @@ func (c *CLI) newEvaluateCommand() *cobra.Command {
 			slog.Info("Evaluating", "folds", cvFolds, "data-folder", dataFolder)
 			start := time.Now()
 			result, err := dit.Evaluate(dataFolder, &dit.EvalConfig{
-				Folds:   cvFolds,
-				Verbose: c.verbose,
+				Folds:      cvFolds,
+				Verbose:    c.verbose,
+				MultiLabel: multiLabel,
 			})
 			if err != nil {
 				return err
@@ -39,6 +41,10 @@ func (c *CLI) newEvaluateCommand() *cobra.Command {
 					result.FieldAccuracy*100, result.FieldCorrect, result.FieldTotal)
 				fmt.Printf("Sequence accuracy: %.1f%% (%d/%d forms)\n",
 					result.SequenceAccuracy*100, result.SequenceCorrect, result.SequenceTotal)
+				fmt.Printf("Field macro F1: %.1f%%\n", result.FieldMacroF1*100)
+				if c.verbose {
+					printClassReport(result.FieldConfusion, result.FieldClasses, result.FieldPrecision, result.FieldRecall, result.FieldF1)
+				}
 			}
 			if result.PageTotal > 0 {
 				fmt.Printf("Page type accuracy: %.1f%% (%d/%d)\n",
@@ -48,12 +54,22 @@ func (c *CLI) newEvaluateCommand() *cobra.Command {
 				printConfusionMatrix(result.PageConfusion, result.PageClasses)
 				printClassReport(result.PageConfusion, result.PageClasses, result.PagePrecision, result.PageRecall, result.PageF1)
 			}
+			if result.PageMultiLabelTotal > 0 {
+				fmt.Printf("\nMulti-label page types (%d pages):\n", result.PageMultiLabelTotal)
+				fmt.Printf("Micro F1: %.1f%%  Macro F1: %.1f%%\n",
+					result.PageMultiLabelMicroF1*100, result.PageMultiLabelMacroF1*100)
+				fmt.Printf("%8s  %6s\n", "class", "f1")
+				for _, cls := range result.PageMultiLabelClasses {
+					fmt.Printf("%8s  %5.1f%%\n", cls, result.PageMultiLabelF1[cls]*100)
+				}
+			}
 			return nil
 		},
 	}
 
 	cmd.Flags().StringVar(&dataFolder, "data-folder", "data", "Path to annotation data folder")
 	cmd.Flags().IntVar(&cvFolds, "cv", 10, "Number of cross-validation folds")
+	cmd.Flags().BoolVar(&multiLabel, "multi-label", false, "Also evaluate the multi-label page type model (uses page_types from the page index)")
 	return cmd
 }
 
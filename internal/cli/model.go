@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/happyhackingspace/dit"
+	"github.com/happyhackingspace/dit/classifier"
+	"github.com/spf13/cobra"
+)
+
+func (c *CLI) newModelCommand() *cobra.Command {
+	modelCmd := &cobra.Command{
+		Use:   "model",
+		Short: "Inspect and convert model files",
+		Run: func(cmd *cobra.Command, args []string) {
+			_ = cmd.Help()
+		},
+	}
+
+	var format string
+	convertCmd := &cobra.Command{
+		Use:   "convert <input> <output>",
+		Short: "Convert a model between the JSON and binary formats",
+		Example: `  # Convert to the compact binary format
+  dit model convert model.json model.bin
+
+  # Convert back to JSON
+  dit model convert model.bin model.json --format json`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return modelConvert(args[0], args[1], format)
+		},
+	}
+	convertCmd.Flags().StringVar(&format, "format", "binary", "Output format: binary or json")
+
+	var asJSON bool
+	infoCmd := &cobra.Command{
+		Use:   "info <modelfile>",
+		Short: "Print model metadata",
+		Example: `  dit model info model.json
+  dit model info model.bin --json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return modelInfo(os.Stdout, args[0], asJSON)
+		},
+	}
+	infoCmd.Flags().BoolVar(&asJSON, "json", false, "Print metadata as JSON")
+
+	modelCmd.AddCommand(convertCmd, infoCmd)
+	return modelCmd
+}
+
+func modelConvert(input, output, format string) error {
+	if format != "binary" && format != "json" {
+		return fmt.Errorf("unknown format %q (want binary or json)", format)
+	}
+
+	start := time.Now()
+	c, err := dit.Load(input)
+	if err != nil {
+		return err
+	}
+	slog.Info("Model loaded", "path", input, "duration", time.Since(start))
+
+	if format == "binary" {
+		err = c.SaveBinary(output)
+	} else {
+		err = c.Save(output)
+	}
+	if err != nil {
+		return err
+	}
+
+	if in, err := os.Stat(input); err == nil {
+		if out, err := os.Stat(output); err == nil {
+			slog.Info("Model converted", "path", output, "format", format,
+				"size", fmt.Sprintf("%.1fMB", float64(out.Size())/1024/1024),
+				"ratio", fmt.Sprintf("%.2f", float64(out.Size())/float64(in.Size())))
+		}
+	}
+	return nil
+}
+
+func modelInfo(w io.Writer, path string, asJSON bool) error {
+	c, err := dit.Load(path)
+	if err != nil {
+		return err
+	}
+	meta := c.Meta()
+
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(meta)
+	}
+
+	format := "json"
+	if data, err := readHeader(path, 4); err == nil && classifier.IsBinaryModel(data) {
+		format = "binary"
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	row := func(k, v string) { _, _ = fmt.Fprintf(tw, "%s\t%s\n", k, v) }
+	row("Path:", path)
+	row("Format:", format)
+	row("Schema version:", strconv.Itoa(meta.SchemaVersion))
+	row("Feature version:", strconv.Itoa(meta.FeatureVersion))
+	row("dit version:", orUnknown(meta.DitVersion))
+	if meta.CreatedAt.IsZero() {
+		row("Created:", "unknown")
+	} else {
+		row("Created:", meta.CreatedAt.Format(time.RFC3339))
+	}
+	row("Data hash:", orUnknown(meta.DataHash))
+	row("Form pipelines:", strings.Join(meta.FormPipelines, ", "))
+	row("Page pipelines:", strings.Join(meta.PagePipelines, ", "))
+	row(fmt.Sprintf("Form classes (%d):", len(meta.FormClasses)), strings.Join(meta.FormClasses, ", "))
+	row(fmt.Sprintf("Field classes (%d):", len(meta.FieldClasses)), strings.Join(meta.FieldClasses, ", "))
+	row(fmt.Sprintf("Page classes (%d):", len(meta.PageClasses)), strings.Join(meta.PageClasses, ", "))
+	if len(meta.Eval) == 0 {
+		row("Eval scores:", "none")
+	}
+	for _, name := range slices.Sorted(maps.Keys(meta.Eval)) {
+		row("  "+name+":", fmt.Sprintf("%.1f%%", meta.Eval[name]*100))
+	}
+	return tw.Flush()
+}
+
+func readHeader(path string, n int) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	buf := make([]byte, n)
+	_, err = io.ReadFull(f, buf)
+	return buf, err
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
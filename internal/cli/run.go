@@ -8,11 +8,14 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"runtime"
 	"strings"
 	"time"
 
 	"github.com/chromedp/chromedp"
 	"github.com/happyhackingspace/dit"
+	"github.com/happyhackingspace/dit/captcha"
+	"github.com/happyhackingspace/dit/protection"
 	"github.com/spf13/cobra"
 )
 
@@ -20,12 +23,21 @@ func (c *CLI) newRunCommand() *cobra.Command {
 	var modelPath string
 	var threshold float64
 	var proba bool
+	var explain bool
+	var minConfidence float64
 	var render bool
 	var renderTimeout int
+	var pageURL string
+	var batchFile string
+	var jsonlFile string
+	var recursive bool
+	var workers int
+	var captchaRules string
+	var protectionRules string
 
 	cmd := &cobra.Command{
-		Use:   "run [url-or-file]",
-		Short: "Classify page type and forms in a URL, HTML file, or stdin",
+		Use:   "run [url-file-or-dir]",
+		Short: "Classify page type and forms in a URL, HTML file, directory, or stdin",
 		Args:  cobra.MaximumNArgs(1),
 		Example: `  # Classify a URL directly
   dit run https://github.com/login
@@ -48,31 +60,85 @@ func (c *CLI) newRunCommand() *cobra.Command {
   # Use custom probability threshold
   dit run https://github.com/login --proba --threshold 0.1
 
+  # Say "unknown" rather than guess when the top type is below 60%
+  dit run https://github.com/login --min-confidence 0.6
+
+  # Show which features drove the page, form and field predictions
+  dit run https://example.com/missing --explain
+
   # Use custom model file
   dit run login.html --model custom.json
 
+  # Classify a saved page, telling the model where it came from
+  dit run login.html --url https://github.com/login
+
   # Render JavaScript-heavy pages
   dit run https://github.com/login --render
 
+  # Classify every URL or file listed in targets.txt (one JSON result per line)
+  dit run --batch targets.txt
+
+  # Classify all HTML files under a directory
+  dit run pages/ --recursive
+
+  # Classify JSONL records of {"url": ..., "html": ...}
+  dit run --jsonl crawl.jsonl --workers 8
+
+  # Add or override captcha/bot-protection and WAF vendor signatures
+  dit run https://example.com/login --captcha-rules my-rules.yaml
+  dit run https://example.com/login --protection-rules my-vendors.yaml
+
   # Silent mode (no banner)
   dit run https://github.com/login -s
 
   # Verbose mode with debug output
   dit run https://github.com/login -v`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := useRules(captchaRules, protectionRules); err != nil {
+				return err
+			}
+
 			var htmlContent string
 			var target string
+			var resp *dit.Response
 			var err error
 			fetchOpts := fetchOptions{
 				render:  render,
 				timeout: time.Duration(renderTimeout) * time.Second,
 			}
 
+			var produce func(chan<- batchJob) error
+			switch {
+			case batchFile != "" && jsonlFile != "":
+				return fmt.Errorf("--batch and --jsonl are mutually exclusive")
+			case batchFile != "":
+				produce = targetListProducer(batchFile)
+			case jsonlFile != "":
+				produce = jsonlProducer(jsonlFile)
+			case len(args) == 1 && isDir(args[0]):
+				produce = dirProducer(args[0], recursive)
+			}
+			if produce != nil {
+				if fetchOpts.render && renderTimeout <= 0 {
+					return fmt.Errorf("--timeout must be a positive integer")
+				}
+				cl, err := loadModel(modelPath)
+				if err != nil {
+					return err
+				}
+				return runBatch(cl, produce, os.Stdout, batchOptions{
+					workers:  workers,
+					proba:    proba,
+					classify: dit.Options{Threshold: threshold, MinConfidence: minConfidence, Explain: explain},
+					fetch:    fetchOpts,
+				})
+			}
+
 			if len(args) == 0 {
 				if isStdinTerminal() {
 					return cmd.Help()
 				}
-				htmlContent, target, err = readFromStdin(fetchOpts)
+				htmlContent, target, resp, err = readFromStdin(fetchOpts)
 				if err != nil {
 					return err
 				}
@@ -82,13 +148,17 @@ func (c *CLI) newRunCommand() *cobra.Command {
 					return fmt.Errorf("--timeout must be a positive integer")
 				}
 				slog.Debug("Fetching HTML", "target", target, "render", fetchOpts.render)
-				htmlContent, err = fetchHTML(target, fetchOpts)
+				htmlContent, resp, err = fetchHTML(target, fetchOpts)
 				if err != nil {
 					return err
 				}
 			}
 			slog.Debug("HTML fetched", "target", target, "bytes", len(htmlContent))
 
+			if pageURL == "" && isURL(target) {
+				pageURL = target
+			}
+
 			start := time.Now()
 			cl, err := loadModel(modelPath)
 			if err != nil {
@@ -97,45 +167,23 @@ func (c *CLI) newRunCommand() *cobra.Command {
 			slog.Debug("Model loaded", "duration", time.Since(start))
 
 			start = time.Now()
-			if proba {
-				pageResult, pageErr := cl.ExtractPageTypeProba(htmlContent, threshold)
-				if pageErr == nil {
-					slog.Debug("Page+form classification completed", "duration", time.Since(start))
-					output, _ := json.MarshalIndent(pageResult, "", "  ")
-					fmt.Println(string(output))
-				} else {
-					results, err := cl.ExtractFormsProba(htmlContent, threshold)
-					if err != nil {
-						return err
-					}
-					slog.Debug("Form classification completed", "forms", len(results), "duration", time.Since(start))
-					if len(results) == 0 {
-						fmt.Println("No forms found.")
-						return nil
-					}
-					output, _ := json.MarshalIndent(results, "", "  ")
-					fmt.Println(string(output))
-				}
-			} else {
-				pageResult, pageErr := cl.ExtractPageType(htmlContent)
-				if pageErr == nil {
-					slog.Debug("Page+form classification completed", "duration", time.Since(start))
-					output, _ := json.MarshalIndent(pageResult, "", "  ")
-					fmt.Println(string(output))
-				} else {
-					results, err := cl.ExtractForms(htmlContent)
-					if err != nil {
-						return err
-					}
-					slog.Debug("Form classification completed", "forms", len(results), "duration", time.Since(start))
-					if len(results) == 0 {
-						fmt.Println("No forms found.")
-						return nil
-					}
-					output, _ := json.MarshalIndent(results, "", "  ")
-					fmt.Println(string(output))
-				}
+			result, err := classifyHTML(cl, htmlContent, &dit.Options{
+				URL:           pageURL,
+				Threshold:     threshold,
+				Response:      resp,
+				MinConfidence: minConfidence,
+				Explain:       explain,
+			}, proba)
+			if err != nil {
+				return err
 			}
+			slog.Debug("Classification completed", "duration", time.Since(start))
+			if isEmptyFormList(result) {
+				fmt.Println("No forms found.")
+				return nil
+			}
+			output, _ := json.MarshalIndent(result, "", "  ")
+			fmt.Println(string(output))
 			return nil
 		},
 	}
@@ -143,11 +191,31 @@ func (c *CLI) newRunCommand() *cobra.Command {
 	cmd.Flags().StringVar(&modelPath, "model", "", "Path to model file (default: auto-detect or download)")
 	cmd.Flags().Float64Var(&threshold, "threshold", 0.05, "Minimum probability threshold")
 	cmd.Flags().BoolVar(&proba, "proba", false, "Show probabilities")
+	cmd.Flags().Float64Var(&minConfidence, "min-confidence", 0, "Report page and form types less probable than this as \"unknown\"")
+	cmd.Flags().BoolVar(&explain, "explain", false, "Add the top feature contributions behind each page, form and field prediction")
 	cmd.Flags().BoolVar(&render, "render", false, "Render JavaScript-driven pages in a headless browser")
-	cmd.Flags().IntVar(&renderTimeout, "timeout", 30, "Render browser timeout in seconds")
+	cmd.Flags().IntVar(&renderTimeout, "timeout", 30, "Fetch and render browser timeout in seconds")
+	cmd.Flags().StringVar(&pageURL, "url", "", "Page URL used as a classification feature (default: the target, if it is a URL)")
+	cmd.Flags().StringVar(&batchFile, "batch", "", "File with one URL or HTML file path per line (\"-\" for stdin)")
+	cmd.Flags().StringVar(&jsonlFile, "jsonl", "", "JSONL file of {\"url\", \"html\"} records (\"-\" for stdin)")
+	cmd.Flags().BoolVar(&recursive, "recursive", false, "Descend into subdirectories when the target is a directory")
+	cmd.Flags().IntVar(&workers, "workers", runtime.NumCPU(), "Number of parallel workers in batch mode")
+	cmd.Flags().StringVar(&captchaRules, "captcha-rules", "", "YAML or JSON file of extra captcha/bot-protection signatures")
+	cmd.Flags().StringVar(&protectionRules, "protection-rules", "", "YAML or JSON file of extra WAF/CDN vendor signatures")
 	return cmd
 }
 
+// isEmptyFormList reports whether v is a form-only result with no forms.
+func isEmptyFormList(v any) bool {
+	switch r := v.(type) {
+	case []dit.FormResult:
+		return len(r) == 0
+	case []dit.FormResultProba:
+		return len(r) == 0
+	}
+	return false
+}
+
 func isStdinTerminal() bool {
 	fi, err := os.Stdin.Stat()
 	if err != nil {
@@ -156,6 +224,29 @@ func isStdinTerminal() bool {
 	return fi.Mode()&os.ModeCharDevice != 0
 }
 
+// useRules extends the built-in captcha and protection vendor signatures
+// with the rules in the given files, if set. Providers in a file replace
+// built-ins of the same name.
+func useRules(captchaPath, protectionPath string) error {
+	if captchaPath != "" {
+		rules, err := captcha.LoadRules(captchaPath)
+		if err != nil {
+			return err
+		}
+		captcha.SetRules(captcha.DefaultRules().With(rules))
+		slog.Debug("Loaded captcha rules", "path", captchaPath, "providers", len(rules.Providers))
+	}
+	if protectionPath != "" {
+		rules, err := protection.LoadRules(protectionPath)
+		if err != nil {
+			return err
+		}
+		protection.SetRules(protection.DefaultRules().With(rules))
+		slog.Debug("Loaded protection rules", "path", protectionPath, "providers", len(rules.Providers))
+	}
+	return nil
+}
+
 func loadModel(modelPath string) (*dit.Classifier, error) {
 	if modelPath != "" {
 		slog.Debug("Loading custom model", "path", modelPath)
@@ -169,34 +260,42 @@ type fetchOptions struct {
 	timeout time.Duration
 }
 
-func fetchHTML(target string, opts fetchOptions) (string, error) {
+// fetchHTML returns the HTML of a URL or file. For plain HTTP fetches it
+// also returns the response status and headers; otherwise the response
+// is nil.
+func fetchHTML(target string, opts fetchOptions) (string, *dit.Response, error) {
 	if isURL(target) {
 		if opts.render {
-			return fetchHTMLRender(target, opts.timeout)
+			html, err := fetchHTMLRender(target, opts.timeout)
+			return html, nil, err
 		}
-		return fetchHTMLPlain(target)
+		return fetchHTMLPlain(target, opts.timeout)
 	}
 	if opts.render {
 		slog.Debug("Render flag ignored for non-URL target", "target", target)
 	}
 	data, err := os.ReadFile(target)
 	if err != nil {
-		return "", fmt.Errorf("read file: %w", err)
+		return "", nil, fmt.Errorf("read file: %w", err)
 	}
-	return string(data), nil
+	return string(data), nil, nil
 }
 
-func fetchHTMLPlain(target string) (string, error) {
-	resp, err := http.Get(target)
+func fetchHTMLPlain(target string, timeout time.Duration) (string, *dit.Response, error) {
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Get(target)
 	if err != nil {
-		return "", fmt.Errorf("fetch URL: %w", err)
+		return "", nil, fmt.Errorf("fetch URL: %w", err)
 	}
 	defer func() { _ = resp.Body.Close() }()
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("read response: %w", err)
+		return "", nil, fmt.Errorf("read response: %w", err)
 	}
-	return string(body), nil
+	return string(body), &dit.Response{StatusCode: resp.StatusCode, Header: resp.Header}, nil
 }
 
 func fetchHTMLRender(target string, timeout time.Duration) (string, error) {
@@ -235,28 +334,28 @@ func isURL(target string) bool {
 	return strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://")
 }
 
-func readFromStdin(opts fetchOptions) (string, string, error) {
+func readFromStdin(opts fetchOptions) (string, string, *dit.Response, error) {
 	slog.Debug("Reading from stdin")
 	body, err := io.ReadAll(os.Stdin)
 	if err != nil {
-		return "", "", fmt.Errorf("read stdin: %w", err)
+		return "", "", nil, fmt.Errorf("read stdin: %w", err)
 	}
 	content := strings.TrimSpace(string(body))
 	if content == "" {
-		return "", "", fmt.Errorf("stdin is empty")
+		return "", "", nil, fmt.Errorf("stdin is empty")
 	}
 
 	if isURL(content) {
 		slog.Debug("Stdin contains URL", "url", content)
 		if opts.render && opts.timeout <= 0 {
-			return "", "", fmt.Errorf("--timeout must be a positive integer")
+			return "", "", nil, fmt.Errorf("--timeout must be a positive integer")
 		}
-		html, err := fetchHTML(content, opts)
+		html, resp, err := fetchHTML(content, opts)
 		if err != nil {
-			return "", "", err
+			return "", "", nil, err
 		}
-		return html, content, nil
+		return html, content, resp, nil
 	}
 
-	return content, "stdin", nil
+	return content, "stdin", nil, nil
 }
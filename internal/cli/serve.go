@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/happyhackingspace/dit/internal/server"
+	"github.com/spf13/cobra"
+)
+
+// shutdownTimeout bounds how long in-flight requests may take to drain.
+const shutdownTimeout = 15 * time.Second
+
+func (c *CLI) newServeCommand() *cobra.Command {
+	var addr string
+	var modelPath string
+	var maxBody int64
+	var threshold float64
+	var captchaRules string
+	var protectionRules string
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve page and form classification over HTTP",
+		Args:  cobra.NoArgs,
+		Example: `  # Listen on the default address
+  dit serve
+
+  # Custom address and model
+  dit serve --addr 127.0.0.1:9000 --model custom.json
+
+  # Classify a page (raw HTML body, URL as query parameter)
+  curl -s --data-binary @login.html 'http://localhost:8080/v1/page?url=https://github.com/login'
+
+  # Classify forms with probabilities (JSON body)
+  curl -s -H 'Content-Type: application/json' \
+    -d '{"html": "<form>...</form>"}' 'http://localhost:8080/v1/forms/proba?threshold=0.1'`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := useRules(captchaRules, protectionRules); err != nil {
+				return err
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			srv := server.New(server.Config{
+				MaxBodyBytes: maxBody,
+				Threshold:    threshold,
+			})
+			httpServer := &http.Server{
+				Addr:              addr,
+				Handler:           srv,
+				ReadHeaderTimeout: 10 * time.Second,
+			}
+
+			errCh := make(chan error, 2)
+			go func() {
+				slog.Info("Listening", "addr", addr)
+				if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					errCh <- fmt.Errorf("listen: %w", err)
+				}
+			}()
+
+			go func() {
+				start := time.Now()
+				cl, err := loadModel(modelPath)
+				if err != nil {
+					errCh <- err
+					return
+				}
+				srv.SetClassifier(cl)
+				slog.Info("Model loaded, ready", "duration", time.Since(start))
+			}()
+
+			var runErr error
+			select {
+			case <-ctx.Done():
+				slog.Info("Shutting down")
+			case runErr = <-errCh:
+			}
+
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			defer cancel()
+			if err := httpServer.Shutdown(shutdownCtx); err != nil && runErr == nil {
+				runErr = fmt.Errorf("shutdown: %w", err)
+			}
+			return runErr
+		},
+	}
+
+	cmd.Flags().StringVar(&addr, "addr", ":8080", "Address to listen on")
+	cmd.Flags().StringVar(&modelPath, "model", "", "Path to model file (default: auto-detect or download)")
+	cmd.Flags().Int64Var(&maxBody, "max-body-size", server.DefaultMaxBodyBytes, "Maximum request body size in bytes")
+	cmd.Flags().Float64Var(&threshold, "threshold", 0.05, "Default probability threshold for /proba endpoints")
+	cmd.Flags().StringVar(&captchaRules, "captcha-rules", "", "YAML or JSON file of extra captcha/bot-protection signatures")
+	cmd.Flags().StringVar(&protectionRules, "protection-rules", "", "YAML or JSON file of extra WAF/CDN vendor signatures")
+	return cmd
+}
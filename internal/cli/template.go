@@ -0,0 +1,127 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/happyhackingspace/dit"
+	"github.com/happyhackingspace/dit/login"
+	"github.com/spf13/cobra"
+)
+
+func (c *CLI) newTemplateCommand() *cobra.Command {
+	var modelPath string
+	var format string
+	var pageURL string
+	var render bool
+	var renderTimeout int
+	var anyForm bool
+
+	cmd := &cobra.Command{
+		Use:   "template <url-or-file>",
+		Short: "Generate a login request template from a page's login form",
+		Args:  cobra.ExactArgs(1),
+		Example: `  # JSON template: method, action, credential parameters, hidden fields
+  dit template https://github.com/login
+
+  # Raw HTTP request with {{username}} and {{password}} placeholders
+  dit template https://github.com/login --format raw
+
+  # Ready-to-run commands
+  dit template https://github.com/login --format curl
+  dit template https://github.com/login --format ffuf
+  dit template https://github.com/login --format hydra
+
+  # Use a password form of another type when no login form is found
+  dit template https://example.com/account --any-form
+
+  # A saved page needs its URL to resolve the form action
+  dit template login.html --url https://github.com/login --format raw`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f := login.Format(format)
+			if !slices.Contains(login.Formats, f) {
+				return fmt.Errorf("unknown format %q (want one of %s)", format, formatList())
+			}
+			target := args[0]
+			if render && isURL(target) && renderTimeout <= 0 {
+				return fmt.Errorf("--timeout must be a positive integer")
+			}
+			htmlContent, _, err := fetchHTML(target, fetchOptions{
+				render:  render,
+				timeout: time.Duration(renderTimeout) * time.Second,
+			})
+			if err != nil {
+				return err
+			}
+			if pageURL == "" && isURL(target) {
+				pageURL = target
+			}
+
+			cl, err := loadModel(modelPath)
+			if err != nil {
+				return err
+			}
+			forms, err := cl.ExtractFormsContext(context.Background(), strings.NewReader(htmlContent), &dit.Options{
+				URL:          pageURL,
+				MaxInputSize: -1,
+			})
+			if err != nil {
+				return err
+			}
+			tmpl, err := findLogin(forms, pageURL, anyForm)
+			if err != nil {
+				return err
+			}
+			slog.Debug("Login form found", "action", tmpl.URL, "username", tmpl.UsernameParam, "password", tmpl.PasswordParam)
+
+			out, err := tmpl.Render(f)
+			if err != nil {
+				return err
+			}
+			if f != login.FormatJSON {
+				for _, r := range tmpl.Refresh {
+					slog.Info("Refresh before each attempt", "param", r.Name, "kind", r.Kind, "selector", r.Selector, "page", tmpl.Page)
+				}
+			}
+			fmt.Println(out)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&modelPath, "model", "", "Path to model file (default: auto-detect or download)")
+	cmd.Flags().StringVar(&format, "format", string(login.FormatJSON), "Output format: "+formatList())
+	cmd.Flags().StringVar(&pageURL, "url", "", "Page URL used to resolve the form action (default: the target, if it is a URL)")
+	cmd.Flags().BoolVar(&render, "render", false, "Render JavaScript-driven pages in a headless browser")
+	cmd.Flags().IntVar(&renderTimeout, "timeout", 30, "Fetch and render browser timeout in seconds")
+	cmd.Flags().BoolVar(&anyForm, "any-form", false, "Fall back to any form with a password field when none is classified as login")
+	return cmd
+}
+
+// findLogin returns the template of the login form. Without anyForm, a
+// page whose only password forms are of another type (registration,
+// password change) is an error rather than a wrong template.
+func findLogin(forms []dit.FormResult, pageURL string, anyForm bool) (*login.Template, error) {
+	tmpl, err := login.Find(forms, pageURL)
+	if err != nil {
+		return nil, err
+	}
+	if tmpl.FormType != "login" {
+		if !anyForm {
+			return nil, fmt.Errorf("%w: the only password form is classified as %q (use --any-form to use it anyway)", login.ErrNoLoginForm, tmpl.FormType)
+		}
+		slog.Warn("No form classified as login; using a form with a password field", "type", tmpl.FormType, "action", tmpl.URL)
+	}
+	return tmpl, nil
+}
+
+func formatList() string {
+	names := make([]string, len(login.Formats))
+	for i, f := range login.Formats {
+		names[i] = string(f)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/happyhackingspace/dit"
+	"github.com/happyhackingspace/dit/login"
+)
+
+func TestFindLogin(t *testing.T) {
+	form := func(typ string) dit.FormResult {
+		return dit.FormResult{
+			Type: typ,
+			FieldDetails: []dit.FieldResult{
+				{Name: "email", InputType: "email", Type: "email"},
+				{Name: "pass", InputType: "password", Type: "password"},
+			},
+			FormEndpoint: dit.FormEndpoint{Action: "https://example.com/" + typ, Method: "POST"},
+		}
+	}
+
+	tmpl, err := findLogin([]dit.FormResult{form("registration"), form("login")}, "https://example.com/", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tmpl.URL != "https://example.com/login" || tmpl.FormType != "login" {
+		t.Errorf("template = %s %s, want the login form", tmpl.FormType, tmpl.URL)
+	}
+
+	registration := []dit.FormResult{form("registration")}
+	if _, err := findLogin(registration, "https://example.com/", false); !errors.Is(err, login.ErrNoLoginForm) {
+		t.Errorf("registration form only: err = %v, want ErrNoLoginForm", err)
+	}
+	if tmpl, err := findLogin(registration, "https://example.com/", true); err != nil || tmpl.FormType != "registration" {
+		t.Errorf("registration form with anyForm = %+v, %v", tmpl, err)
+	}
+}
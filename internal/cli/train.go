@@ -10,18 +10,35 @@ import (
 
 func (c *CLI) newTrainCommand() *cobra.Command {
 	var dataFolder string
+	var evalFolds int
+	var calibrationFolds int
+	var multiLabel bool
 
 	cmd := &cobra.Command{
 		Use:   "train <modelfile>",
 		Short: "Train a model on annotated HTML forms",
 		Args:  cobra.ExactArgs(1),
 		Example: `  dit train model.json --data-folder data
-  dit train model.json -v`,
+  dit train model.json -v
+
+  # Record 10-fold cross-validation scores in the model metadata
+  dit train model.json --eval-folds 10
+
+  # Skip probability calibration (faster)
+  dit train model.json --calibration-folds -1
+
+  # Also predict every type of a page (page_types in the page index)
+  dit train model.json --multi-label`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			modelPath := args[0]
 			slog.Info("Training classifier", "data-folder", dataFolder, "output", modelPath)
 			start := time.Now()
-			cl, err := dit.Train(dataFolder, &dit.TrainConfig{Verbose: c.verbose})
+			cl, err := dit.Train(dataFolder, &dit.TrainConfig{
+				Verbose:          c.verbose,
+				EvalFolds:        evalFolds,
+				CalibrationFolds: calibrationFolds,
+				MultiLabel:       multiLabel,
+			})
 			if err != nil {
 				return err
 			}
@@ -35,5 +52,8 @@ func (c *CLI) newTrainCommand() *cobra.Command {
 	}
 
 	cmd.Flags().StringVar(&dataFolder, "data-folder", "data", "Path to annotation data folder")
+	cmd.Flags().IntVar(&evalFolds, "eval-folds", 0, "Run cross-validation with this many folds and store the scores in the model")
+	cmd.Flags().IntVar(&calibrationFolds, "calibration-folds", dit.DefaultCalibrationFolds, "Folds of held-out predictions used to calibrate probabilities (negative disables)")
+	cmd.Flags().BoolVar(&multiLabel, "multi-label", false, "Also train a multi-label page type model (uses page_types from the page index)")
 	return cmd
 }
@@ -3,9 +3,7 @@ package cli
 import (
 	"context"
 	"fmt"
-	"io"
 	"log/slog"
-	"net/http"
 	"os"
 	"path/filepath"
 
@@ -30,7 +28,9 @@ func (c *CLI) selfUpdate() error {
 		v = "0.0.0"
 	}
 
-	updater, err := selfupdate.NewUpdater(selfupdate.Config{})
+	updater, err := selfupdate.NewUpdater(selfupdate.Config{
+		Validator: &selfupdate.ChecksumValidator{UniqueFilename: "checksums.txt"},
+	})
 	if err != nil {
 		return err
 	}
@@ -61,22 +61,15 @@ func (c *CLI) selfUpdate() error {
 
 	fmt.Printf("Updated to %s\n", latest.Version())
 
-	// Also refresh cached model
+	// Also refresh cached model. The download is verified and atomic, so a
+	// failure leaves the previous model in place.
 	modelDest := filepath.Join(dit.ModelDir(), "model.json")
 	if _, err := os.Stat(modelDest); err == nil {
 		slog.Info("Updating cached model")
-		modelResp, err := http.Get(dit.ModelURL)
-		if err == nil {
-			defer func() { _ = modelResp.Body.Close() }()
-			if modelResp.StatusCode == http.StatusOK {
-				if err := os.MkdirAll(filepath.Dir(modelDest), 0755); err == nil {
-					if f, err := os.Create(modelDest); err == nil {
-						_, _ = io.Copy(f, modelResp.Body)
-						_ = f.Close()
-						slog.Info("Model updated")
-					}
-				}
-			}
+		if err := dit.Download(modelDest); err != nil {
+			slog.Warn("Model update failed, keeping the cached model", "error", err)
+		} else {
+			slog.Info("Model updated")
 		}
 	}
 
@@ -0,0 +1,377 @@
+// Package download fetches release files with SHA-256 verification,
+// atomic writes and resumption of interrupted transfers.
+package download
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DefaultBaseURL is where model and data files are published.
+const DefaultBaseURL = "https://huggingface.co/datasets/happyhackingspace/dit/resolve/main"
+
+// BaseURLEnv names the environment variable that overrides DefaultBaseURL,
+// e.g. to point at an internal mirror.
+const BaseURLEnv = "DIT_MODEL_URL"
+
+// SkipVerifyEnv names the environment variable that, set to "1" or "true",
+// allows downloads of files missing from the published manifest.
+const SkipVerifyEnv = "DIT_SKIP_VERIFY"
+
+// ManifestName is the checksum manifest published next to the files, in
+// the format written by sha256sum.
+const ManifestName = "SHA256SUMS"
+
+// partSuffix is appended to the destination while a download is in progress.
+const partSuffix = ".part"
+
+// validatorSuffix is appended to the partial file to name the file holding
+// the ETag or Last-Modified value the partial file was downloaded with.
+const validatorSuffix = ".validator"
+
+// ErrChecksum is returned when a downloaded file does not match the manifest.
+var ErrChecksum = errors.New("checksum mismatch")
+
+// ErrNoChecksum is returned when the published manifest has no entry for a
+// file and verification was not explicitly skipped.
+var ErrNoChecksum = errors.New("no published checksum")
+
+// BaseURL returns the value of DIT_MODEL_URL, or DefaultBaseURL if unset.
+func BaseURL() string {
+	if u := strings.TrimSpace(os.Getenv(BaseURLEnv)); u != "" {
+		return strings.TrimRight(u, "/")
+	}
+	return DefaultBaseURL
+}
+
+// Manifest maps file names to their hex-encoded SHA-256 digests.
+type Manifest map[string]string
+
+// ParseManifest reads a manifest in sha256sum format ("<hex>  <name>").
+func ParseManifest(r io.Reader) (Manifest, error) {
+	m := make(Manifest)
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		sum, name, ok := strings.Cut(line, " ")
+		if !ok || len(sum) != sha256.Size*2 {
+			return nil, fmt.Errorf("parse manifest: malformed line %q", line)
+		}
+		name = strings.TrimPrefix(strings.TrimSpace(name), "*")
+		m[name] = strings.ToLower(sum)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("parse manifest: %w", err)
+	}
+	return m, nil
+}
+
+// WriteManifest writes the SHA-256 digests of files to w in sha256sum
+// format, naming each entry by its base name.
+func WriteManifest(w io.Writer, files ...string) error {
+	m := make(Manifest)
+	if err := m.Add(files...); err != nil {
+		return err
+	}
+	return m.Write(w)
+}
+
+// Add records the SHA-256 digests of files in m, naming each entry by its
+// base name and replacing any previous entry of that name.
+func (m Manifest) Add(files ...string) error {
+	for _, path := range files {
+		sum, err := FileSHA256(path)
+		if err != nil {
+			return err
+		}
+		m[filepath.Base(path)] = sum
+	}
+	return nil
+}
+
+// Write writes m to w in sha256sum format, sorted by name.
+func (m Manifest) Write(w io.Writer) error {
+	for _, name := range slices.Sorted(maps.Keys(m)) {
+		if _, err := fmt.Fprintf(w, "%s  %s\n", m[name], name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FileSHA256 returns the hex-encoded SHA-256 digest of a file.
+func FileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Client downloads files published under BaseURL.
+type Client struct {
+	HTTP    *http.Client // defaults to http.DefaultClient
+	BaseURL string       // defaults to BaseURL()
+	// SkipVerify allows files missing from the published manifest, which
+	// are otherwise rejected with ErrNoChecksum. Published checksums are
+	// always verified.
+	SkipVerify bool
+}
+
+// New returns a Client using the configured base URL, skipping
+// verification only if DIT_SKIP_VERIFY is set.
+func New() *Client {
+	skip, _ := strconv.ParseBool(strings.TrimSpace(os.Getenv(SkipVerifyEnv)))
+	return &Client{BaseURL: BaseURL(), SkipVerify: skip}
+}
+
+// URL returns the download URL of a published file.
+func (c *Client) URL(name string) string {
+	base := c.BaseURL
+	if base == "" {
+		base = BaseURL()
+	}
+	return strings.TrimRight(base, "/") + "/" + name
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTP != nil {
+		return c.HTTP
+	}
+	return http.DefaultClient
+}
+
+// Manifest fetches the published checksum manifest. It returns nil and no
+// error when the server has no manifest.
+func (c *Client) Manifest(ctx context.Context) (Manifest, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.URL(ManifestName), nil)
+	if err != nil {
+		return nil, fmt.Errorf("fetch manifest: %w", err)
+	}
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch manifest: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return ParseManifest(io.LimitReader(resp.Body, 1<<20))
+	case http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("fetch manifest: HTTP %d", resp.StatusCode)
+	}
+}
+
+// Download fetches the published file name to dest and returns its size.
+// The file is verified against the manifest, and fails with ErrNoChecksum
+// if the manifest has no entry for it unless c.SkipVerify is set.
+//
+// Until a manifest is published, as for releases that predate it, files
+// are downloaded unverified with a warning; once one exists, every file
+// must be listed in it. The file is
+// is written to dest+".part" and renamed into place only once complete, so
+// dest is never left truncated. An existing .part file is resumed with an
+// HTTP Range request, conditional on the file being unchanged upstream.
+func (c *Client) Download(ctx context.Context, name, dest string) (int64, error) {
+	manifest, err := c.Manifest(ctx)
+	if err != nil {
+		return 0, err
+	}
+	want, ok := manifest[name]
+	switch {
+	case ok:
+	case manifest == nil:
+		slog.Warn("No checksum manifest is published; downloading WITHOUT integrity verification", "file", name, "manifest", c.URL(ManifestName))
+	case !c.SkipVerify:
+		return 0, fmt.Errorf("download %s: %w (set %s=1 to download anyway)", name, ErrNoChecksum, SkipVerifyEnv)
+	default:
+		slog.Warn("No published checksum, skipping verification", "file", name)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return 0, fmt.Errorf("create directory: %w", err)
+	}
+
+	part := dest + partSuffix
+	size, sum, err := c.fetch(ctx, c.URL(name), part)
+	if err != nil {
+		if fi, statErr := os.Stat(part); statErr == nil && fi.Size() == 0 {
+			_ = os.Remove(part)
+		}
+		return 0, err
+	}
+	if ok && sum != want {
+		_ = os.Remove(part)
+		_ = os.Remove(part + validatorSuffix)
+		return 0, fmt.Errorf("download %s: %w: got %s, want %s", name, ErrChecksum, sum, want)
+	}
+	if err := os.Rename(part, dest); err != nil {
+		return 0, fmt.Errorf("download %s: %w", name, err)
+	}
+	_ = os.Remove(part + validatorSuffix)
+	return size, nil
+}
+
+// fetch downloads url into part, resuming from its current length, and
+// returns the final size and SHA-256 digest of part. The ETag or
+// Last-Modified value of the response that started part is kept next to
+// it and sent as If-Range when resuming, so that a file changed upstream
+// is downloaded afresh rather than spliced onto the old prefix. A partial
+// file without such a validator is not resumed.
+func (c *Client) fetch(ctx context.Context, url, part string) (int64, string, error) {
+	f, err := os.OpenFile(part, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return 0, "", fmt.Errorf("create %s: %w", part, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	h := sha256.New()
+	offset, err := io.Copy(h, f)
+	if err != nil {
+		return 0, "", fmt.Errorf("read %s: %w", part, err)
+	}
+	validatorPath := part + validatorSuffix
+	var validator string
+	if offset > 0 {
+		data, _ := os.ReadFile(validatorPath)
+		if validator = strings.TrimSpace(string(data)); validator == "" {
+			slog.Debug("Partial download has no validator, restarting", "url", url)
+			if err := truncate(f); err != nil {
+				return 0, "", err
+			}
+			h.Reset()
+			offset = 0
+		}
+	}
+
+	resp, err := c.get(ctx, url, offset, validator)
+	if err != nil {
+		return 0, "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	resumed := offset > 0 && resp.StatusCode == http.StatusPartialContent && rangeStart(resp) == offset
+	if offset > 0 && !resumed {
+		// The file changed upstream, the server ignored the range, or the
+		// partial file is complete: start over.
+		slog.Debug("Cannot resume download, restarting", "url", url, "status", resp.StatusCode)
+		if resp.StatusCode != http.StatusOK {
+			_ = resp.Body.Close()
+			if resp, err = c.get(ctx, url, 0, ""); err != nil {
+				return 0, "", err
+			}
+		}
+		if err := truncate(f); err != nil {
+			return 0, "", err
+		}
+		h.Reset()
+		offset = 0
+	}
+	if resumed {
+		slog.Info("Resuming download", "url", url, "offset", offset)
+	} else if resp.StatusCode != http.StatusOK {
+		return 0, "", fmt.Errorf("download %s: HTTP %d", url, resp.StatusCode)
+	} else if err := saveValidator(validatorPath, resp); err != nil {
+		return 0, "", err
+	}
+
+	n, err := io.Copy(io.MultiWriter(f, h), resp.Body)
+	if err != nil {
+		// Keep the partial file so the next attempt can resume.
+		return 0, "", fmt.Errorf("download %s: %w", url, err)
+	}
+	if err := f.Sync(); err != nil {
+		return 0, "", fmt.Errorf("sync %s: %w", part, err)
+	}
+	return offset + n, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (c *Client) get(ctx context.Context, url string, offset int64, validator string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("download %s: %w", url, err)
+	}
+	if offset > 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+		req.Header.Set("If-Range", validator)
+	}
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("download %s: %w", url, err)
+	}
+	return resp, nil
+}
+
+// saveValidator records the value to send as If-Range when resuming the
+// download started by resp: its strong ETag, or else its Last-Modified
+// date. Without either, the partial file cannot be resumed safely and any
+// previous validator is removed.
+func saveValidator(path string, resp *http.Response) error {
+	validator := resp.Header.Get("ETag")
+	if validator == "" || strings.HasPrefix(validator, "W/") {
+		validator = resp.Header.Get("Last-Modified")
+	}
+	if validator == "" {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove %s: %w", path, err)
+		}
+		return nil
+	}
+	if err := os.WriteFile(path, []byte(validator+"\n"), 0644); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return nil
+}
+
+// truncate empties the partial file and rewinds it.
+func truncate(f *os.File) error {
+	if err := f.Truncate(0); err != nil {
+		return fmt.Errorf("truncate %s: %w", f.Name(), err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("seek %s: %w", f.Name(), err)
+	}
+	return nil
+}
+
+// rangeStart returns the first byte position of a Content-Range header,
+// or -1 if it is missing or malformed.
+func rangeStart(resp *http.Response) int64 {
+	cr := resp.Header.Get("Content-Range")
+	spec, ok := strings.CutPrefix(cr, "bytes ")
+	if !ok {
+		return -1
+	}
+	start, _, ok := strings.Cut(spec, "-")
+	if !ok {
+		return -1
+	}
+	n, err := strconv.ParseInt(start, 10, 64)
+	if err != nil {
+		return -1
+	}
+	return n
+}
@@ -0,0 +1,297 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testServer serves files, with their SHA-256 as ETag, and an optional
+// manifest, recording Range headers.
+type testServer struct {
+	*httptest.Server
+	mu          sync.Mutex
+	files       map[string][]byte
+	manifest    string
+	ignoreRange bool
+	ranges      []string
+}
+
+// etag returns the ETag the test server sends for data.
+func etag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+func newTestServer(t *testing.T, files map[string][]byte) *testServer {
+	t.Helper()
+	ts := &testServer{files: files}
+	var b strings.Builder
+	for name, data := range files {
+		sum := sha256.Sum256(data)
+		fmt.Fprintf(&b, "%s  %s\n", hex.EncodeToString(sum[:]), name)
+	}
+	ts.manifest = b.String()
+	ts.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/")
+		ts.mu.Lock()
+		ts.ranges = append(ts.ranges, r.Header.Get("Range"))
+		manifest, ignoreRange := ts.manifest, ts.ignoreRange
+		ts.mu.Unlock()
+
+		if name == ManifestName {
+			if manifest == "" {
+				http.NotFound(w, r)
+				return
+			}
+			_, _ = w.Write([]byte(manifest))
+			return
+		}
+		data, ok := ts.files[name]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if ignoreRange {
+			r.Header.Del("Range")
+		}
+		w.Header().Set("ETag", etag(data))
+		http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(data))
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestDownloadVerified(t *testing.T) {
+	data := bytes.Repeat([]byte("model"), 1000)
+	ts := newTestServer(t, map[string][]byte{"model.json": data})
+	dest := filepath.Join(t.TempDir(), "sub", "model.json")
+
+	c := &Client{BaseURL: ts.URL}
+	n, err := c.Download(context.Background(), "model.json", dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(data)) {
+		t.Errorf("size = %d, want %d", n, len(data))
+	}
+	got, _ := os.ReadFile(dest)
+	if !bytes.Equal(got, data) {
+		t.Error("downloaded content differs")
+	}
+	if _, err := os.Stat(dest + partSuffix); !os.IsNotExist(err) {
+		t.Error("partial file left behind")
+	}
+}
+
+func TestDownloadChecksumMismatch(t *testing.T) {
+	ts := newTestServer(t, map[string][]byte{"model.json": []byte("tampered")})
+	ts.manifest = strings.Repeat("0", 64) + "  model.json\n"
+	dest := filepath.Join(t.TempDir(), "model.json")
+	if err := os.WriteFile(dest, []byte("old model"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Client{BaseURL: ts.URL}
+	if _, err := c.Download(context.Background(), "model.json", dest); !errors.Is(err, ErrChecksum) {
+		t.Fatalf("error = %v, want ErrChecksum", err)
+	}
+	if got, _ := os.ReadFile(dest); string(got) != "old model" {
+		t.Errorf("existing file was overwritten: %q", got)
+	}
+	if _, err := os.Stat(dest + partSuffix); !os.IsNotExist(err) {
+		t.Error("corrupt partial file kept")
+	}
+}
+
+func TestDownloadResume(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 500)
+	ts := newTestServer(t, map[string][]byte{"model.json": data})
+	dest := filepath.Join(t.TempDir(), "model.json")
+	if err := os.WriteFile(dest+partSuffix, data[:1234], 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dest+partSuffix+validatorSuffix, []byte(etag(data)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Client{BaseURL: ts.URL}
+	if _, err := c.Download(context.Background(), "model.json", dest); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(dest); !bytes.Equal(got, data) {
+		t.Error("resumed content differs")
+	}
+	if !slices.Contains(ts.ranges, "bytes=1234-") {
+		t.Errorf("no resume request sent; ranges = %q", ts.ranges)
+	}
+	if _, err := os.Stat(dest + partSuffix + validatorSuffix); !os.IsNotExist(err) {
+		t.Error("validator file left behind")
+	}
+}
+
+func TestDownloadRestartsWhenChangedUpstream(t *testing.T) {
+	old := bytes.Repeat([]byte("old model "), 500)
+	data := bytes.Repeat([]byte("new model "), 500)
+	ts := newTestServer(t, map[string][]byte{"model.json": data})
+	dest := filepath.Join(t.TempDir(), "model.json")
+	if err := os.WriteFile(dest+partSuffix, old[:1234], 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dest+partSuffix+validatorSuffix, []byte(etag(old)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Client{BaseURL: ts.URL}
+	if _, err := c.Download(context.Background(), "model.json", dest); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(dest); !bytes.Equal(got, data) {
+		t.Error("new content was spliced onto the old partial file")
+	}
+
+	// A partial file without a validator is not resumed at all.
+	ts.mu.Lock()
+	ts.ranges = nil
+	ts.mu.Unlock()
+	if err := os.WriteFile(dest+partSuffix, data[:1234], 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Download(context.Background(), "model.json", dest); err != nil {
+		t.Fatal(err)
+	}
+	if slices.ContainsFunc(ts.ranges, func(r string) bool { return r != "" }) {
+		t.Errorf("resumed without a validator; ranges = %q", ts.ranges)
+	}
+}
+
+func TestDownloadRestartsWhenRangeIgnored(t *testing.T) {
+	data := []byte("fresh model contents")
+	ts := newTestServer(t, map[string][]byte{"model.json": data})
+	ts.ignoreRange = true
+	dest := filepath.Join(t.TempDir(), "model.json")
+	if err := os.WriteFile(dest+partSuffix, []byte("stale"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Client{BaseURL: ts.URL}
+	if _, err := c.Download(context.Background(), "model.json", dest); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(dest); !bytes.Equal(got, data) {
+		t.Errorf("content = %q, want %q", got, data)
+	}
+}
+
+func TestDownloadWithoutManifest(t *testing.T) {
+	ts := newTestServer(t, map[string][]byte{"model.json": []byte("{}")})
+	ts.manifest = ""
+	dest := filepath.Join(t.TempDir(), "model.json")
+
+	// Without a published manifest, files download unverified.
+	c := &Client{BaseURL: ts.URL}
+	if _, err := c.Download(context.Background(), "model.json", dest); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Download(context.Background(), "missing.json", dest+".missing"); err == nil {
+		t.Error("expected error for missing file")
+	}
+	if _, err := os.Stat(dest + ".missing" + partSuffix); !os.IsNotExist(err) {
+		t.Error("empty partial file left behind")
+	}
+}
+
+func TestDownloadNotInManifest(t *testing.T) {
+	ts := newTestServer(t, map[string][]byte{"model.json": []byte("{}")})
+	ts.manifest = strings.Repeat("0", 64) + "  other.json\n"
+	dest := filepath.Join(t.TempDir(), "model.json")
+
+	c := &Client{BaseURL: ts.URL}
+	if _, err := c.Download(context.Background(), "model.json", dest); !errors.Is(err, ErrNoChecksum) {
+		t.Fatalf("error = %v, want ErrNoChecksum", err)
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Error("unverified file written")
+	}
+
+	c.SkipVerify = true
+	if _, err := c.Download(context.Background(), "model.json", dest); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBaseURLFromEnv(t *testing.T) {
+	t.Setenv(BaseURLEnv, "https://mirror.internal/dit/")
+	if got := New().URL("model.json"); got != "https://mirror.internal/dit/model.json" {
+		t.Errorf("URL = %q", got)
+	}
+	t.Setenv(BaseURLEnv, "")
+	if got := New().URL("model.json"); got != DefaultBaseURL+"/model.json" {
+		t.Errorf("URL = %q", got)
+	}
+	if New().SkipVerify {
+		t.Error("verification skipped by default")
+	}
+	t.Setenv(SkipVerifyEnv, "1")
+	if !New().SkipVerify {
+		t.Errorf("%s=1 did not skip verification", SkipVerifyEnv)
+	}
+}
+
+func TestManifestRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "model.json")
+	if err := os.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteManifest(&buf, path); err != nil {
+		t.Fatal(err)
+	}
+	m, err := ParseManifest(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["model.json"] != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" {
+		t.Errorf("manifest = %v", m)
+	}
+	if _, err := ParseManifest(strings.NewReader("nothex model.json\n")); err == nil {
+		t.Error("expected error for malformed manifest")
+	}
+}
+
+func TestManifestMerge(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.tar.gz")
+	if err := os.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	model := strings.Repeat("a", 64)
+	m, err := ParseManifest(strings.NewReader(model + "  model.json\n" + strings.Repeat("b", 64) + "  data.tar.gz\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Add(path); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := m.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824  data.tar.gz\n" + model + "  model.json\n"
+	if buf.String() != want {
+		t.Errorf("merged manifest = %q, want %q", buf.String(), want)
+	}
+}
@@ -54,6 +54,20 @@ func GetFieldsToAnnotate(form *goquery.Selection) []*goquery.Selection {
 	return result
 }
 
+// GetFieldsToClassify returns the visible fields classified at inference time.
+// Unlike GetFieldsToAnnotate it keeps unnamed inputs, which script-driven
+// forms often use; unnamed buttons are still skipped.
+func GetFieldsToClassify(form *goquery.Selection) []*goquery.Selection {
+	visible := GetVisibleFields(form)
+	var result []*goquery.Selection
+	for _, f := range visible {
+		if name, _ := f.Attr("name"); name != "" || !isButton(f) {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
 // GetTypeCounts returns counts of different input types in a form.
 func GetTypeCounts(form *goquery.Selection) map[string]int {
 	counts := make(map[string]int)
@@ -210,3 +224,28 @@ func GetInputTitles(form *goquery.Selection) string {
 func GetAllFormText(form *goquery.Selection) string {
 	return form.Text()
 }
+
+// LabelledByText returns the text of the elements referenced by the
+// aria-labelledby attribute of elem, looked up in the document containing
+// form (or in form itself when it is detached).
+func LabelledByText(elem, form *goquery.Selection) string {
+	ids := strings.Fields(elem.AttrOr("aria-labelledby", ""))
+	if len(ids) == 0 {
+		return ""
+	}
+	root := form.Parents().Last()
+	if root.Length() == 0 {
+		root = form
+	}
+	var parts []string
+	for _, id := range ids {
+		root.Find("[id]").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+			if s.AttrOr("id", "") == id {
+				parts = append(parts, strings.TrimSpace(s.Text()))
+				return false
+			}
+			return true
+		})
+	}
+	return strings.Join(parts, " ")
+}
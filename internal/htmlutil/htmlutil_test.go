@@ -3,6 +3,8 @@ package htmlutil
 import (
 	"strings"
 	"testing"
+
+	"github.com/PuerkitoBio/goquery"
 )
 
 const testHTML = `
@@ -191,3 +193,141 @@ func TestGetFormMethodMissing(t *testing.T) {
 		t.Errorf("method = %q, want %q", method, "MISSING")
 	}
 }
+
+func TestCSSPathAndXPath(t *testing.T) {
+	doc, _ := LoadHTMLString(`<html><body>
+<div><form><input name="q"/><input name="q"/></form></div>
+<div id="widget"><input type="email"/></div>
+<p id="dup"></p><p id="dup"><input type="password"/></p>
+</body></html>`)
+
+	tests := []struct {
+		sel       *goquery.Selection
+		wantCSS   string
+		wantXPath string
+	}{
+		{doc.Find("form input").Eq(1), "html > body:nth-of-type(1) > div:nth-of-type(1) > form:nth-of-type(1) > input:nth-of-type(2)", "/html[1]/body[1]/div[1]/form[1]/input[2]"},
+		{doc.Find("#widget input"), "#widget > input:nth-of-type(1)", "/html[1]/body[1]/div[2]/input[1]"},
+		{doc.Find("input[type=password]"), "html > body:nth-of-type(1) > p:nth-of-type(2) > input:nth-of-type(1)", "/html[1]/body[1]/p[2]/input[1]"},
+	}
+	ids := CountIDs(doc.Selection)
+	if ids["dup"] != 2 || ids["widget"] != 1 {
+		t.Errorf("CountIDs = %v", ids)
+	}
+	for _, tt := range tests {
+		css := CSSPath(tt.sel)
+		if css != tt.wantCSS {
+			t.Errorf("CSSPath = %q, want %q", css, tt.wantCSS)
+		}
+		if shared := ids.CSSPath(tt.sel); shared != css {
+			t.Errorf("IDCounts.CSSPath = %q, want %q", shared, css)
+		}
+		if got := doc.Find(css); got.Length() != 1 || got.Get(0) != tt.sel.Get(0) {
+			t.Errorf("selector %q does not resolve to the original element", css)
+		}
+		if got := XPath(tt.sel); got != tt.wantXPath {
+			t.Errorf("XPath = %q, want %q", got, tt.wantXPath)
+		}
+	}
+}
+
+func TestGetVirtualForms(t *testing.T) {
+	doc, _ := LoadHTMLString(`<html><body>
+<header><div class="search"><input type="search" placeholder="Search"/><button>Go</button></div></header>
+<div id="app"><div class="login">
+  <div class="row"><input type="email" placeholder="Email"/></div>
+  <div class="row"><input type="password" placeholder="Password"/><button>Sign in</button></div>
+</div></div>
+<form><input name="q"/></form>
+<input type="hidden" value="x"/>
+</body></html>`)
+
+	forms := GetVirtualForms(doc)
+	if len(forms) != 2 {
+		t.Fatalf("expected 2 virtual forms, got %d", len(forms))
+	}
+	if cls, _ := forms[0].Attr("class"); cls != "search" {
+		t.Errorf("first virtual form class = %q, want search", cls)
+	}
+	if cls, _ := forms[1].Attr("class"); cls != "login" {
+		t.Errorf("second virtual form class = %q, want login", cls)
+	}
+	for _, f := range forms {
+		if !IsVirtualForm(f) {
+			t.Error("expected IsVirtualForm to be true")
+		}
+	}
+
+	fields := GetFieldsToClassify(forms[1])
+	if len(fields) != 2 {
+		t.Errorf("expected 2 unnamed fields to classify, got %d", len(fields))
+	}
+
+	if all := GetAllForms(doc); len(all) != 3 || IsVirtualForm(all[0]) {
+		t.Errorf("GetAllForms should list the <form> first, then virtual forms; got %d", len(all))
+	}
+}
+
+func TestBaseURL(t *testing.T) {
+	tests := []struct {
+		html, pageURL, ref, want string
+	}{
+		{`<form></form>`, "https://example.com/a/login", "session", "https://example.com/a/session"},
+		{`<head><base href="/app/"></head>`, "https://example.com/a/login", "session", "https://example.com/app/session"},
+		{`<head><base href="https://cdn.example.net/x/"></head>`, "", "session", "https://cdn.example.net/x/session"},
+		{`<form></form>`, "", "/session", "/session"},
+	}
+	for _, tt := range tests {
+		doc, err := LoadHTMLString(tt.html)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ResolveURL(BaseURL(doc, tt.pageURL), tt.ref); got != tt.want {
+			t.Errorf("ResolveURL(%q) with page %q, html %q = %q, want %q", tt.ref, tt.pageURL, tt.html, got, tt.want)
+		}
+	}
+}
+
+func TestCardFieldKind(t *testing.T) {
+	doc, err := LoadHTMLString(`<form>
+<input id="a" autocomplete="billing cc-number">
+<input id="b" name="cardNumber">
+<input id="c" name="payment[card_cvv]">
+<input id="d" name="exp_month">
+<input id="e" name="cc-exp">
+<input id="f" name="card_holder_name">
+<input id="g" name="iban">
+<input id="h" name="card_message">
+<input id="i" type="hidden" name="cvc">
+</form>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"a": CardNumber, "b": CardNumber, "c": CardCVC, "d": CardExpiryMonth,
+		"e": CardExpiry, "f": CardName, "g": CardIBAN, "h": "", "i": "",
+	}
+	for id, kind := range want {
+		if got := CardFieldKind(doc.Find("#" + id)); got != kind {
+			t.Errorf("CardFieldKind(#%s) = %q, want %q", id, got, kind)
+		}
+	}
+}
+
+func TestAddressSection(t *testing.T) {
+	doc, err := LoadHTMLString(`<form>
+<input id="a" autocomplete="shipping street-address">
+<input id="b" name="billing_address_1">
+<fieldset><legend>Delivery address</legend><input id="c" name="city"></fieldset>
+<input id="d" name="address">
+</form>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"a": "shipping", "b": "billing", "c": "shipping", "d": ""}
+	for id, section := range want {
+		if got := AddressSection(doc.Find("#" + id)); got != section {
+			t.Errorf("AddressSection(#%s) = %q, want %q", id, got, section)
+		}
+	}
+}
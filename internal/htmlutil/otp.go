@@ -0,0 +1,63 @@
+package htmlutil
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// otpInputTypes are the input types used for one-time code entry.
+var otpInputTypes = map[string]bool{"text": true, "tel": true, "number": true, "password": true}
+
+// MaxLength returns the maxlength attribute of elem, or 0 if it is missing
+// or not a positive integer.
+func MaxLength(elem *goquery.Selection) int {
+	return intAttr(elem, "maxlength")
+}
+
+// MinLength returns the minlength attribute of elem, or 0 if it is missing
+// or not a positive integer.
+func MinLength(elem *goquery.Selection) int {
+	return intAttr(elem, "minlength")
+}
+
+func intAttr(elem *goquery.Selection, name string) int {
+	v, _ := elem.Attr(name)
+	n, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// IsOneTimeCodeInput reports whether elem asks for a one-time code through
+// autocomplete="one-time-code".
+func IsOneTimeCodeInput(elem *goquery.Selection) bool {
+	ac, _ := elem.Attr("autocomplete")
+	return strings.Contains(strings.ToLower(ac), "one-time-code")
+}
+
+// IsDigitInput reports whether elem is a single-character text-like input,
+// as used by split one-time code widgets (one box per digit).
+func IsDigitInput(elem *goquery.Selection) bool {
+	if goquery.NodeName(elem) != "input" || MaxLength(elem) != 1 {
+		return false
+	}
+	tp, ok := elem.Attr("type")
+	if !ok {
+		tp = "text"
+	}
+	return otpInputTypes[strings.ToLower(tp)]
+}
+
+// CountDigitInputs returns the number of single-character inputs in form.
+func CountDigitInputs(form *goquery.Selection) int {
+	n := 0
+	form.Find("input").Each(func(_ int, s *goquery.Selection) {
+		if IsDigitInput(s) {
+			n++
+		}
+	})
+	return n
+}
@@ -72,15 +72,31 @@ func GetNavText(doc *goquery.Document) string {
 	return strings.Join(parts, " ")
 }
 
+// PageLink is an <a> element with its trimmed text and raw href.
+type PageLink struct {
+	Text string
+	Href string
+	Sel  *goquery.Selection
+}
+
+// GetPageLinks returns all <a> elements in document order.
+func GetPageLinks(doc *goquery.Document) []PageLink {
+	var links []PageLink
+	doc.Find("a").Each(func(_ int, s *goquery.Selection) {
+		href, _ := s.Attr("href")
+		links = append(links, PageLink{Text: strings.TrimSpace(s.Text()), Href: strings.TrimSpace(href), Sel: s})
+	})
+	return links
+}
+
 // GetPageLinkTexts returns concatenated text of all <a> elements.
 func GetPageLinkTexts(doc *goquery.Document) string {
 	var parts []string
-	doc.Find("a").Each(func(_ int, s *goquery.Selection) {
-		text := strings.TrimSpace(s.Text())
-		if text != "" {
-			parts = append(parts, text)
+	for _, l := range GetPageLinks(doc) {
+		if l.Text != "" {
+			parts = append(parts, l.Text)
 		}
-	})
+	}
 	return strings.Join(parts, " ")
 }
 
@@ -0,0 +1,110 @@
+package htmlutil
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// cssIdentRe matches ids that can be used in a #id selector without escaping.
+var cssIdentRe = regexp.MustCompile(`^[A-Za-z_][-A-Za-z0-9_]*$`)
+
+// IDCounts maps the element ids of a document to their number of
+// occurrences, so that the selectors of one document share a single walk.
+type IDCounts map[string]int
+
+// CountIDs counts the ids of the document containing s.
+func CountIDs(s *goquery.Selection) IDCounts {
+	ids := make(IDCounts)
+	if s.Length() == 0 {
+		return ids
+	}
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			if id := attr(n, "id"); id != "" {
+				ids[id]++
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(rootNode(s.Get(0)))
+	return ids
+}
+
+// CSSPath returns a CSS selector that locates the first element of s in its
+// document. The path is anchored at the nearest ancestor-or-self with a
+// document-unique id, or at the root element otherwise. It walks the whole
+// document; use IDCounts.CSSPath for many elements of one document.
+func CSSPath(s *goquery.Selection) string {
+	return CountIDs(s).CSSPath(s)
+}
+
+// CSSPath is CSSPath for an element of the document whose ids are counted
+// by ids.
+func (ids IDCounts) CSSPath(s *goquery.Selection) string {
+	if s.Length() == 0 {
+		return ""
+	}
+	var parts []string
+	for n := s.Get(0); n != nil && n.Type == html.ElementNode; n = n.Parent {
+		if id := attr(n, "id"); cssIdentRe.MatchString(id) && ids[id] == 1 {
+			parts = append(parts, "#"+id)
+			break
+		}
+		if n.Parent == nil || n.Parent.Type != html.ElementNode {
+			parts = append(parts, n.Data)
+			break
+		}
+		parts = append(parts, fmt.Sprintf("%s:nth-of-type(%d)", n.Data, typeIndex(n)))
+	}
+	slices.Reverse(parts)
+	return strings.Join(parts, " > ")
+}
+
+// XPath returns an absolute XPath for the first element of s,
+// e.g. /html/body/form[1]/input[2].
+func XPath(s *goquery.Selection) string {
+	if s.Length() == 0 {
+		return ""
+	}
+	var parts []string
+	for n := s.Get(0); n != nil && n.Type == html.ElementNode; n = n.Parent {
+		parts = append(parts, fmt.Sprintf("%s[%d]", n.Data, typeIndex(n)))
+	}
+	slices.Reverse(parts)
+	return "/" + strings.Join(parts, "/")
+}
+
+// typeIndex returns the 1-based position of n among its siblings with the same tag.
+func typeIndex(n *html.Node) int {
+	idx := 1
+	for sib := n.PrevSibling; sib != nil; sib = sib.PrevSibling {
+		if sib.Type == html.ElementNode && sib.Data == n.Data {
+			idx++
+		}
+	}
+	return idx
+}
+
+func rootNode(n *html.Node) *html.Node {
+	for n.Parent != nil {
+		n = n.Parent
+	}
+	return n
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,128 @@
+package htmlutil
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Card field kinds returned by CardFieldKind.
+const (
+	CardNumber      = "number"
+	CardExpiry      = "expiry"
+	CardExpiryMonth = "expiry-month"
+	CardExpiryYear  = "expiry-year"
+	CardCVC         = "cvc"
+	CardName        = "name"
+	CardIBAN        = "iban"
+)
+
+// cardAutocomplete maps the autocomplete tokens of card fields to kinds.
+var cardAutocomplete = map[string]string{
+	"cc-number":      CardNumber,
+	"cc-exp":         CardExpiry,
+	"cc-exp-month":   CardExpiryMonth,
+	"cc-exp-year":    CardExpiryYear,
+	"cc-csc":         CardCVC,
+	"cc-name":        CardName,
+	"cc-given-name":  CardName,
+	"cc-family-name": CardName,
+}
+
+// cardNames matches the names and ids of card fields, tried in order.
+// Identifiers are lowercased with "-", "_", "[", "]" and "." replaced by
+// spaces and camelCase split, so "cardNumber" and "card[number]" both read
+// "card number".
+var cardNames = []struct {
+	kind string
+	re   *regexp.Regexp
+}{
+	{CardIBAN, regexp.MustCompile(`\biban\b`)},
+	{CardCVC, regexp.MustCompile(`\b(cvv2?|cvc2?|csc|card (security|verification) (code|value)|card code)\b`)},
+	{CardExpiryMonth, regexp.MustCompile(`\b(cc|card) ?exp(iry|iration)? ?(month|mm|m)\b|\bexp(iry|iration)? ?(month|mm)\b`)},
+	{CardExpiryYear, regexp.MustCompile(`\b(cc|card) ?exp(iry|iration)? ?(year|yy|yyyy|y)\b|\bexp(iry|iration)? ?(year|yy|yyyy)\b`)},
+	{CardExpiry, regexp.MustCompile(`\b(cc|card) ?exp(iry|iration)?( ?date)?\b|\bexp(iry|iration) ?date\b|\bmm ?yy\b`)},
+	{CardNumber, regexp.MustCompile(`\b(cc|card|credit ?card) ?(num|number|no|nr)\b|\bcardnumber\b|\bccnum\b`)},
+	{CardName, regexp.MustCompile(`\b(card|cc) ?(holder|owner)( ?name)?\b|\bcardholder\b|\bname on card\b|\b(cc|card) name\b`)},
+}
+
+var (
+	camelBoundary  = regexp.MustCompile(`([a-z0-9])([A-Z])`)
+	identifierSeps = strings.NewReplacer("-", " ", "_", " ", "[", " ", "]", " ", ".", " ")
+)
+
+// CardFieldKind returns the kind of payment card data elem asks for, from
+// its autocomplete tokens and then its name and id, or "" for other fields.
+func CardFieldKind(elem *goquery.Selection) string {
+	for _, tok := range strings.Fields(strings.ToLower(elem.AttrOr("autocomplete", ""))) {
+		if kind, ok := cardAutocomplete[tok]; ok {
+			return kind
+		}
+	}
+	tp := strings.ToLower(elem.AttrOr("type", "text"))
+	if tp == "hidden" || tp == "submit" || tp == "button" || tp == "checkbox" || tp == "radio" {
+		return ""
+	}
+	ident := identifierWords(elem.AttrOr("name", "") + " " + elem.AttrOr("id", ""))
+	for _, c := range cardNames {
+		if c.re.MatchString(ident) {
+			return c.kind
+		}
+	}
+	return ""
+}
+
+// AddressSection returns "billing" or "shipping" when elem belongs to a
+// billing or shipping address, judging by its autocomplete section, its
+// name and id, or the id, class or legend of an enclosing fieldset or
+// section. It returns "" otherwise.
+func AddressSection(elem *goquery.Selection) string {
+	for _, tok := range strings.Fields(strings.ToLower(elem.AttrOr("autocomplete", ""))) {
+		if tok == "billing" || tok == "shipping" {
+			return tok
+		}
+	}
+	if s := sectionWord(identifierWords(elem.AttrOr("name", "") + " " + elem.AttrOr("id", ""))); s != "" {
+		return s
+	}
+	section := ""
+	elem.ParentsFiltered("fieldset, section, div[id], div[class]").EachWithBreak(func(_ int, p *goquery.Selection) bool {
+		text := p.AttrOr("id", "") + " " + p.AttrOr("class", "")
+		if goquery.NodeName(p) == "fieldset" {
+			text += " " + p.ChildrenFiltered("legend").Text()
+		}
+		section = sectionWord(identifierWords(text))
+		return section == ""
+	})
+	return section
+}
+
+var sectionPattern = regexp.MustCompile(`\b(billing|shipping|delivery)\b`)
+
+func sectionWord(s string) string {
+	switch sectionPattern.FindString(s) {
+	case "billing":
+		return "billing"
+	case "shipping", "delivery":
+		return "shipping"
+	}
+	return ""
+}
+
+// identifierWords splits HTML identifiers into lowercase words.
+func identifierWords(s string) string {
+	return strings.ToLower(identifierSeps.Replace(camelBoundary.ReplaceAllString(s, "$1 $2")))
+}
+
+// HasCardInput reports whether form has an input for a card number or
+// security code.
+func HasCardInput(form *goquery.Selection) bool {
+	found := false
+	form.Find("input").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		kind := CardFieldKind(s)
+		found = kind == CardNumber || kind == CardCVC
+		return !found
+	})
+	return found
+}
@@ -0,0 +1,43 @@
+package htmlutil
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// BaseURL returns the URL that relative references in doc resolve against:
+// the first <base href>, resolved against pageURL, or pageURL itself. It
+// returns nil when neither is an absolute URL.
+func BaseURL(doc *goquery.Document, pageURL string) *url.URL {
+	page, err := url.Parse(strings.TrimSpace(pageURL))
+	if err != nil || !page.IsAbs() {
+		page = nil
+	}
+	if href, ok := doc.Find("base[href]").First().Attr("href"); ok {
+		if ref, err := url.Parse(strings.TrimSpace(href)); err == nil {
+			if page != nil {
+				return page.ResolveReference(ref)
+			}
+			if ref.IsAbs() {
+				return ref
+			}
+		}
+	}
+	return page
+}
+
+// ResolveURL resolves ref against base. It returns ref unchanged when base
+// is nil or ref cannot be parsed.
+func ResolveURL(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if base == nil {
+		return ref
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return base.ResolveReference(u).String()
+}
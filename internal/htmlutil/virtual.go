@@ -0,0 +1,125 @@
+package htmlutil
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// maxVirtualFormDepth bounds how far a virtual form container is searched
+// above an orphan control.
+const maxVirtualFormDepth = 5
+
+// GetAllForms returns the <form> elements of the document followed by its
+// virtual forms.
+func GetAllForms(doc *goquery.Document) []*goquery.Selection {
+	return append(GetForms(doc), GetVirtualForms(doc)...)
+}
+
+// IsVirtualForm reports whether form is a virtual form container rather
+// than a <form> element.
+func IsVirtualForm(form *goquery.Selection) bool {
+	return goquery.NodeName(form) != "form"
+}
+
+// GetVirtualForms clusters controls that live outside any <form> into
+// pseudo-forms, as rendered by script-driven login widgets. Each virtual
+// form is the closest common container of a group of orphan controls that
+// does not itself contain a <form>. Nested containers are merged into the
+// outermost one.
+func GetVirtualForms(doc *goquery.Document) []*goquery.Selection {
+	orphans := make(map[*html.Node]bool)
+	var seeds []*goquery.Selection
+	doc.Find("input, select, textarea, button").Each(func(_ int, s *goquery.Selection) {
+		if s.Closest("form").Length() > 0 {
+			return
+		}
+		if owner, _ := s.Attr("form"); owner != "" {
+			return
+		}
+		if tp, _ := s.Attr("type"); strings.EqualFold(tp, "hidden") {
+			return
+		}
+		orphans[s.Get(0)] = true
+		if !isButton(s) {
+			seeds = append(seeds, s)
+		}
+	})
+	if len(seeds) == 0 {
+		return nil
+	}
+
+	var containers []*html.Node
+	seen := make(map[*html.Node]bool)
+	for _, s := range seeds {
+		c := virtualFormContainer(s, orphans)
+		if c != nil && !seen[c] {
+			seen[c] = true
+			containers = append(containers, c)
+		}
+	}
+
+	var forms []*goquery.Selection
+	for _, c := range containers {
+		if hasAncestorIn(c, seen) {
+			continue
+		}
+		forms = append(forms, doc.FindNodes(c))
+	}
+	return forms
+}
+
+// virtualFormContainer returns the lowest ancestor of s that holds another
+// orphan control, falling back to the parent of s. It returns nil when no
+// container can be found without swallowing a <form>.
+func virtualFormContainer(s *goquery.Selection, orphans map[*html.Node]bool) *html.Node {
+	parent := s.Parent()
+	if parent.Length() == 0 || parent.Find("form").Length() > 0 {
+		return nil
+	}
+	fallback := parent.Get(0)
+
+	p := parent
+	for depth := 0; depth < maxVirtualFormDepth && p.Length() > 0; depth++ {
+		if goquery.NodeName(p) == "html" || p.Find("form").Length() > 0 {
+			break
+		}
+		count := 0
+		p.Find("input, select, textarea, button").Each(func(_ int, c *goquery.Selection) {
+			if orphans[c.Get(0)] {
+				count++
+			}
+		})
+		if count > 1 {
+			return p.Get(0)
+		}
+		p = p.Parent()
+	}
+	return fallback
+}
+
+func hasAncestorIn(n *html.Node, set map[*html.Node]bool) bool {
+	for p := n.Parent; p != nil; p = p.Parent {
+		if set[p] {
+			return true
+		}
+	}
+	return false
+}
+
+// isButton reports whether s is a <button> or a button-like <input>.
+func isButton(s *goquery.Selection) bool {
+	if goquery.NodeName(s) == "button" {
+		return true
+	}
+	if goquery.NodeName(s) != "input" {
+		return false
+	}
+	tp, _ := s.Attr("type")
+	switch strings.ToLower(tp) {
+	case "submit", "button", "reset", "image":
+		return true
+	}
+	return false
+}
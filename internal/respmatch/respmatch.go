@@ -0,0 +1,105 @@
+// Package respmatch holds the signature matching shared by the captcha and
+// protection packages: response header and cookie matchers, the strict
+// YAML/JSON rule decoding and the noisy-OR combination of evidence.
+package respmatch
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Header matches a response header by name and, optionally, value.
+type Header struct {
+	Name  string         // canonical header name
+	Value *regexp.Regexp // nil matches any value
+}
+
+// ParseHeader parses a header matcher: a header name, optionally followed
+// by ":" and a regular expression matched against the lowercased value.
+func ParseHeader(spec string) (Header, error) {
+	name, value, hasValue := strings.Cut(spec, ":")
+	h := Header{Name: http.CanonicalHeaderKey(strings.TrimSpace(name))}
+	if hasValue {
+		re, err := regexp.Compile(strings.TrimSpace(value))
+		if err != nil {
+			return Header{}, fmt.Errorf("header pattern %q: %w", spec, err)
+		}
+		h.Value = re
+	}
+	return h, nil
+}
+
+// ParseHeaders parses a list of header matchers.
+func ParseHeaders(specs []string) ([]Header, error) {
+	var out []Header
+	for _, spec := range specs {
+		h, err := ParseHeader(spec)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, h)
+	}
+	return out, nil
+}
+
+// Match returns the evidence for h in header, "name" or "name: value" with
+// the name lowercased, and whether h matched.
+func (h Header) Match(header http.Header) (string, bool) {
+	for _, v := range header[h.Name] {
+		if h.Value == nil {
+			return strings.ToLower(h.Name), true
+		}
+		if h.Value.MatchString(strings.ToLower(v)) {
+			return strings.ToLower(h.Name) + ": " + v, true
+		}
+	}
+	return "", false
+}
+
+// CookieNames returns the lowercased names of the cookies set by a
+// response with the given header.
+func CookieNames(header http.Header) []string {
+	var names []string
+	for _, c := range (&http.Response{Header: header}).Cookies() {
+		names = append(names, strings.ToLower(c.Name))
+	}
+	return names
+}
+
+// MatchCookie returns the first of names starting with prefix, and whether
+// there is one.
+func MatchCookie(prefix string, names []string) (string, bool) {
+	for _, name := range names {
+		if strings.HasPrefix(name, prefix) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// NoisyOR combines the confidences of independent pieces of evidence.
+func NoisyOR(weights []float64) float64 {
+	miss := 1.0
+	for _, w := range weights {
+		miss *= 1 - w
+	}
+	return 1 - miss
+}
+
+// Decode parses rules in YAML or JSON into v. Unknown keys are rejected so
+// that misspelt locations are not silently ignored.
+func Decode(data []byte, v any) error {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("parse rules: %w", err)
+	}
+	return nil
+}
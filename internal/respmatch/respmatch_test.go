@@ -0,0 +1,45 @@
+package respmatch
+
+import (
+	"math"
+	"net/http"
+	"testing"
+)
+
+func TestHeaderMatch(t *testing.T) {
+	header := http.Header{"Server": {"Cloudflare"}, "Cf-Ray": {"8a1b-AMS"}}
+	tests := []struct {
+		spec, want string
+		ok         bool
+	}{
+		{"cf-ray", "cf-ray", true},
+		{"server: ^cloudflare", "server: Cloudflare", true},
+		{"server: nginx", "", false},
+		{"x-missing", "", false},
+	}
+	for _, tt := range tests {
+		h, err := ParseHeader(tt.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, ok := h.Match(header); got != tt.want || ok != tt.ok {
+			t.Errorf("%q: got %q %v, want %q %v", tt.spec, got, ok, tt.want, tt.ok)
+		}
+	}
+	if _, err := ParseHeader("server: ("); err == nil {
+		t.Error("expected an error for a bad value pattern")
+	}
+}
+
+func TestCookiesAndNoisyOR(t *testing.T) {
+	names := CookieNames(http.Header{"Set-Cookie": {"Incap_Ses_1=a; Path=/", "other=b"}})
+	if name, ok := MatchCookie("incap_ses", names); !ok || name != "incap_ses_1" {
+		t.Errorf("MatchCookie = %q %v", name, ok)
+	}
+	if _, ok := MatchCookie("__cf_bm", names); ok {
+		t.Error("unexpected cookie match")
+	}
+	if got := NoisyOR([]float64{0.5, 0.5}); math.Abs(got-0.75) > 1e-12 {
+		t.Errorf("NoisyOR = %v, want 0.75", got)
+	}
+}
@@ -0,0 +1,244 @@
+// Package server exposes the dit classifier over HTTP.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/happyhackingspace/dit"
+)
+
+// DefaultMaxBodyBytes is the default request body size limit (10MB).
+const DefaultMaxBodyBytes = 10 << 20
+
+// Config holds server configuration.
+type Config struct {
+	MaxBodyBytes int64   // maximum request body size; <= 0 uses DefaultMaxBodyBytes
+	Threshold    float64 // default probability threshold for /proba endpoints
+}
+
+// Server serves classification requests using a shared, preloaded classifier.
+// It reports not-ready until a classifier is set, so the listener can start
+// while the model is still loading.
+type Server struct {
+	config Config
+	cl     atomic.Pointer[dit.Classifier]
+	mux    *http.ServeMux
+}
+
+// classifyRequest is the JSON request body accepted by the classification endpoints.
+type classifyRequest struct {
+	HTML string `json:"html"`
+	URL  string `json:"url,omitempty"`
+}
+
+// options returns the extraction options for req. The body size is
+// already bounded by MaxBodyBytes, so no further input limit applies.
+func (req classifyRequest) options(threshold float64) *dit.Options {
+	return &dit.Options{URL: req.URL, Threshold: threshold, MaxInputSize: -1}
+}
+
+// errorResponse is the JSON body returned on failure.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// New creates a Server with the given configuration.
+func New(config Config) *Server {
+	if config.MaxBodyBytes <= 0 {
+		config.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	s := &Server{config: config, mux: http.NewServeMux()}
+
+	s.mux.HandleFunc("GET /healthz", s.handleHealth)
+	s.mux.HandleFunc("GET /readyz", s.handleReady)
+	s.mux.HandleFunc("POST /v1/page", s.handlePage)
+	s.mux.HandleFunc("POST /v1/page/proba", s.handlePageProba)
+	s.mux.HandleFunc("POST /v1/forms", s.handleForms)
+	s.mux.HandleFunc("POST /v1/forms/proba", s.handleFormsProba)
+	return s
+}
+
+// SetClassifier makes the server ready to classify requests with cl.
+func (s *Server) SetClassifier(cl *dit.Classifier) {
+	s.cl.Store(cl)
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *Server) handleReady(w http.ResponseWriter, _ *http.Request) {
+	if s.cl.Load() == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "loading"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+}
+
+func (s *Server) handlePage(w http.ResponseWriter, r *http.Request) {
+	cl, req, ok := s.prepare(w, r)
+	if !ok {
+		return
+	}
+	minConfidence, err := floatQuery(r, "min_confidence", 0)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	opts := req.options(0)
+	opts.MinConfidence = minConfidence
+	result, err := cl.ExtractPageTypeContext(r.Context(), strings.NewReader(req.HTML), opts)
+	s.respond(w, result, err)
+}
+
+func (s *Server) handlePageProba(w http.ResponseWriter, r *http.Request) {
+	cl, req, ok := s.prepare(w, r)
+	if !ok {
+		return
+	}
+	threshold, err := s.threshold(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	result, err := cl.ExtractPageTypeProbaContext(r.Context(), strings.NewReader(req.HTML), req.options(threshold))
+	s.respond(w, result, err)
+}
+
+func (s *Server) handleForms(w http.ResponseWriter, r *http.Request) {
+	cl, req, ok := s.prepare(w, r)
+	if !ok {
+		return
+	}
+	minConfidence, err := floatQuery(r, "min_confidence", 0)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	opts := req.options(0)
+	opts.MinConfidence = minConfidence
+	results, err := cl.ExtractFormsContext(r.Context(), strings.NewReader(req.HTML), opts)
+	s.respond(w, results, err)
+}
+
+func (s *Server) handleFormsProba(w http.ResponseWriter, r *http.Request) {
+	cl, req, ok := s.prepare(w, r)
+	if !ok {
+		return
+	}
+	threshold, err := s.threshold(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	results, err := cl.ExtractFormsProbaContext(r.Context(), strings.NewReader(req.HTML), req.options(threshold))
+	s.respond(w, results, err)
+}
+
+// prepare checks readiness and decodes the request body. It writes an error
+// response and returns ok=false if the request cannot be served.
+func (s *Server) prepare(w http.ResponseWriter, r *http.Request) (*dit.Classifier, classifyRequest, bool) {
+	cl := s.cl.Load()
+	if cl == nil {
+		writeError(w, http.StatusServiceUnavailable, errors.New("model is still loading"))
+		return nil, classifyRequest{}, false
+	}
+
+	req, err := s.readRequest(w, r)
+	if err != nil {
+		status := http.StatusBadRequest
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		writeError(w, status, err)
+		return nil, classifyRequest{}, false
+	}
+	return cl, req, true
+}
+
+// readRequest reads either a JSON {html, url} body or raw HTML. For raw
+// bodies the page URL may be passed as the "url" query parameter.
+func (s *Server) readRequest(w http.ResponseWriter, r *http.Request) (classifyRequest, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.config.MaxBodyBytes))
+	if err != nil {
+		return classifyRequest{}, fmt.Errorf("read body: %w", err)
+	}
+
+	var req classifyRequest
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/json" {
+		if err := json.Unmarshal(body, &req); err != nil {
+			return classifyRequest{}, fmt.Errorf("decode body: %w", err)
+		}
+	} else {
+		req.HTML = string(body)
+		req.URL = r.URL.Query().Get("url")
+	}
+
+	if req.HTML == "" {
+		return classifyRequest{}, errors.New("empty html")
+	}
+	return req, nil
+}
+
+// threshold returns the "threshold" query parameter or the configured default.
+func (s *Server) threshold(r *http.Request) (float64, error) {
+	return floatQuery(r, "threshold", s.config.Threshold)
+}
+
+// floatQuery returns the query parameter name as a number, or def if it is
+// absent.
+func floatQuery(r *http.Request, name string, def float64) (float64, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return def, nil
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %q", name, raw)
+	}
+	return v, nil
+}
+
+func (s *Server) respond(w http.ResponseWriter, v any, err error) {
+	switch {
+	case errors.Is(err, context.Canceled):
+		// The client went away; nobody is left to read the response.
+		return
+	case errors.Is(err, context.DeadlineExceeded):
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Debug("Write response failed", "error", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
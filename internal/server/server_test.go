@@ -0,0 +1,117 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/happyhackingspace/dit"
+)
+
+const loginFormHTML = `<html><body>
+<form method="POST" action="/login">
+  <input type="text" name="username"/>
+  <input type="password" name="password"/>
+  <input type="submit" value="Log In"/>
+</form>
+</body></html>`
+
+func do(t *testing.T, s *Server, method, target, contentType, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHealthAndReadiness(t *testing.T) {
+	s := New(Config{})
+
+	if rec := do(t, s, http.MethodGet, "/healthz", "", ""); rec.Code != http.StatusOK {
+		t.Errorf("healthz: got %d, want 200", rec.Code)
+	}
+	if rec := do(t, s, http.MethodGet, "/readyz", "", ""); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("readyz before load: got %d, want 503", rec.Code)
+	}
+	if rec := do(t, s, http.MethodPost, "/v1/page", "text/html", loginFormHTML); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("page before load: got %d, want 503", rec.Code)
+	}
+
+	s.SetClassifier(&dit.Classifier{})
+	if rec := do(t, s, http.MethodGet, "/readyz", "", ""); rec.Code != http.StatusOK {
+		t.Errorf("readyz after load: got %d, want 200", rec.Code)
+	}
+}
+
+func TestRequestValidation(t *testing.T) {
+	s := New(Config{MaxBodyBytes: 64})
+	s.SetClassifier(&dit.Classifier{})
+
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		contentType string
+		body        string
+		want        int
+	}{
+		{"too large", http.MethodPost, "/v1/forms", "text/html", strings.Repeat("a", 65), http.StatusRequestEntityTooLarge},
+		{"empty", http.MethodPost, "/v1/forms", "text/html", "", http.StatusBadRequest},
+		{"bad json", http.MethodPost, "/v1/forms", "application/json", "{", http.StatusBadRequest},
+		{"bad threshold", http.MethodPost, "/v1/forms/proba?threshold=x", "text/html", "<form></form>", http.StatusBadRequest},
+		{"bad min_confidence", http.MethodPost, "/v1/page?min_confidence=x", "text/html", "<form></form>", http.StatusBadRequest},
+		{"wrong method", http.MethodGet, "/v1/forms", "", "", http.StatusMethodNotAllowed},
+		{"uninitialized classifier", http.MethodPost, "/v1/forms", "text/html", "<form></form>", http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := do(t, s, tt.method, tt.target, tt.contentType, tt.body)
+			if rec.Code != tt.want {
+				t.Errorf("got %d, want %d (body: %s)", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestClassifyEndpoints(t *testing.T) {
+	path, err := dit.FindModel("model.json")
+	if err != nil {
+		t.Skip("model.json not found, skipping")
+	}
+	cl, err := dit.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := New(Config{Threshold: 0.05})
+	s.SetClassifier(cl)
+
+	body, _ := json.Marshal(classifyRequest{HTML: loginFormHTML, URL: "https://example.com/login"})
+	rec := do(t, s, http.MethodPost, "/v1/page", "application/json", string(body))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("page: got %d: %s", rec.Code, rec.Body.String())
+	}
+	var page dit.PageResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+	if page.Type == "" || len(page.Forms) != 1 {
+		t.Errorf("unexpected page result: %+v", page)
+	}
+
+	rec = do(t, s, http.MethodPost, "/v1/forms/proba", "text/html", loginFormHTML)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("forms/proba: got %d: %s", rec.Code, rec.Body.String())
+	}
+	var forms []dit.FormResultProba
+	if err := json.Unmarshal(rec.Body.Bytes(), &forms); err != nil {
+		t.Fatal(err)
+	}
+	if len(forms) != 1 || len(forms[0].Type) == 0 {
+		t.Errorf("unexpected forms result: %+v", forms)
+	}
+}
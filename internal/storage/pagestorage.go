@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 )
 
@@ -25,17 +26,22 @@ type pageConfigJSON struct {
 }
 
 // pageIndexEntry represents a single entry in the page index.json.
+// PageTypes lists further types of a page that is several things at once,
+// e.g. a "waf_block" that is also a "captcha"; PageType is its primary
+// type.
 type pageIndexEntry struct {
-	URL      string `json:"url"`
-	PageType string `json:"page_type"`
+	URL       string   `json:"url"`
+	PageType  string   `json:"page_type"`
+	PageTypes []string `json:"page_types,omitempty"`
 }
 
 // PageAnnotation represents a single annotated page.
 type PageAnnotation struct {
-	HTML     string
-	URL      string
-	Type     string // short page type
-	TypeFull string // full page type
+	HTML      string
+	URL       string
+	Type      string   // short page type
+	TypeFull  string   // full page type
+	TypesFull []string // all full page types, the primary type first
 }
 
 // GetPageSchema reads the page type schema from config.json.
@@ -48,7 +54,7 @@ func (s *PageStorage) GetPageSchema() (*AnnotationSchema, error) {
 	if err := json.Unmarshal(data, &config); err != nil {
 		return nil, err
 	}
-	return buildSchema(config.PageTypes), nil
+	return buildSchema(config.PageTypes, nil), nil
 }
 
 // GetPageIndex reads the page index file.
@@ -111,19 +117,32 @@ func (s *PageStorage) IterPageAnnotations(opts IterOptions) ([]PageAnnotation, e
 			continue
 		}
 
-		typeFull := tp
-		if full, ok := schema.TypesInv[tp]; ok {
-			typeFull = full
+		typeFull := fullType(schema, tp)
+		typesFull := []string{typeFull}
+		for _, extra := range pi.info.PageTypes {
+			if full := fullType(schema, extra); !slices.Contains(typesFull, full) {
+				typesFull = append(typesFull, full)
+			}
 		}
 
 		ann := PageAnnotation{
-			HTML:     string(htmlData),
-			URL:      pi.info.URL,
-			Type:     tp,
-			TypeFull: typeFull,
+			HTML:      string(htmlData),
+			URL:       pi.info.URL,
+			Type:      tp,
+			TypeFull:  typeFull,
+			TypesFull: typesFull,
 		}
 		annotations = append(annotations, ann)
 	}
 
 	return annotations, nil
 }
+
+// fullType returns the full name of the short page type tp, or tp itself
+// if the schema does not list it.
+func fullType(schema *AnnotationSchema, tp string) string {
+	if full, ok := schema.TypesInv[tp]; ok {
+		return full
+	}
+	return tp
+}
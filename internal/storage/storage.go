@@ -63,13 +63,35 @@ func (s *Storage) GetConfig() (*configJSON, error) {
 	return &config, nil
 }
 
+// Built-in types added to those of config.json, so that data annotated
+// with them can be used before the published config lists them.
+var (
+	builtinFormTypes = []typeEntry{
+		{Full: "2fa challenge", Short: "2fa"},
+	}
+	builtinFieldTypes = []typeEntry{
+		{Full: "one-time code", Short: "otp"},
+		{Full: "one-time code digit", Short: "otp1"},
+		{Full: "backup code", Short: "bkc"},
+		{Full: "card number", Short: "ccnum"},
+		{Full: "card expiry date", Short: "ccexp"},
+		{Full: "card expiry month", Short: "ccexpm"},
+		{Full: "card expiry year", Short: "ccexpy"},
+		{Full: "card security code", Short: "cvc"},
+		{Full: "cardholder name", Short: "ccname"},
+		{Full: "iban", Short: "iban"},
+		{Full: "billing address", Short: "baddr"},
+		{Full: "shipping address", Short: "saddr"},
+	}
+)
+
 // GetFormSchema returns the form annotation schema.
 func (s *Storage) GetFormSchema() (*AnnotationSchema, error) {
 	config, err := s.GetConfig()
 	if err != nil {
 		return nil, err
 	}
-	return buildSchema(config.FormTypes), nil
+	return buildSchema(config.FormTypes, builtinFormTypes), nil
 }
 
 // GetFieldSchema returns the field annotation schema.
@@ -78,16 +100,26 @@ func (s *Storage) GetFieldSchema() (*AnnotationSchema, error) {
 	if err != nil {
 		return nil, err
 	}
-	return buildSchema(config.FieldTypes), nil
+	return buildSchema(config.FieldTypes, builtinFieldTypes), nil
 }
 
-func buildSchema(tc typeConfig) *AnnotationSchema {
-	types := make(map[string]string, len(tc.Types))
-	typesInv := make(map[string]string, len(tc.Types))
+// buildSchema indexes the types of tc. Builtin types are added unless
+// config.json already defines their full or short name.
+func buildSchema(tc typeConfig, builtin []typeEntry) *AnnotationSchema {
+	types := make(map[string]string, len(tc.Types)+len(builtin))
+	typesInv := make(map[string]string, len(tc.Types)+len(builtin))
 	for _, t := range tc.Types {
 		types[t.Full] = t.Short
 		typesInv[t.Short] = t.Full
 	}
+	for _, t := range builtin {
+		_, hasFull := types[t.Full]
+		_, hasShort := typesInv[t.Short]
+		if !hasFull && !hasShort {
+			types[t.Full] = t.Short
+			typesInv[t.Short] = t.Full
+		}
+	}
 	return &AnnotationSchema{
 		Types:       types,
 		TypesInv:    typesInv,
@@ -1,6 +1,11 @@
 package storage
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
 
 func TestGetDomain(t *testing.T) {
 	tests := []struct {
@@ -20,3 +25,50 @@ func TestGetDomain(t *testing.T) {
 		}
 	}
 }
+
+func TestBuildSchemaBuiltinTypes(t *testing.T) {
+	tc := typeConfig{Types: []typeEntry{{Full: "login", Short: "l"}, {Full: "backup code", Short: "bk"}}}
+	s := buildSchema(tc, append([]typeEntry{{Full: "login", Short: "x"}}, builtinFieldTypes...))
+
+	if s.TypesInv["otp"] != "one-time code" || s.Types["one-time code digit"] != "otp1" {
+		t.Errorf("builtin OTP types missing: %v", s.Types)
+	}
+	if s.Types["login"] != "l" || s.TypesInv["x"] != "" {
+		t.Errorf("config types must win over builtins: %v", s.Types)
+	}
+	if s.Types["backup code"] != "bk" || s.TypesInv["bkc"] != "" {
+		t.Errorf("builtin backup code should defer to config.json: %v", s.Types)
+	}
+}
+
+func TestIterPageAnnotationsMultiLabel(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"config.json": `{"page_types": {"types": [{"full": "waf_block", "short": "w"}, {"full": "captcha", "short": "c"}, {"full": "login", "short": "l"}]}}`,
+		"index.json": `{
+			"a.html": {"url": "http://a.example.com/", "page_type": "w", "page_types": ["c", "w"]},
+			"b.html": {"url": "http://b.example.com/", "page_type": "l"}
+		}`,
+		"a.html": "<html></html>",
+		"b.html": "<html></html>",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	anns, err := NewPageStorage(dir).IterPageAnnotations(DefaultIterOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anns) != 2 {
+		t.Fatalf("got %d annotations, want 2", len(anns))
+	}
+	if got := anns[0].TypesFull; anns[0].TypeFull != "waf_block" || !slices.Equal(got, []string{"waf_block", "captcha"}) {
+		t.Errorf("multi-label page: TypeFull %q, TypesFull %v", anns[0].TypeFull, got)
+	}
+	if got := anns[1].TypesFull; !slices.Equal(got, []string{"login"}) {
+		t.Errorf("single-label page: TypesFull %v, want [login]", got)
+	}
+}
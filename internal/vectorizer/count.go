@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/happyhackingspace/dit/internal/textutil"
 )
@@ -15,6 +16,9 @@ type CountVectorizer struct {
 	Binary     bool           `json:"binary"`
 	Analyzer   string         `json:"analyzer"` // "word" or "char_wb"
 	MinDF      int            `json:"min_df"`
+
+	terms     []string // set by SetTerms until Vocabulary is built
+	vocabOnce sync.Once
 }
 
 // NewCountVectorizer creates a CountVectorizer with default settings.
@@ -72,6 +76,7 @@ func (cv *CountVectorizer) Fit(corpus []string) {
 	}
 
 	// Build vocabulary filtered by min_df
+	cv.terms = nil
 	cv.Vocabulary = make(map[string]int)
 	// Sort terms for deterministic ordering
 	terms := make([]string, 0, len(dfCounts))
@@ -86,6 +91,29 @@ func (cv *CountVectorizer) Fit(corpus []string) {
 	}
 }
 
+// SetTerms sets the vocabulary to terms, in index order. The term index is
+// built on first use, so that loading a model does not pay for
+// vectorizers it never runs.
+func (cv *CountVectorizer) SetTerms(terms []string) {
+	cv.Vocabulary = nil
+	cv.terms = terms
+}
+
+// Index returns the vocabulary, building it first if it was set by
+// SetTerms.
+func (cv *CountVectorizer) Index() map[string]int {
+	cv.vocabOnce.Do(func() {
+		if cv.Vocabulary != nil || cv.terms == nil {
+			return
+		}
+		cv.Vocabulary = make(map[string]int, len(cv.terms))
+		for i, term := range cv.terms {
+			cv.Vocabulary[term] = i
+		}
+	})
+	return cv.Vocabulary
+}
+
 // FitTransform fits the vocabulary and transforms the corpus.
 func (cv *CountVectorizer) FitTransform(corpus []string) []SparseVector {
 	cv.Fit(corpus)
@@ -98,13 +126,13 @@ func (cv *CountVectorizer) FitTransform(corpus []string) []SparseVector {
 
 // Transform converts a single document to a sparse vector.
 func (cv *CountVectorizer) Transform(text string) SparseVector {
-	dim := len(cv.Vocabulary)
-	sv := NewSparseVector(dim)
+	vocab := cv.Index()
+	sv := NewSparseVector(len(vocab))
 	features := cv.analyze(text)
 
 	counts := make(map[int]float64)
 	for _, f := range features {
-		if idx, ok := cv.Vocabulary[f]; ok {
+		if idx, ok := vocab[f]; ok {
 			counts[idx]++
 		}
 	}
@@ -121,11 +149,30 @@ func (cv *CountVectorizer) Transform(text string) SparseVector {
 
 // VocabSize returns the vocabulary size.
 func (cv *CountVectorizer) VocabSize() int {
-	return len(cv.Vocabulary)
+	if cv.terms != nil {
+		return len(cv.terms)
+	}
+	return len(cv.Index())
+}
+
+// Names returns the term of each vector index.
+func (cv *CountVectorizer) Names() []string {
+	if cv.terms != nil {
+		return cv.terms
+	}
+	vocab := cv.Index()
+	names := make([]string, len(vocab))
+	for term, idx := range vocab {
+		if idx < len(names) {
+			names[idx] = term
+		}
+	}
+	return names
 }
 
 // MarshalJSON implements json.Marshaler.
 func (cv *CountVectorizer) MarshalJSON() ([]byte, error) {
+	cv.Index()
 	type Alias CountVectorizer
 	return json.Marshal((*Alias)(cv))
 }
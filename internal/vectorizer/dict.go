@@ -1,14 +1,19 @@
 package vectorizer
 
 import (
+	"encoding/json"
 	"fmt"
 	"sort"
+	"sync"
 )
 
-// DictVectorizer converts feature dicts to sparse vectors.
+// DictVectorizer converts feature dicts to sparse vectors. A vectorizer
+// given only FeatureNames builds FeatureIndex on first use.
 type DictVectorizer struct {
 	FeatureNames []string       `json:"feature_names"`
 	FeatureIndex map[string]int `json:"feature_index"`
+
+	indexOnce sync.Once
 }
 
 // NewDictVectorizer creates an empty DictVectorizer.
@@ -38,6 +43,20 @@ func (dv *DictVectorizer) Fit(data []map[string]any) {
 	}
 }
 
+// index builds FeatureIndex from FeatureNames if it is missing.
+func (dv *DictVectorizer) index() map[string]int {
+	dv.indexOnce.Do(func() {
+		if dv.FeatureIndex != nil {
+			return
+		}
+		dv.FeatureIndex = make(map[string]int, len(dv.FeatureNames))
+		for i, f := range dv.FeatureNames {
+			dv.FeatureIndex[f] = i
+		}
+	})
+	return dv.FeatureIndex
+}
+
 // FitTransform fits and transforms the data.
 func (dv *DictVectorizer) FitTransform(data []map[string]any) []SparseVector {
 	dv.Fit(data)
@@ -53,9 +72,10 @@ func (dv *DictVectorizer) Transform(d map[string]any) SparseVector {
 	dim := len(dv.FeatureNames)
 	sv := NewSparseVector(dim)
 
+	index := dv.index()
 	for k, v := range d {
 		key := dv.featureKey(k, v)
-		if idx, ok := dv.FeatureIndex[key]; ok {
+		if idx, ok := index[key]; ok {
 			sv.Set(idx, dv.featureValue(v))
 		}
 	}
@@ -67,6 +87,21 @@ func (dv *DictVectorizer) VocabSize() int {
 	return len(dv.FeatureNames)
 }
 
+// Names returns the feature name of each vector index.
+func (dv *DictVectorizer) Names() []string {
+	return dv.FeatureNames
+}
+
+// MarshalJSON implements json.Marshaler, writing FeatureIndex even if it
+// has not been built yet.
+func (dv *DictVectorizer) MarshalJSON() ([]byte, error) {
+	type alias struct {
+		FeatureNames []string       `json:"feature_names"`
+		FeatureIndex map[string]int `json:"feature_index"`
+	}
+	return json.Marshal(alias{FeatureNames: dv.FeatureNames, FeatureIndex: dv.index()})
+}
+
 // featureKey returns the feature key for a given name-value pair.
 // For string values, it creates compound keys like "name=value".
 // For numeric and bool values, it uses the key directly.
@@ -82,6 +82,11 @@ func (tv *TfidfVectorizer) VocabSize() int {
 	return tv.CountVec.VocabSize()
 }
 
+// Names returns the term of each vector index.
+func (tv *TfidfVectorizer) Names() []string {
+	return tv.CountVec.Names()
+}
+
 func (tv *TfidfVectorizer) filterCorpus(corpus []string) []string {
 	if len(tv.StopWords) == 0 {
 		return corpus
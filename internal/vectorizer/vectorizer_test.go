@@ -2,6 +2,7 @@ package vectorizer
 
 import (
 	"math"
+	"slices"
 	"testing"
 )
 
@@ -115,6 +116,29 @@ func TestTfidfVectorizerStopWords(t *testing.T) {
 	}
 }
 
+func TestVectorizerNames(t *testing.T) {
+	cv := NewCountVectorizer([2]int{1, 1}, true, "word", 1)
+	cv.Fit([]string{"sign in", "log in"})
+	for term, idx := range cv.Vocabulary {
+		if got := cv.Names()[idx]; got != term {
+			t.Errorf("Names()[%d] = %q, want %q", idx, got, term)
+		}
+	}
+
+	tv := NewTfidfVectorizer([2]int{1, 1}, 1, true, "word", nil)
+	tv.Fit([]string{"forgot password"})
+	sv := tv.Transform("password")
+	if len(sv.Indices) != 1 || tv.Names()[sv.Indices[0]] != "password" {
+		t.Errorf("tfidf Names() = %v for indices %v", tv.Names(), sv.Indices)
+	}
+
+	dv := NewDictVectorizer()
+	dv.Fit([]map[string]any{{"method": "post"}})
+	if names := dv.Names(); len(names) != 1 || names[0] != "method=post" {
+		t.Errorf("dict Names() = %v", names)
+	}
+}
+
 func TestDictVectorizer(t *testing.T) {
 	dv := NewDictVectorizer()
 	data := []map[string]any{
@@ -160,3 +184,21 @@ func TestDictVectorizerTransform(t *testing.T) {
 		t.Errorf("unknown feature value should produce no entries, got %d", sv2.Nnz())
 	}
 }
+
+func TestCountVectorizerSetTerms(t *testing.T) {
+	fitted := NewCountVectorizer([2]int{1, 1}, false, "word", 1)
+	fitted.Fit([]string{"sign in", "sign up"})
+
+	cv := NewCountVectorizer([2]int{1, 1}, false, "word", 1)
+	cv.SetTerms(fitted.Names())
+	if cv.Vocabulary != nil || cv.VocabSize() != 3 {
+		t.Fatalf("SetTerms: vocabulary %v, size %d; want an unbuilt index of 3 terms", cv.Vocabulary, cv.VocabSize())
+	}
+	got, want := cv.Transform("sign in sign"), fitted.Transform("sign in sign")
+	if !slices.Equal(got.ToDense(), want.ToDense()) {
+		t.Errorf("Transform = %v, want %v", got.ToDense(), want.ToDense())
+	}
+	if len(cv.Vocabulary) != 3 {
+		t.Errorf("vocabulary after use = %v", cv.Vocabulary)
+	}
+}
@@ -0,0 +1,143 @@
+// Package login turns a classified login form into a request template for
+// credential auditing tools: the method and resolved action, the username
+// and password parameters named by the field model, the static hidden
+// fields to send along, and which values must be fetched afresh from the
+// login page before each attempt.
+package login
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/happyhackingspace/dit"
+	"github.com/happyhackingspace/dit/endpoint"
+)
+
+// ErrNoLoginForm is returned when no form has a password field.
+var ErrNoLoginForm = errors.New("login: no login form found")
+
+// Param is a hidden request parameter. Kind is set for values that change
+// between requests; Value is then the one seen when the page was
+// classified.
+type Param struct {
+	Name  string              `json:"name"`
+	Value string              `json:"value"`
+	Kind  endpoint.HiddenKind `json:"kind,omitempty"`
+}
+
+// Refresh tells how to get the current value of a dynamic parameter:
+// fetch Template.Page and read the value of the input at Selector.
+type Refresh struct {
+	Name     string              `json:"name"`
+	Kind     endpoint.HiddenKind `json:"kind"`
+	Selector string              `json:"selector"`
+}
+
+// Template is a login request: the credentials followed by Params, the
+// hidden fields of the form in document order. Refresh lists the
+// parameters to fetch afresh before each attempt.
+type Template struct {
+	FormType      string    `json:"form_type,omitempty"` // class of the form, "login" unless a fallback
+	Method        string    `json:"method"`
+	URL           string    `json:"url"`
+	Enctype       string    `json:"enctype"`
+	Page          string    `json:"page,omitempty"` // page to fetch refresh values from
+	UsernameParam string    `json:"username_param,omitempty"`
+	PasswordParam string    `json:"password_param"`
+	Params        []Param   `json:"params,omitempty"`
+	Refresh       []Refresh `json:"refresh,omitempty"`
+	CrossOrigin   bool      `json:"cross_origin,omitempty"`
+	Insecure      bool      `json:"insecure,omitempty"`
+}
+
+// usernameTypes are the field types usable as the login name, most
+// specific first.
+var usernameTypes = []string{"username or email", "username", "email"}
+
+// Find returns the template of the first form classified as a login form,
+// falling back to the first form with a password field. pageURL is the
+// page the forms were found on.
+func Find(forms []dit.FormResult, pageURL string) (*Template, error) {
+	var fallback *Template
+	for _, f := range forms {
+		t, err := FromForm(f, pageURL)
+		if err != nil {
+			continue
+		}
+		if f.Type == "login" {
+			return t, nil
+		}
+		if fallback == nil {
+			fallback = t
+		}
+	}
+	if fallback == nil {
+		return nil, ErrNoLoginForm
+	}
+	return fallback, nil
+}
+
+// FromForm returns the template of f. It fails with ErrNoLoginForm if f
+// has no password field.
+func FromForm(f dit.FormResult, pageURL string) (*Template, error) {
+	if f.Virtual {
+		return nil, fmt.Errorf("%w: virtual forms are submitted by script", ErrNoLoginForm)
+	}
+	password := fieldOfType(f.FieldDetails, "password")
+	if password == nil {
+		i := slices.IndexFunc(f.FieldDetails, func(d dit.FieldResult) bool { return d.InputType == "password" && d.Name != "" })
+		if i < 0 {
+			return nil, ErrNoLoginForm
+		}
+		password = &f.FieldDetails[i]
+	}
+
+	t := &Template{
+		FormType:      f.Type,
+		Method:        f.Method,
+		URL:           f.Action,
+		Enctype:       f.Enctype,
+		Page:          pageURL,
+		PasswordParam: password.Name,
+		CrossOrigin:   f.CrossOrigin,
+		Insecure:      f.Insecure,
+	}
+	for _, typ := range usernameTypes {
+		if u := fieldOfType(f.FieldDetails, typ); u != nil {
+			t.UsernameParam = u.Name
+			break
+		}
+	}
+	for _, h := range f.HiddenFields {
+		t.Params = append(t.Params, Param{Name: h.Name, Value: h.Value, Kind: h.Kind})
+		if h.Kind != "" {
+			t.Refresh = append(t.Refresh, Refresh{
+				Name:     h.Name,
+				Kind:     h.Kind,
+				Selector: fmt.Sprintf(`input[name=%q]`, h.Name),
+			})
+		}
+	}
+	return t, nil
+}
+
+func fieldOfType(fields []dit.FieldResult, typ string) *dit.FieldResult {
+	for i := range fields {
+		if fields[i].Type == typ && fields[i].Name != "" {
+			return &fields[i]
+		}
+	}
+	return nil
+}
+
+// values returns the request parameters: the credentials, replaced by the
+// given placeholders, followed by the hidden fields.
+func (t *Template) values(user, pass string) []Param {
+	out := make([]Param, 0, len(t.Params)+2)
+	if t.UsernameParam != "" {
+		out = append(out, Param{Name: t.UsernameParam, Value: user})
+	}
+	out = append(out, Param{Name: t.PasswordParam, Value: pass})
+	return append(out, t.Params...)
+}
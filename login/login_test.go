@@ -0,0 +1,138 @@
+package login_test
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/happyhackingspace/dit"
+	"github.com/happyhackingspace/dit/endpoint"
+	"github.com/happyhackingspace/dit/login"
+)
+
+func loginForm() dit.FormResult {
+	return dit.FormResult{
+		Type: "login",
+		FieldDetails: []dit.FieldResult{
+			{Name: "login", InputType: "text", Type: "username or email"},
+			{Name: "password", InputType: "password", Type: "password"},
+		},
+		FormEndpoint: dit.FormEndpoint{
+			Action:  "https://example.com/session",
+			Method:  "POST",
+			Enctype: endpoint.EnctypeURLEncoded,
+			HiddenFields: []endpoint.HiddenField{
+				{Name: "authenticity_token", Value: "a+b", Kind: endpoint.KindCSRF, Confidence: 0.95},
+				{Name: "return_to", Value: "/home"},
+			},
+		},
+	}
+}
+
+func TestFind(t *testing.T) {
+	search := dit.FormResult{Type: "search", FieldDetails: []dit.FieldResult{{Name: "q", Type: "search query"}}}
+	tmpl, err := login.Find([]dit.FormResult{search, loginForm()}, "https://example.com/login")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tmpl.UsernameParam != "login" || tmpl.PasswordParam != "password" || tmpl.URL != "https://example.com/session" {
+		t.Errorf("template = %+v", tmpl)
+	}
+	if len(tmpl.Params) != 2 || tmpl.Params[1] != (login.Param{Name: "return_to", Value: "/home"}) {
+		t.Errorf("params = %+v", tmpl.Params)
+	}
+	if len(tmpl.Refresh) != 1 || tmpl.Refresh[0].Selector != `input[name="authenticity_token"]` {
+		t.Errorf("refresh params = %+v", tmpl.Refresh)
+	}
+
+	if _, err := login.Find([]dit.FormResult{search}, ""); !errors.Is(err, login.ErrNoLoginForm) {
+		t.Errorf("Find without a password field: err = %v, want ErrNoLoginForm", err)
+	}
+}
+
+func TestRender(t *testing.T) {
+	tmpl, err := login.FromForm(loginForm(), "https://example.com/login")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		format login.Format
+		want   []string
+	}{
+		{login.FormatRaw, []string{
+			"POST /session HTTP/1.1\r\nHost: example.com\r\n",
+			"login={{username}}&password={{password}}&authenticity_token=a%2Bb&return_to=%2Fhome",
+		}},
+		{login.FormatCurl, []string{"curl -i -X POST 'https://example.com/session'", "--data-raw 'login={{username}}&password={{password}}"}},
+		{login.FormatFFUF, []string{"-w users.txt:USER", "-d 'login=USER&password=PASS&"}},
+		{login.FormatHydra, []string{"hydra -L users.txt -P passwords.txt example.com https-post-form '/session:login=^USER^&password=^PASS^&", `:F=name="password"'`}},
+	}
+	for _, tt := range tests {
+		out, err := tmpl.Render(tt.format)
+		if err != nil {
+			t.Fatalf("Render(%s): %v", tt.format, err)
+		}
+		for _, w := range tt.want {
+			if !strings.Contains(out, w) {
+				t.Errorf("Render(%s) = %q, missing %q", tt.format, out, w)
+			}
+		}
+	}
+
+	out, err := tmpl.Render(login.FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded login.Template
+	if err := json.Unmarshal([]byte(out), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := tmpl.Render(login.FormatRaw)
+	if again, _ := decoded.Render(login.FormatRaw); again != raw {
+		t.Errorf("raw request from decoded JSON = %q, want %q", again, raw)
+	}
+
+	tmpl.URL = "/session"
+	if _, err := tmpl.Render(login.FormatCurl); err == nil {
+		t.Error("expected an error for a relative action URL")
+	}
+}
+
+func TestRenderActionQuery(t *testing.T) {
+	form := loginForm()
+	form.FormEndpoint.Action = "https://example.com/login.aspx?ReturnUrl=%2Fadmin"
+	tmpl, err := login.FromForm(form, "https://example.com/login.aspx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := tmpl.Render(login.FormatHydra)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "https-post-form '/login.aspx?ReturnUrl=%2Fadmin:login=^USER^"; !strings.Contains(out, want) {
+		t.Errorf("hydra = %q, missing %q", out, want)
+	}
+}
+
+func TestRenderPlain(t *testing.T) {
+	form := loginForm()
+	form.FormEndpoint.Enctype = endpoint.EnctypePlain
+	tmpl, err := login.FromForm(form, "https://example.com/login")
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := tmpl.Render(login.FormatRaw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := "login={{username}}\r\npassword={{password}}\r\nauthenticity_token=a+b\r\nreturn_to=/home\r\n"
+	if !strings.Contains(raw, "Content-Type: text/plain\r\n") || !strings.HasSuffix(raw, "\r\n\r\n"+body) {
+		t.Errorf("raw = %q, want a text/plain body %q", raw, body)
+	}
+	for _, format := range []login.Format{login.FormatFFUF, login.FormatHydra} {
+		if _, err := tmpl.Render(format); err == nil {
+			t.Errorf("Render(%s): expected an error for a text/plain form", format)
+		}
+	}
+}
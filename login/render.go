@@ -0,0 +1,218 @@
+package login
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/url"
+	"strings"
+
+	"github.com/happyhackingspace/dit/endpoint"
+)
+
+// Format is an output format of Render.
+type Format string
+
+// Output formats.
+const (
+	FormatJSON  Format = "json"  // the Template as JSON
+	FormatRaw   Format = "raw"   // raw HTTP/1.1 request
+	FormatCurl  Format = "curl"  // curl command line
+	FormatFFUF  Format = "ffuf"  // ffuf command line with USER and PASS wordlist keywords
+	FormatHydra Format = "hydra" // hydra command line with ^USER^ and ^PASS^
+)
+
+// Formats lists the supported output formats.
+var Formats = []Format{FormatJSON, FormatRaw, FormatCurl, FormatFFUF, FormatHydra}
+
+// Credential placeholders of each format.
+var placeholders = map[Format][2]string{
+	FormatRaw:   {"{{username}}", "{{password}}"},
+	FormatCurl:  {"{{username}}", "{{password}}"},
+	FormatFFUF:  {"USER", "PASS"},
+	FormatHydra: {"^USER^", "^PASS^"},
+}
+
+// multipartBoundary is the fixed boundary of multipart raw requests, so
+// that the output is reproducible.
+const multipartBoundary = "----ditFormBoundary7MA4YWxkTrZu0gW"
+
+// Render writes t in the given format. All formats but JSON need an
+// absolute action URL, which requires the page URL to be known.
+func (t *Template) Render(format Format) (string, error) {
+	if format == FormatJSON {
+		data, err := json.MarshalIndent(t, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("login: %w", err)
+		}
+		return string(data), nil
+	}
+	ph, ok := placeholders[format]
+	if !ok {
+		return "", fmt.Errorf("login: unknown format %q", format)
+	}
+	u, err := url.Parse(t.URL)
+	if err != nil || !u.IsAbs() {
+		return "", fmt.Errorf("login: action URL %q is not absolute; the page URL is needed", t.URL)
+	}
+	params := t.values(ph[0], ph[1])
+	multipartBody := t.Method == "POST" && t.Enctype == endpoint.EnctypeMultipart
+	plainBody := t.Method == "POST" && t.Enctype == endpoint.EnctypePlain
+	if multipartBody && (format == FormatFFUF || format == FormatHydra) {
+		return "", fmt.Errorf("login: %s does not support multipart forms", format)
+	}
+	if plainBody && (format == FormatFFUF || format == FormatHydra) {
+		return "", fmt.Errorf("login: %s does not support text/plain forms", format)
+	}
+
+	switch format {
+	case FormatRaw:
+		return t.raw(u, params, multipartBody, plainBody)
+	case FormatCurl:
+		return t.curl(u, params, multipartBody, plainBody), nil
+	case FormatFFUF:
+		return t.ffuf(u, params), nil
+	default:
+		return t.hydra(u, params), nil
+	}
+}
+
+// encode URL-encodes params, keeping the credential placeholders of
+// format literal so that tools can substitute them.
+func encode(params []Param, keep ...string) string {
+	parts := make([]string, len(params))
+	for i, p := range params {
+		v := url.QueryEscape(p.Value)
+		for _, k := range keep {
+			if p.Value == k {
+				v = k
+			}
+		}
+		parts[i] = url.QueryEscape(p.Name) + "=" + v
+	}
+	return strings.Join(parts, "&")
+}
+
+// encodePlain encodes params as a text/plain form body: each name=value
+// pair followed by CRLF, without escaping.
+func encodePlain(params []Param) string {
+	var b strings.Builder
+	for _, p := range params {
+		b.WriteString(p.Name + "=" + p.Value + "\r\n")
+	}
+	return b.String()
+}
+
+// withQuery returns u with params as its query, for GET forms.
+func withQuery(u *url.URL, query string) string {
+	v := *u
+	v.RawQuery = query
+	v.Fragment = ""
+	return v.String()
+}
+
+func (t *Template) raw(u *url.URL, params []Param, multipartBody, plainBody bool) (string, error) {
+	ph := placeholders[FormatRaw]
+	var b strings.Builder
+	if t.Method != "POST" {
+		target := withQuery(&url.URL{Path: u.EscapedPath()}, encode(params, ph[0], ph[1]))
+		if target == "" {
+			target = "/"
+		}
+		fmt.Fprintf(&b, "%s %s HTTP/1.1\r\nHost: %s\r\n\r\n", t.Method, target, u.Host)
+		return b.String(), nil
+	}
+
+	var body, contentType string
+	if multipartBody {
+		var mb strings.Builder
+		w := multipart.NewWriter(&mb)
+		if err := w.SetBoundary(multipartBoundary); err != nil {
+			return "", fmt.Errorf("login: %w", err)
+		}
+		for _, p := range params {
+			if err := w.WriteField(p.Name, p.Value); err != nil {
+				return "", fmt.Errorf("login: %w", err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			return "", fmt.Errorf("login: %w", err)
+		}
+		body, contentType = mb.String(), w.FormDataContentType()
+	} else if plainBody {
+		body, contentType = encodePlain(params), t.Enctype
+	} else {
+		body, contentType = encode(params, ph[0], ph[1]), t.Enctype
+	}
+	path := u.RequestURI()
+	fmt.Fprintf(&b, "POST %s HTTP/1.1\r\nHost: %s\r\nContent-Type: %s\r\nContent-Length: %d\r\n\r\n%s",
+		path, u.Host, contentType, len(body), body)
+	return b.String(), nil
+}
+
+func (t *Template) curl(u *url.URL, params []Param, multipartBody, plainBody bool) string {
+	ph := placeholders[FormatCurl]
+	if t.Method != "POST" {
+		return "curl -i " + shellQuote(withQuery(u, encode(params, ph[0], ph[1])))
+	}
+	args := []string{"curl", "-i", "-X", "POST", shellQuote(u.String())}
+	if multipartBody {
+		for _, p := range params {
+			args = append(args, "-F", shellQuote(p.Name+"="+p.Value))
+		}
+	} else if plainBody {
+		args = append(args, "-H", shellQuote("Content-Type: "+t.Enctype), "--data-binary", shellQuote(encodePlain(params)))
+	} else {
+		args = append(args, "-H", shellQuote("Content-Type: "+t.Enctype), "--data-raw", shellQuote(encode(params, ph[0], ph[1])))
+	}
+	return strings.Join(args, " ")
+}
+
+func (t *Template) ffuf(u *url.URL, params []Param) string {
+	ph := placeholders[FormatFFUF]
+	args := []string{"ffuf", "-w", "users.txt:" + ph[0], "-w", "passwords.txt:" + ph[1], "-mode", "clusterbomb"}
+	if t.Method != "POST" {
+		args = append(args, "-u", shellQuote(withQuery(u, encode(params, ph[0], ph[1]))))
+	} else {
+		args = append(args, "-u", shellQuote(u.String()), "-X", "POST",
+			"-H", shellQuote("Content-Type: "+t.Enctype), "-d", shellQuote(encode(params, ph[0], ph[1])))
+	}
+	return strings.Join(args, " ")
+}
+
+// hydra renders an http(s)-{get,post}-form command. The failure condition
+// is the password field reappearing in the response, i.e. the login form
+// being shown again.
+func (t *Template) hydra(u *url.URL, params []Param) string {
+	ph := placeholders[FormatHydra]
+	module := "http"
+	if u.Scheme == "https" {
+		module = "https"
+	}
+	if t.Method == "POST" {
+		module += "-post-form"
+	} else {
+		module += "-get-form"
+	}
+	// POST actions keep their query string (e.g. a return URL); a GET form
+	// replaces it with the form data, as browsers do.
+	target := u.RequestURI()
+	if t.Method != "POST" {
+		target = (&url.URL{Path: u.Path, RawPath: u.RawPath}).RequestURI()
+	}
+	// Colons separate the fields of the hydra form specification.
+	escape := strings.NewReplacer(":", `\:`)
+	spec := escape.Replace(target) + ":" + escape.Replace(encode(params, ph[0], ph[1])) +
+		":F=" + escape.Replace(`name="`+t.PasswordParam+`"`)
+	args := []string{"hydra", "-L", "users.txt", "-P", "passwords.txt"}
+	if p := u.Port(); p != "" {
+		args = append(args, "-s", p)
+	}
+	args = append(args, u.Hostname(), module, shellQuote(spec))
+	return strings.Join(args, " ")
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,176 @@
+// Package payment finds where a page collects payment card data: in hosted
+// fields, iframes and checkout pages of a payment provider, which keep card
+// numbers off the page, or in card inputs of the page itself, which bring
+// it into PCI DSS scope.
+package payment
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/happyhackingspace/dit/internal/htmlutil"
+)
+
+// Provider identifies a payment provider.
+type Provider string
+
+// Kind is how a hosted payment integration appears on the page.
+type Kind string
+
+// Hosted integration kinds.
+const (
+	KindIframe Kind = "iframe" // card fields rendered in a provider iframe
+	KindScript Kind = "script" // provider SDK that mounts hosted fields
+	KindForm   Kind = "form"   // form posting to a provider-hosted payment page
+	KindWidget Kind = "widget" // container marked up for a provider's hosted fields
+)
+
+// Hosted is a provider integration that collects card data outside the page.
+type Hosted struct {
+	Provider Provider `json:"provider"`
+	Kind     Kind     `json:"kind"`
+	URL      string   `json:"url,omitempty"`
+	Selector string   `json:"selector,omitempty"`
+}
+
+// CardField is an input of the page itself that asks for card data. Kind
+// is one of the htmlutil card field kinds: number, expiry, expiry-month,
+// expiry-year, cvc, name or iban.
+type CardField struct {
+	Kind     string `json:"kind"`
+	Name     string `json:"name,omitempty"`
+	Selector string `json:"selector"`
+}
+
+// Result lists the hosted integrations and direct card fields of a page.
+type Result struct {
+	Hosted     []Hosted    `json:"hosted,omitempty"`
+	CardFields []CardField `json:"card_fields,omitempty"`
+}
+
+// CollectsCardData reports whether the page itself takes a card number or
+// security code, as opposed to delegating them to a hosted integration.
+func (r *Result) CollectsCardData() bool {
+	if r == nil {
+		return false
+	}
+	for _, f := range r.CardFields {
+		if f.Kind == htmlutil.CardNumber || f.Kind == htmlutil.CardCVC {
+			return true
+		}
+	}
+	return false
+}
+
+// AddCardField adds f unless a field with the same selector is listed.
+func (r *Result) AddCardField(f CardField) {
+	for _, g := range r.CardFields {
+		if g.Selector == f.Selector {
+			return
+		}
+	}
+	r.CardFields = append(r.CardFields, f)
+}
+
+// Detect returns the hosted payment integrations and card inputs of doc,
+// or nil if it has neither. pageURL, if set, resolves relative URLs.
+func Detect(doc *goquery.Document, pageURL string) *Result {
+	if doc == nil {
+		return nil
+	}
+	base := htmlutil.BaseURL(doc, pageURL)
+	r := &Result{}
+	var ids htmlutil.IDCounts // counted on the first match
+	path := func(s *goquery.Selection) string {
+		if ids == nil {
+			ids = htmlutil.CountIDs(doc.Selection)
+		}
+		return ids.CSSPath(s)
+	}
+	seen := make(map[string]bool)
+	add := func(h Hosted) {
+		key := string(h.Provider) + "|" + string(h.Kind)
+		if !seen[key] {
+			seen[key] = true
+			r.Hosted = append(r.Hosted, h)
+		}
+	}
+
+	sources := []struct {
+		kind     Kind
+		selector string
+		attr     string
+	}{
+		{KindIframe, "iframe[src]", "src"},
+		{KindScript, "script[src]", "src"},
+		{KindForm, "form[action]", "action"},
+	}
+	for _, src := range sources {
+		doc.Find(src.selector).Each(func(_ int, s *goquery.Selection) {
+			raw := strings.TrimSpace(s.AttrOr(src.attr, ""))
+			if p := providerFor(src.kind, strings.ToLower(raw)); p != "" {
+				add(Hosted{Provider: p, Kind: src.kind, URL: htmlutil.ResolveURL(base, raw), Selector: path(s)})
+			}
+		})
+	}
+	for _, p := range providers {
+		if p.widget == "" {
+			continue
+		}
+		if s := doc.Find(p.widget).First(); s.Length() > 0 {
+			add(Hosted{Provider: p.name, Kind: KindWidget, Selector: path(s)})
+		}
+	}
+
+	doc.Find("input, select").Each(func(_ int, s *goquery.Selection) {
+		if kind := htmlutil.CardFieldKind(s); kind != "" {
+			r.AddCardField(CardField{Kind: kind, Name: s.AttrOr("name", ""), Selector: path(s)})
+		}
+	})
+
+	if len(r.Hosted) == 0 && len(r.CardFields) == 0 {
+		return nil
+	}
+	return r
+}
+
+// providerFor returns the provider whose URL patterns for kind match the
+// lowercased URL u, or "".
+func providerFor(kind Kind, u string) Provider {
+	if u == "" {
+		return ""
+	}
+	if parsed, err := url.Parse(u); err == nil && parsed.Host == "" && !strings.HasPrefix(u, "//") {
+		return "" // same-site URL
+	}
+	for _, p := range compiledProviders() {
+		for _, re := range p.urls[kind] {
+			if re.MatchString(u) {
+				return p.name
+			}
+		}
+	}
+	return ""
+}
+
+type compiledProvider struct {
+	name Provider
+	urls map[Kind][]*regexp.Regexp
+}
+
+var compiledProviders = sync.OnceValue(func() []compiledProvider {
+	out := make([]compiledProvider, len(providers))
+	for i, p := range providers {
+		c := compiledProvider{name: p.name, urls: make(map[Kind][]*regexp.Regexp)}
+		for kind, exprs := range map[Kind][]string{KindIframe: p.iframes, KindScript: p.scripts, KindForm: p.forms} {
+			for _, e := range exprs {
+				c.urls[kind] = append(c.urls[kind], regexp.MustCompile(e))
+			}
+		}
+		out[i] = c
+	}
+	return out
+})
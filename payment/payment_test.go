@@ -0,0 +1,83 @@
+package payment_test
+
+import (
+	"testing"
+
+	"github.com/happyhackingspace/dit/internal/htmlutil"
+	"github.com/happyhackingspace/dit/payment"
+)
+
+func TestDetectHosted(t *testing.T) {
+	doc, err := htmlutil.LoadHTMLString(`<html><head>
+<script src="https://js.stripe.com/v3/"></script>
+<script src="/assets/app.js"></script>
+</head><body>
+<form id="checkout" action="/pay">
+  <input name="email" autocomplete="email">
+  <div id="card-element" class="StripeElement">
+    <iframe src="https://js.stripe.com/v3/elements-inner-card-1234.html#locale=en"></iframe>
+  </div>
+  <button>Pay</button>
+</form>
+<iframe src="https://js.stripe.com/v3/m-outer-93afeeb17bc37e711759584dbfc50d47.html"></iframe>
+</body></html>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := payment.Detect(doc, "https://shop.example.com/checkout")
+	if r == nil {
+		t.Fatal("expected a result")
+	}
+	kinds := make(map[payment.Kind]bool)
+	for _, h := range r.Hosted {
+		if h.Provider != payment.ProviderStripe {
+			t.Errorf("unexpected provider %q", h.Provider)
+		}
+		kinds[h.Kind] = true
+	}
+	for _, k := range []payment.Kind{payment.KindIframe, payment.KindScript, payment.KindWidget} {
+		if !kinds[k] {
+			t.Errorf("missing %s integration in %+v", k, r.Hosted)
+		}
+	}
+	if r.CollectsCardData() {
+		t.Errorf("hosted fields reported as direct card collection: %+v", r.CardFields)
+	}
+}
+
+func TestDetectCardFields(t *testing.T) {
+	doc, err := htmlutil.LoadHTMLString(`<form action="/pay" method="post">
+<input name="cc_number" inputmode="numeric" maxlength="19">
+<select name="exp_month"></select><select name="exp_year"></select>
+<input name="cvv" maxlength="4">
+<input name="billing_zip">
+</form>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := payment.Detect(doc, "")
+	if !r.CollectsCardData() {
+		t.Fatalf("expected direct card collection, got %+v", r)
+	}
+	if len(r.Hosted) != 0 {
+		t.Errorf("unexpected hosted integrations: %+v", r.Hosted)
+	}
+	got := make(map[string]string)
+	for _, f := range r.CardFields {
+		got[f.Name] = f.Kind
+	}
+	want := map[string]string{"cc_number": "number", "exp_month": "expiry-month", "exp_year": "expiry-year", "cvv": "cvc"}
+	for name, kind := range want {
+		if got[name] != kind {
+			t.Errorf("field %s: kind %q, want %q", name, got[name], kind)
+		}
+	}
+
+	plain, err := htmlutil.LoadHTMLString(`<form><input name="q"></form>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := payment.Detect(plain, ""); r != nil {
+		t.Errorf("expected nil for a page without payment, got %+v", r)
+	}
+}
@@ -0,0 +1,102 @@
+package payment
+
+// Known providers.
+const (
+	ProviderStripe       Provider = "stripe"
+	ProviderBraintree    Provider = "braintree"
+	ProviderAdyen        Provider = "adyen"
+	ProviderCheckoutCom  Provider = "checkout.com"
+	ProviderSquare       Provider = "square"
+	ProviderPayPal       Provider = "paypal"
+	ProviderWorldpay     Provider = "worldpay"
+	ProviderCyberSource  Provider = "cybersource"
+	ProviderAuthorizeNet Provider = "authorize.net"
+	ProviderMollie       Provider = "mollie"
+	ProviderRecurly      Provider = "recurly"
+	ProviderSpreedly     Provider = "spreedly"
+)
+
+// provider lists the URL patterns of a provider's hosted integrations.
+// Patterns are regular expressions matched against lowercased URLs; widget
+// is a CSS selector for containers its SDK mounts hosted fields into.
+type provider struct {
+	name    Provider
+	iframes []string
+	scripts []string
+	forms   []string
+	widget  string
+}
+
+var providers = []provider{
+	{
+		name:    ProviderStripe,
+		iframes: []string{`//js\.stripe\.com/v\d/elements-inner`, `//checkout\.stripe\.com/`},
+		scripts: []string{`//js\.stripe\.com/`},
+		forms:   []string{`//checkout\.stripe\.com/`, `//buy\.stripe\.com/`},
+		widget:  `.StripeElement`,
+	},
+	{
+		name:    ProviderBraintree,
+		iframes: []string{`//assets\.braintreegateway\.com/web/.*hosted-fields`},
+		scripts: []string{`//js\.braintreegateway\.com/`},
+		widget:  `[class*=braintree-hosted-fields], .braintree-dropin`,
+	},
+	{
+		name:    ProviderAdyen,
+		iframes: []string{`//checkoutshopper-[a-z-]+\.adyen\.com/.*securedfields`},
+		scripts: []string{`//checkoutshopper-[a-z-]+\.adyen\.com/`},
+		forms:   []string{`//(live|test)\.adyen\.com/hpp/`},
+		widget:  `.adyen-checkout__card-input, [data-cse=encryptedCardNumber]`,
+	},
+	{
+		name:    ProviderCheckoutCom,
+		iframes: []string{`//[a-z-]*frames?\.checkout\.com/`},
+		scripts: []string{`//cdn\.checkout\.com/js/frames`, `//checkout-web-components\.checkout\.com/`},
+		widget:  `.card-frame.frame--activated`,
+	},
+	{
+		name:    ProviderSquare,
+		iframes: []string{`//web\.squarecdn\.com/.*/single-card-element-iframe`, `//pci-connect\.squareup(sandbox)?\.com/`},
+		scripts: []string{`//(sandbox\.)?web\.squarecdn\.com/`, `//js\.squareup(sandbox)?\.com/`},
+	},
+	{
+		name:    ProviderPayPal,
+		iframes: []string{`//www\.paypal\.com/(smart/buttons|smart/card-fields|webapps/hermes)`},
+		scripts: []string{`//www\.paypal(objects)?\.com/sdk/js`, `//www\.paypalobjects\.com/api/checkout`},
+		forms:   []string{`//(www\.)?(sandbox\.)?paypal\.com/cgi-bin/webscr`},
+	},
+	{
+		name:    ProviderWorldpay,
+		iframes: []string{`//(try\.)?access\.worldpay\.com/`, `//secure(-test)?\.worldpay\.com/`},
+		scripts: []string{`//(try\.)?access\.worldpay\.com/access-checkout/`, `//cdn\.worldpay\.com/`},
+		forms:   []string{`//secure(-test)?\.worldpay\.com/wcc/purchase`},
+	},
+	{
+		name:    ProviderCyberSource,
+		iframes: []string{`//(test)?flex\.cybersource\.com/`},
+		scripts: []string{`//(test)?flex\.cybersource\.com/`},
+		forms:   []string{`//(test)?secureacceptance\.cybersource\.com/`},
+	},
+	{
+		name:    ProviderAuthorizeNet,
+		iframes: []string{`//(test\.)?authorize\.net/payment/payment`, `//accept(test)?\.authorize\.net/`},
+		scripts: []string{`//js(test)?\.authorize\.net/`},
+		forms:   []string{`//(test\.)?authorize\.net/payment/payment`, `//accept(test)?\.authorize\.net/`},
+	},
+	{
+		name:    ProviderMollie,
+		iframes: []string{`//js\.mollie\.com/`},
+		scripts: []string{`//js\.mollie\.com/`},
+	},
+	{
+		name:    ProviderRecurly,
+		iframes: []string{`//api\.recurly\.com/js/v\d/field`},
+		scripts: []string{`//js\.recurly\.com/`},
+		widget:  `[data-recurly]`,
+	},
+	{
+		name:    ProviderSpreedly,
+		iframes: []string{`//core\.spreedly\.com/`},
+		scripts: []string{`//core\.spreedly\.com/iframe/`},
+	},
+}
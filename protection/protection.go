@@ -0,0 +1,154 @@
+// Package protection fingerprints the WAF, CDN or bot-management vendor
+// in front of a page from its block page text, title, asset URLs, response
+// headers and cookies.
+package protection
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/happyhackingspace/dit/internal/respmatch"
+)
+
+// Vendor identifies a protection vendor.
+type Vendor string
+
+// Location is where a piece of evidence was found.
+type Location string
+
+// Evidence locations.
+const (
+	LocationTitle  Location = "title"  // <title> of the page
+	LocationBody   Location = "body"   // visible page text
+	LocationAsset  Location = "asset"  // script, stylesheet, image, iframe or form URL
+	LocationHeader Location = "header" // response header
+	LocationCookie Location = "cookie" // name of a cookie set by the response
+)
+
+// locationWeights is the confidence contributed by a single piece of
+// evidence from each location, combined with a noisy-OR.
+var locationWeights = map[Location]float64{
+	LocationTitle:  0.9,
+	LocationBody:   0.85,
+	LocationAsset:  0.7,
+	LocationHeader: 0.9,
+	LocationCookie: 0.8,
+}
+
+// Evidence is a single match supporting a vendor.
+type Evidence struct {
+	Location Location `json:"location"`
+	Value    string   `json:"value"`
+}
+
+// Result is a vendor identified by Detect. Block is set when the page
+// itself is the vendor's block or challenge page, as opposed to an ordinary
+// page served through it.
+type Result struct {
+	Vendor     Vendor     `json:"vendor"`
+	Confidence float64    `json:"confidence"` // 0..1
+	Block      bool       `json:"block"`
+	Evidence   []Evidence `json:"evidence"`
+}
+
+// Detect returns the vendors of the current rules found in doc and header,
+// most confident first. header may be nil when the response is unknown.
+func Detect(doc *goquery.Document, header http.Header) []Result {
+	p := page{header: header}
+	if doc != nil {
+		p.title = strings.ToLower(strings.TrimSpace(doc.Find("title").First().Text()))
+		p.body = strings.ToLower(strings.Join(strings.Fields(doc.Find("body").Text()), " "))
+		p.assets = assetURLs(doc)
+	}
+	if header != nil {
+		p.cookies = respmatch.CookieNames(header)
+	}
+
+	var results []Result
+	for _, s := range CurrentRules().compiled {
+		if r, ok := s.match(&p); ok {
+			results = append(results, r)
+		}
+	}
+	slices.SortStableFunc(results, func(a, b Result) int {
+		return cmp.Compare(b.Confidence, a.Confidence)
+	})
+	return results
+}
+
+// page holds the normalized inputs matched by the rules.
+type page struct {
+	title   string
+	body    string
+	assets  []string
+	header  http.Header
+	cookies []string
+}
+
+// assetURLs returns the lowercased URLs referenced by the document.
+func assetURLs(doc *goquery.Document) []string {
+	var urls []string
+	doc.Find("script[src], link[href], img[src], iframe[src], form[action]").Each(func(_ int, s *goquery.Selection) {
+		for _, attr := range []string{"src", "href", "action"} {
+			if v, ok := s.Attr(attr); ok && v != "" {
+				urls = append(urls, strings.ToLower(v))
+			}
+		}
+	})
+	return urls
+}
+
+// match collects the evidence for c in p.
+func (c compiled) match(p *page) (Result, bool) {
+	r := Result{Vendor: c.vendor}
+	add := func(loc Location, value string) {
+		e := Evidence{Location: loc, Value: value}
+		if !slices.Contains(r.Evidence, e) {
+			r.Evidence = append(r.Evidence, e)
+		}
+	}
+
+	for _, re := range c.titles {
+		if m := re.FindString(p.title); m != "" {
+			add(LocationTitle, m)
+			r.Block = true
+		}
+	}
+	for _, re := range c.bodies {
+		if m := re.FindString(p.body); m != "" {
+			add(LocationBody, m)
+			r.Block = true
+		}
+	}
+	for _, re := range c.assets {
+		for _, u := range p.assets {
+			if re.MatchString(u) {
+				add(LocationAsset, u)
+				break
+			}
+		}
+	}
+	for _, hm := range c.headers {
+		if value, ok := hm.Match(p.header); ok {
+			add(LocationHeader, value)
+		}
+	}
+	for _, prefix := range c.cookies {
+		if name, ok := respmatch.MatchCookie(prefix, p.cookies); ok {
+			add(LocationCookie, name)
+		}
+	}
+
+	if len(r.Evidence) == 0 {
+		return Result{}, false
+	}
+	weights := make([]float64, len(r.Evidence))
+	for i, e := range r.Evidence {
+		weights[i] = locationWeights[e.Location]
+	}
+	r.Confidence = respmatch.NoisyOR(weights)
+	return r, true
+}
@@ -0,0 +1,172 @@
+package protection_test
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/happyhackingspace/dit/internal/htmlutil"
+	"github.com/happyhackingspace/dit/protection"
+)
+
+func TestDetectBlockPages(t *testing.T) {
+	tests := []struct {
+		name   string
+		html   string
+		header http.Header
+		want   protection.Vendor
+		block  bool
+	}{
+		{
+			name: "cloudflare",
+			html: `<html><head><title>Attention Required! | Cloudflare</title>
+<link rel="stylesheet" href="/cdn-cgi/styles/cf.errors.css"></head>
+<body><h1>Sorry, you have been blocked</h1><p>Cloudflare Ray ID: 8a1b2c3d4e5f</p></body></html>`,
+			want:  protection.VendorCloudflare,
+			block: true,
+		},
+		{
+			name: "akamai",
+			html: `<html><head><title>Access Denied</title></head><body><h1>Access Denied</h1>
+You don't have permission to access "http://www.example.com/" on this server.<p>
+Reference #18.2d351ab8.1557333295.a4e16ab</p></body></html>`,
+			want:  protection.VendorAkamai,
+			block: true,
+		},
+		{
+			name: "imperva",
+			html: `<html><body><iframe src="/_Incapsula_Resource?CWUDNSAI=9"></iframe>
+Request unsuccessful. Incapsula incident ID: 1234-5678</body></html>`,
+			want:  protection.VendorImperva,
+			block: true,
+		},
+		{
+			name: "f5",
+			html: `<html><head><title>Request Rejected</title></head><body>The requested URL was rejected.
+Please consult with your administrator.<br><br>Your support ID is: 1234567890</body></html>`,
+			want:  protection.VendorF5,
+			block: true,
+		},
+		{
+			name: "sucuri",
+			html: `<html><head><title>Sucuri WebSite Firewall - Access Denied</title></head>
+<body><h2>Access Denied - Sucuri Website Firewall</h2><p>Block ID: GEO01</p>
+<p>If you are the site owner, contact cloudproxy@sucuri.net.</p></body></html>`,
+			want:  protection.VendorSucuri,
+			block: true,
+		},
+		{
+			name:   "sucuri header",
+			html:   `<html><body>Welcome</body></html>`,
+			header: http.Header{"X-Sucuri-Id": {"17012"}},
+			want:   protection.VendorSucuri,
+		},
+		{
+			name:   "aws waf cookie",
+			html:   `<html><body>Welcome</body></html>`,
+			header: http.Header{"Set-Cookie": {"aws-waf-token=abc; Path=/"}},
+			want:   protection.VendorAWSWAF,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := htmlutil.LoadHTMLString(tt.html)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := protection.Detect(doc, tt.header)
+			if len(got) == 0 {
+				t.Fatalf("no vendor detected, want %s", tt.want)
+			}
+			if got[0].Vendor != tt.want {
+				t.Errorf("vendor = %s, want %s (%+v)", got[0].Vendor, tt.want, got)
+			}
+			if got[0].Block != tt.block {
+				t.Errorf("block = %v, want %v", got[0].Block, tt.block)
+			}
+			if len(got[0].Evidence) == 0 || got[0].Confidence <= 0 || got[0].Confidence > 1 {
+				t.Errorf("bad result %+v", got[0])
+			}
+		})
+	}
+}
+
+func TestDetectNoProtection(t *testing.T) {
+	doc, err := htmlutil.LoadHTMLString(`<html><head><title>Sign in</title></head>
+<body><form action="/login"><input name="user"><input type="password" name="pass"></form></body></html>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := protection.Detect(doc, http.Header{"Server": {"nginx"}}); len(got) != 0 {
+		t.Errorf("got %+v, want none", got)
+	}
+	if got := protection.Detect(nil, nil); len(got) != 0 {
+		t.Errorf("nil input: got %+v, want none", got)
+	}
+}
+
+func TestDetectVendorMentions(t *testing.T) {
+	// Pages about the vendors, or behind generic load balancers, are
+	// neither block pages nor evidence of the vendor.
+	doc, err := htmlutil.LoadHTMLString(`<html><head><title>Our security partners</title></head>
+<body><p>We protect our customers with Barracuda Networks, the Sucuri Website Firewall,
+FortiGate Application Control and Imperva.</p><p>Security powered by Imperva.</p>
+<p>This report was generated by Wordfence.</p></body></html>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := http.Header{"Set-Cookie": {"TS01a2b3c4=abc; Path=/", "cookiesession1=xyz; Path=/", "_pxyz=1; Path=/"}}
+	if got := protection.Detect(doc, header); len(got) != 0 {
+		t.Errorf("got %+v, want none", got)
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vendors.yaml")
+	if err := os.WriteFile(path, []byte(`providers:
+  - name: acmeshield
+    match:
+      title: ['^blocked by acme shield$']
+      header: ['x-acme-shield']
+  - name: sucuri
+    match:
+      cookie: [my_sucuri]
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	extra, err := protection.LoadRules(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	protection.SetRules(protection.DefaultRules().With(extra))
+	t.Cleanup(func() { protection.SetRules(nil) })
+
+	doc, err := htmlutil.LoadHTMLString(`<html><head><title>Blocked by Acme Shield</title></head><body></body></html>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := protection.Detect(doc, http.Header{"X-Acme-Shield": {"1"}})
+	if len(got) == 0 || got[0].Vendor != "acmeshield" || !got[0].Block || len(got[0].Evidence) != 2 {
+		t.Errorf("custom vendor: got %+v", got)
+	}
+
+	// The file replaced the built-in sucuri rule.
+	if got := protection.Detect(nil, http.Header{"X-Sucuri-Id": {"17012"}}); len(got) != 0 {
+		t.Errorf("replaced vendor matched a built-in header: %+v", got)
+	}
+	if got := protection.Detect(nil, http.Header{"Set-Cookie": {"my_sucuri=1"}}); len(got) != 1 || got[0].Vendor != protection.VendorSucuri {
+		t.Errorf("replaced vendor: got %+v", got)
+	}
+
+	for name, data := range map[string]string{
+		"unknown location": `{"providers": [{"name": "x", "match": {"titles": ["a"]}}]}`,
+		"bad regexp":       `{"providers": [{"name": "x", "match": {"body": ["("]}}]}`,
+		"bad header":       `{"providers": [{"name": "x", "match": {"header": ["server: ("]}}]}`,
+		"duplicate":        `{"providers": [{"name": "x"}, {"name": "x"}]}`,
+	} {
+		if _, err := protection.ParseRules([]byte(data)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package protection
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/happyhackingspace/dit/internal/respmatch"
+)
+
+//go:embed vendors.yaml
+var defaultRulesYAML []byte
+
+// Rules is a set of vendor signatures, in the format of the captcha rules.
+// See vendors.yaml for the built-in set and the meaning of each matcher
+// location.
+type Rules struct {
+	Providers []VendorRule `yaml:"providers" json:"providers"`
+
+	compiled []compiled
+}
+
+// VendorRule describes how to recognise one vendor.
+type VendorRule struct {
+	Name  string   `yaml:"name" json:"name"`
+	Match Matchers `yaml:"match" json:"match"`
+}
+
+// Matchers lists patterns by location. Title, body and asset patterns are
+// regular expressions matched against lowercased input; header entries are
+// a header name, optionally followed by ":" and a regular expression for
+// its value; cookie entries are cookie name prefixes.
+type Matchers struct {
+	Title  []string `yaml:"title,omitempty" json:"title,omitempty"`
+	Body   []string `yaml:"body,omitempty" json:"body,omitempty"`
+	Asset  []string `yaml:"asset,omitempty" json:"asset,omitempty"`
+	Header []string `yaml:"header,omitempty" json:"header,omitempty"`
+	Cookie []string `yaml:"cookie,omitempty" json:"cookie,omitempty"`
+}
+
+// compiled is a vendor rule with its patterns compiled.
+type compiled struct {
+	vendor  Vendor
+	titles  []*regexp.Regexp
+	bodies  []*regexp.Regexp
+	assets  []*regexp.Regexp
+	headers []respmatch.Header
+	cookies []string
+}
+
+var defaultRules = sync.OnceValue(func() *Rules {
+	r, err := ParseRules(defaultRulesYAML)
+	if err != nil {
+		panic("protection: invalid built-in rules: " + err.Error())
+	}
+	return r
+})
+
+// activeRules overrides the built-in rules when set by SetRules.
+var activeRules atomic.Pointer[Rules]
+
+// DefaultRules returns the built-in rules embedded in the binary.
+func DefaultRules() *Rules {
+	return defaultRules()
+}
+
+// SetRules replaces the rules used by Detect. Passing nil restores the
+// built-in rules.
+func SetRules(r *Rules) {
+	activeRules.Store(r)
+}
+
+// CurrentRules returns the rules in effect for Detect.
+func CurrentRules() *Rules {
+	if r := activeRules.Load(); r != nil {
+		return r
+	}
+	return DefaultRules()
+}
+
+// LoadRules reads rules from a YAML or JSON file.
+func LoadRules(path string) (*Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("load protection rules: %w", err)
+	}
+	r, err := ParseRules(data)
+	if err != nil {
+		return nil, fmt.Errorf("load protection rules %s: %w", path, err)
+	}
+	return r, nil
+}
+
+// ParseRules parses rules in YAML or JSON and compiles their patterns.
+// Unknown keys are rejected.
+func ParseRules(data []byte) (*Rules, error) {
+	var r Rules
+	if err := respmatch.Decode(data, &r); err != nil {
+		return nil, err
+	}
+	if err := r.compile(); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// With returns the rules of r extended by extra. Vendors in extra replace
+// those of r with the same name; new vendors are added.
+func (r *Rules) With(extra *Rules) *Rules {
+	out := &Rules{Providers: slices.Clone(r.Providers)}
+	for _, p := range extra.Providers {
+		if i := slices.IndexFunc(out.Providers, func(q VendorRule) bool { return q.Name == p.Name }); i >= 0 {
+			out.Providers[i] = p
+		} else {
+			out.Providers = append(out.Providers, p)
+		}
+	}
+	if err := out.compile(); err != nil {
+		// Both inputs compiled successfully, so their patterns are valid.
+		panic("protection: " + err.Error())
+	}
+	return out
+}
+
+// compile validates the vendors and compiles their patterns.
+func (r *Rules) compile() error {
+	seen := make(map[string]bool)
+	r.compiled = make([]compiled, 0, len(r.Providers))
+	for _, p := range r.Providers {
+		switch {
+		case p.Name == "":
+			return fmt.Errorf("provider without a name")
+		case seen[p.Name]:
+			return fmt.Errorf("duplicate provider %q", p.Name)
+		}
+		seen[p.Name] = true
+
+		c := compiled{vendor: Vendor(p.Name)}
+		var err error
+		if c.titles, err = compileAll(p.Name, "title", p.Match.Title); err != nil {
+			return err
+		}
+		if c.bodies, err = compileAll(p.Name, "body", p.Match.Body); err != nil {
+			return err
+		}
+		if c.assets, err = compileAll(p.Name, "asset", p.Match.Asset); err != nil {
+			return err
+		}
+		if c.headers, err = respmatch.ParseHeaders(p.Match.Header); err != nil {
+			return fmt.Errorf("provider %q: %w", p.Name, err)
+		}
+		for _, cookie := range p.Match.Cookie {
+			c.cookies = append(c.cookies, strings.ToLower(cookie))
+		}
+		r.compiled = append(r.compiled, c)
+	}
+	return nil
+}
+
+func compileAll(name, location string, exprs []string) ([]*regexp.Regexp, error) {
+	var out []*regexp.Regexp
+	for _, expr := range exprs {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("provider %q: %s pattern: %w", name, location, err)
+		}
+		out = append(out, re)
+	}
+	return out, nil
+}
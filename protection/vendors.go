@@ -0,0 +1,21 @@
+package protection
+
+// Known vendors of the built-in rules.
+const (
+	VendorCloudflare  Vendor = "cloudflare"
+	VendorAkamai      Vendor = "akamai"
+	VendorImperva     Vendor = "imperva"
+	VendorSucuri      Vendor = "sucuri"
+	VendorF5          Vendor = "f5"
+	VendorModSecurity Vendor = "modsecurity"
+	VendorAWSWAF      Vendor = "awswaf"
+	VendorAzure       Vendor = "azure"
+	VendorBarracuda   Vendor = "barracuda"
+	VendorFortiWeb    Vendor = "fortiweb"
+	VendorWordfence   Vendor = "wordfence"
+	VendorDataDome    Vendor = "datadome"
+	VendorPerimeterX  Vendor = "perimeterx"
+	VendorKasada      Vendor = "kasada"
+	VendorReblaze     Vendor = "reblaze"
+	VendorRadware     Vendor = "radware"
+)
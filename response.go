@@ -0,0 +1,83 @@
+package dit
+
+import (
+	"net/http"
+
+	"github.com/happyhackingspace/dit/captcha"
+	"github.com/happyhackingspace/dit/classifier"
+	"github.com/happyhackingspace/dit/protection"
+)
+
+// Response is the HTTP response metadata a page was served with: status
+// code and headers, including Set-Cookie. Passing it in Options lets
+// bot-protection providers be identified from headers and cookies as well
+// as from the HTML.
+type Response = captcha.Response
+
+// minBlockConfidence is the provider confidence from which a blocked
+// response overrides the page type model.
+const minBlockConfidence = 0.8
+
+// blockingStatus reports whether code is used by bot-protection services
+// for challenge and block pages.
+func blockingStatus(code int) bool {
+	switch code {
+	case http.StatusForbidden, http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	}
+	return false
+}
+
+// protectionAdjuster returns a page type hook that favours "captcha" (when
+// the page shows a CAPTCHA) or "waf_block" when resp is a block status
+// from a provider identified by its headers or cookies. It returns nil
+// when the response gives no such evidence.
+func protectionAdjuster(resp *Response, providers []CaptchaResult, pageCaptcha captcha.Detection) func(map[string]float64) {
+	if resp == nil || !blockingStatus(resp.StatusCode) {
+		return nil
+	}
+	conf := 0.0
+	for _, p := range providers {
+		if p.FromResponse() {
+			conf = max(conf, p.Confidence)
+		}
+	}
+	if conf < minBlockConfidence {
+		return nil
+	}
+	target := "waf_block"
+	if pageCaptcha.Found() {
+		target = "captcha"
+	}
+	return func(proba map[string]float64) { boostClass(proba, target, conf) }
+}
+
+// boostClass raises the probability of class to at least p, scaling the
+// other classes so that the distribution still sums to one. Models
+// without the class are left unchanged.
+func boostClass(proba map[string]float64, class string, p float64) {
+	cur, ok := proba[class]
+	if !ok || cur >= p {
+		return
+	}
+	rest := 1 - cur
+	for k, v := range proba {
+		if k != class && rest > 0 {
+			proba[k] = v * (1 - p) / rest
+		}
+	}
+	proba[class] = p
+}
+
+// detectProtection returns the most likely WAF vendor of the page, or nil.
+func detectProtection(doc *classifier.Document, resp *Response) *ProtectionResult {
+	var header http.Header
+	if resp != nil {
+		header = resp.Header
+	}
+	found := protection.Detect(doc.Doc, header)
+	if len(found) == 0 {
+		return nil
+	}
+	return &found[0]
+}
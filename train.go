@@ -1,10 +1,15 @@
 package dit
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"log/slog"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"time"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/happyhackingspace/dit/classifier"
@@ -16,12 +21,30 @@ import (
 // TrainConfig holds configuration for training.
 type TrainConfig struct {
 	Verbose bool
+	// EvalFolds, when positive, runs cross-validation with this many folds
+	// after training and records the scores in the model metadata.
+	EvalFolds int
+	// CalibrationFolds is the number of folds whose held-out predictions
+	// calibrate the form and page type probabilities (temperature
+	// scaling). Zero means DefaultCalibrationFolds; negative disables
+	// calibration.
+	CalibrationFolds int
+	// MultiLabel also trains a one-vs-rest page model that predicts every
+	// type of a page (PageResult.Types), from the page_types of the page
+	// index.
+	MultiLabel bool
 }
 
+// DefaultCalibrationFolds is the number of calibration folds used by Train
+// when TrainConfig.CalibrationFolds is zero.
+const DefaultCalibrationFolds = 5
+
 // EvalConfig holds configuration for evaluation.
 type EvalConfig struct {
 	Folds   int
 	Verbose bool
+	// MultiLabel also evaluates the one-vs-rest page model.
+	MultiLabel bool
 }
 
 // EvalResult holds cross-validation evaluation results.
@@ -46,13 +69,34 @@ type EvalResult struct {
 	PageF1         map[string]float64
 	PageMacroF1    float64
 	PageWeightedF1 float64
+	// Per-class field metrics
+	FieldConfusion map[string]map[string]int
+	FieldClasses   []string
+	FieldPrecision map[string]float64
+	FieldRecall    map[string]float64
+	FieldF1        map[string]float64
+	FieldMacroF1   float64
+	// Multi-label page metrics, over the sets of types of each page
+	PageMultiLabelTotal   int
+	PageMultiLabelClasses []string
+	PageMultiLabelF1      map[string]float64
+	PageMultiLabelMicroF1 float64
+	PageMultiLabelMacroF1 float64
 }
 
 // Train trains a classifier on annotated HTML forms in the given data directory.
 func Train(dataDir string, config *TrainConfig) (*Classifier, error) {
 	verbose := false
+	evalFolds := 0
+	multiLabel := false
+	calibrationFolds := DefaultCalibrationFolds
 	if config != nil {
 		verbose = config.Verbose
+		evalFolds = config.EvalFolds
+		multiLabel = config.MultiLabel
+		if config.CalibrationFolds != 0 {
+			calibrationFolds = config.CalibrationFolds
+		}
 	}
 
 	store := storage.NewStorage(filepath.Join(dataDir, "forms"))
@@ -72,6 +116,10 @@ func Train(dataDir string, config *TrainConfig) (*Classifier, error) {
 	formConfig := classifier.DefaultFormTypeTrainConfig()
 	formConfig.Verbose = verbose
 	formModel := classifier.TrainFormType(forms, formLabels, formConfig)
+	if calibrationFolds > 0 {
+		formModel.Temperature = formTemperature(forms, formLabels, domainGroups(formAnnotations), calibrationFolds)
+		slog.Info("Calibrated form type probabilities", "temperature", formModel.Temperature)
+	}
 
 	// Train field type classifier
 	fieldAnnotations := filterFieldAnnotated(annotations)
@@ -85,20 +133,29 @@ func Train(dataDir string, config *TrainConfig) (*Classifier, error) {
 
 	// Train page type classifier (if page data exists)
 	var pageModel *classifier.PageTypeModel
+	var pageAnnotations []storage.PageAnnotation
 	pagesDir := filepath.Join(dataDir, "pages")
 	if _, err := os.Stat(filepath.Join(pagesDir, "index.json")); err == nil {
 		pageStore := storage.NewPageStorage(pagesDir)
 		pageOpts := storage.DefaultIterOptions()
 		pageOpts.Verbose = verbose
-		pageAnnotations, err := pageStore.IterPageAnnotations(pageOpts)
+		pageAnnotations, err = pageStore.IterPageAnnotations(pageOpts)
 		if err != nil {
 			slog.Warn("Failed to load page annotations", "error", err)
 		} else if len(pageAnnotations) > 0 {
 			slog.Info("Training page type classifier", "annotations", len(pageAnnotations))
-			docs, formResults, urls, labels := extractPageTrainingData(pageAnnotations, formModel)
+			docs, formResults, urls, labels, types := extractPageTrainingData(pageAnnotations, formModel)
 			pageConfig := classifier.DefaultPageTypeTrainConfig()
 			pageConfig.Verbose = verbose
 			pageModel = classifier.TrainPageType(docs, formResults, urls, labels, pageConfig)
+			if calibrationFolds > 0 {
+				pageModel.Temperature = pageTemperature(docs, formResults, urls, labels, pageDomainGroups(pageAnnotations), calibrationFolds)
+				slog.Info("Calibrated page type probabilities", "temperature", pageModel.Temperature)
+			}
+			if multiLabel {
+				slog.Info("Training multi-label page type classifier")
+				pageModel.TrainMultiLabel(docs, formResults, urls, types, pageConfig)
+			}
 		}
 	}
 
@@ -106,19 +163,75 @@ func Train(dataDir string, config *TrainConfig) (*Classifier, error) {
 		FormModel:  formModel,
 		FieldModel: fieldModel,
 		PageModel:  pageModel,
+		Meta: &classifier.ModelMeta{
+			DitVersion: Version(),
+			CreatedAt:  time.Now().UTC(),
+			DataHash:   annotationsHash(annotations, pageAnnotations),
+		},
+	}
+
+	if evalFolds > 0 {
+		slog.Info("Evaluating model", "folds", evalFolds)
+		result, err := Evaluate(dataDir, &EvalConfig{Folds: evalFolds, Verbose: verbose, MultiLabel: multiLabel})
+		if err != nil {
+			return nil, err
+		}
+		fc.Meta.Eval = result.Scores()
 	}
+
+	fc.Meta = fc.Describe()
 	return &Classifier{fc: fc}, nil
 }
 
+// Scores returns the headline metrics of r keyed by name, as stored in
+// model metadata.
+func (r *EvalResult) Scores() map[string]float64 {
+	scores := make(map[string]float64)
+	if r.FormTotal > 0 {
+		scores["form_accuracy"] = r.FormAccuracy
+	}
+	if r.FieldTotal > 0 {
+		scores["field_accuracy"] = r.FieldAccuracy
+		scores["sequence_accuracy"] = r.SequenceAccuracy
+	}
+	if r.PageTotal > 0 {
+		scores["page_accuracy"] = r.PageAccuracy
+		scores["page_macro_f1"] = r.PageMacroF1
+		scores["page_weighted_f1"] = r.PageWeightedF1
+	}
+	if r.PageMultiLabelTotal > 0 {
+		scores["page_multilabel_micro_f1"] = r.PageMultiLabelMicroF1
+		scores["page_multilabel_macro_f1"] = r.PageMultiLabelMacroF1
+	}
+	return scores
+}
+
+// annotationsHash returns a SHA-256 digest of the training annotations.
+func annotationsHash(forms []storage.FormAnnotation, pages []storage.PageAnnotation) string {
+	h := sha256.New()
+	for _, a := range forms {
+		fmt.Fprintf(h, "form\x00%s\x00%s\x00%s\x00", a.URL, a.TypeFull, a.FormHTML)
+		for _, name := range slices.Sorted(maps.Keys(a.FieldTypesFull)) {
+			fmt.Fprintf(h, "%s=%s\x00", name, a.FieldTypesFull[name])
+		}
+	}
+	for _, a := range pages {
+		fmt.Fprintf(h, "page\x00%s\x00%s\x00%s\x00", a.URL, a.TypeFull, a.HTML)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
 // Evaluate runs cross-validation evaluation on annotated data.
 func Evaluate(dataDir string, config *EvalConfig) (*EvalResult, error) {
 	nFolds := 10
 	verbose := false
+	multiLabel := false
 	if config != nil {
 		if config.Folds > 0 {
 			nFolds = config.Folds
 		}
 		verbose = config.Verbose
+		multiLabel = config.MultiLabel
 	}
 
 	store := storage.NewStorage(filepath.Join(dataDir, "forms"))
@@ -165,6 +278,17 @@ func Evaluate(dataDir string, config *EvalConfig) (*EvalResult, error) {
 		groups := domainGroups(keptAnnotations)
 		folds := groupKFold(groups, nFolds)
 
+		result.FieldConfusion = make(map[string]map[string]int)
+		for _, seq := range sequences {
+			for _, l := range seq.Labels {
+				if result.FieldConfusion[l] == nil {
+					result.FieldConfusion[l] = make(map[string]int)
+					result.FieldClasses = append(result.FieldClasses, l)
+				}
+			}
+		}
+		slices.Sort(result.FieldClasses)
+
 		for _, testIdx := range folds {
 			testSet := makeTestSet(len(sequences), testIdx)
 			var trainSeqs []crf.TrainingSequence
@@ -187,6 +311,9 @@ func Evaluate(dataDir string, config *EvalConfig) (*EvalResult, error) {
 					} else {
 						allCorrect = false
 					}
+					if j < len(pred) {
+						result.FieldConfusion[seq.Labels[j]][pred[j]]++
+					}
 					result.FieldTotal++
 				}
 				if allCorrect {
@@ -197,6 +324,7 @@ func Evaluate(dataDir string, config *EvalConfig) (*EvalResult, error) {
 		}
 		if result.FieldTotal > 0 {
 			result.FieldAccuracy = float64(result.FieldCorrect) / float64(result.FieldTotal)
+			result.FieldPrecision, result.FieldRecall, result.FieldF1, result.FieldMacroF1, _ = computeMetrics(result.FieldConfusion, result.FieldClasses)
 		}
 		if result.SequenceTotal > 0 {
 			result.SequenceAccuracy = float64(result.SequenceCorrect) / float64(result.SequenceTotal)
@@ -221,7 +349,7 @@ func Evaluate(dataDir string, config *EvalConfig) (*EvalResult, error) {
 			trainForms, trainFormLabels := extractFormTrainingData(formAnnotated)
 			foldFormModel := classifier.TrainFormType(trainForms, trainFormLabels, classifier.DefaultFormTypeTrainConfig())
 
-			docs, _, urls, labels := extractPageTrainingData(pageAnnotations, nil)
+			docs, _, urls, labels, types := extractPageTrainingData(pageAnnotations, nil)
 			// Compute form results for all docs once
 			allFormResults := make([][]classifier.ClassifyResult, len(docs))
 			for i, doc := range docs {
@@ -241,14 +369,36 @@ func Evaluate(dataDir string, config *EvalConfig) (*EvalResult, error) {
 				result.PageClasses = append(result.PageClasses, cls)
 			}
 
+			var multiConfusion map[string]*labelCounts
+			if multiLabel {
+				multiConfusion = make(map[string]*labelCounts)
+			}
+
 			for _, testIdx := range folds {
 				testSet := makeTestSet(len(docs), testIdx)
 				trainDocs, trainFormResults, trainURLs, trainLabels := filterPageByIndex(docs, allFormResults, urls, labels, testSet, false)
 				pageConfig := classifier.DefaultPageTypeTrainConfig()
 				pageModel := classifier.TrainPageType(trainDocs, trainFormResults, trainURLs, trainLabels, pageConfig)
+				if multiLabel {
+					var trainTypes [][]string
+					for i := range types {
+						if !testSet[i] {
+							trainTypes = append(trainTypes, types[i])
+						}
+					}
+					pageModel.TrainMultiLabel(trainDocs, trainFormResults, trainURLs, trainTypes, pageConfig)
+					for _, idx := range testIdx {
+						pred := pageModel.ClassifyTypes(docs[idx], allFormResults[idx], urls[idx])
+						if len(pred) == 0 {
+							pred = []string{pageModel.Classify(docs[idx], allFormResults[idx], urls[idx])}
+						}
+						countLabels(multiConfusion, types[idx], pred)
+						result.PageMultiLabelTotal++
+					}
+				}
 
 				for _, idx := range testIdx {
-					pred := pageModel.Classify(docs[idx], allFormResults[idx])
+					pred := pageModel.Classify(docs[idx], allFormResults[idx], urls[idx])
 					true_ := labels[idx]
 					if pred == true_ {
 						result.PageCorrect++
@@ -261,6 +411,10 @@ func Evaluate(dataDir string, config *EvalConfig) (*EvalResult, error) {
 				result.PageAccuracy = float64(result.PageCorrect) / float64(result.PageTotal)
 				result.PagePrecision, result.PageRecall, result.PageF1, result.PageMacroF1, result.PageWeightedF1 = computeMetrics(result.PageConfusion, result.PageClasses)
 			}
+			if result.PageMultiLabelTotal > 0 {
+				result.PageMultiLabelClasses = slices.Sorted(maps.Keys(multiConfusion))
+				result.PageMultiLabelF1, result.PageMultiLabelMicroF1, result.PageMultiLabelMacroF1 = multiLabelMetrics(multiConfusion)
+			}
 		}
 	}
 
@@ -413,11 +567,14 @@ func filterByIndex(forms []*goquery.Selection, labels []string, testSet []bool,
 
 // --- page classifier helpers ---
 
-func extractPageTrainingData(annotations []storage.PageAnnotation, formModel *classifier.FormTypeModel) ([]*goquery.Document, [][]classifier.ClassifyResult, []string, []string) {
+// extractPageTrainingData returns the parsed pages with their form
+// results, URLs, primary types and all types.
+func extractPageTrainingData(annotations []storage.PageAnnotation, formModel *classifier.FormTypeModel) ([]*goquery.Document, [][]classifier.ClassifyResult, []string, []string, [][]string) {
 	docs := make([]*goquery.Document, 0, len(annotations))
 	formResults := make([][]classifier.ClassifyResult, 0, len(annotations))
 	urls := make([]string, 0, len(annotations))
 	labels := make([]string, 0, len(annotations))
+	types := make([][]string, 0, len(annotations))
 
 	for _, ann := range annotations {
 		doc, err := htmlutil.LoadHTMLString(ann.HTML)
@@ -434,13 +591,14 @@ func extractPageTrainingData(annotations []storage.PageAnnotation, formModel *cl
 		formResults = append(formResults, results)
 		urls = append(urls, ann.URL)
 		labels = append(labels, ann.TypeFull)
+		types = append(types, ann.TypesFull)
 	}
 
-	return docs, formResults, urls, labels
+	return docs, formResults, urls, labels, types
 }
 
 func classifyFormsOnDoc(formModel *classifier.FormTypeModel, doc *goquery.Document) []classifier.ClassifyResult {
-	forms := htmlutil.GetForms(doc)
+	forms := htmlutil.GetAllForms(doc)
 	results := make([]classifier.ClassifyResult, len(forms))
 	for i, form := range forms {
 		results[i] = classifier.ClassifyResult{
@@ -543,3 +701,101 @@ func filterPageByIndex(docs []*goquery.Document, formResults [][]classifier.Clas
 	}
 	return outDocs, outFormResults, outURLs, outLabels
 }
+
+// labelCounts holds the true positives, false positives and false
+// negatives of one type over multi-label predictions.
+type labelCounts struct {
+	tp, fp, fn int
+}
+
+// countLabels adds the predicted types of a page, given its true types, to
+// counts.
+func countLabels(counts map[string]*labelCounts, truth, pred []string) {
+	get := func(cls string) *labelCounts {
+		if counts[cls] == nil {
+			counts[cls] = &labelCounts{}
+		}
+		return counts[cls]
+	}
+	for _, cls := range pred {
+		if slices.Contains(truth, cls) {
+			get(cls).tp++
+		} else {
+			get(cls).fp++
+		}
+	}
+	for _, cls := range truth {
+		if !slices.Contains(pred, cls) {
+			get(cls).fn++
+		}
+	}
+}
+
+// multiLabelMetrics returns the F1 of each type, the micro F1 over all
+// type decisions and the macro F1, the mean of the per-type scores.
+func multiLabelMetrics(counts map[string]*labelCounts) (f1 map[string]float64, microF1, macroF1 float64) {
+	f1 = make(map[string]float64, len(counts))
+	var total labelCounts
+	for cls, c := range counts {
+		if d := 2*c.tp + c.fp + c.fn; d > 0 {
+			f1[cls] = 2 * float64(c.tp) / float64(d)
+		}
+		macroF1 += f1[cls]
+		total.tp += c.tp
+		total.fp += c.fp
+		total.fn += c.fn
+	}
+	if len(counts) > 0 {
+		macroF1 /= float64(len(counts))
+	}
+	if d := 2*total.tp + total.fp + total.fn; d > 0 {
+		microF1 = 2 * float64(total.tp) / float64(d)
+	}
+	return f1, microF1, macroF1
+}
+
+// --- calibration helpers ---
+
+// formTemperature trains a form type model per fold and fits the
+// temperature on the logits it gives the held-out forms. Forms of a class
+// missing from their fold's training data are skipped.
+func formTemperature(forms []*goquery.Selection, labels []string, groups []int, nFolds int) float64 {
+	var logits [][]float64
+	var targets []int
+	for _, testIdx := range groupKFold(groups, nFolds) {
+		testSet := makeTestSet(len(forms), testIdx)
+		trainForms, trainLabels := filterByIndex(forms, labels, testSet, false)
+		if len(trainForms) == 0 {
+			continue
+		}
+		model := classifier.TrainFormType(trainForms, trainLabels, classifier.DefaultFormTypeTrainConfig())
+		for _, idx := range testIdx {
+			if y := slices.Index(model.Classes, labels[idx]); y >= 0 {
+				logits = append(logits, model.Logits(forms[idx]))
+				targets = append(targets, y)
+			}
+		}
+	}
+	return classifier.FitTemperature(logits, targets)
+}
+
+// pageTemperature is formTemperature for the page type model.
+func pageTemperature(docs []*goquery.Document, formResults [][]classifier.ClassifyResult, urls, labels []string, groups []int, nFolds int) float64 {
+	var logits [][]float64
+	var targets []int
+	for _, testIdx := range groupKFold(groups, nFolds) {
+		testSet := makeTestSet(len(docs), testIdx)
+		trainDocs, trainFormResults, trainURLs, trainLabels := filterPageByIndex(docs, formResults, urls, labels, testSet, false)
+		if len(trainDocs) == 0 {
+			continue
+		}
+		model := classifier.TrainPageType(trainDocs, trainFormResults, trainURLs, trainLabels, classifier.DefaultPageTypeTrainConfig())
+		for _, idx := range testIdx {
+			if y := slices.Index(model.Classes, labels[idx]); y >= 0 {
+				logits = append(logits, model.Logits(docs[idx], formResults[idx], urls[idx]))
+				targets = append(targets, y)
+			}
+		}
+	}
+	return classifier.FitTemperature(logits, targets)
+}